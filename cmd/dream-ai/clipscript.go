@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dream-ai/cli/internal/documents"
+)
+
+// clipScriptName is the file name of the CLIP2 captioning script
+const clipScriptName = "clip2_process.py"
+
+// clipScriptCandidates returns where to look for the CLIP2 script, in order:
+// $DREAM_AI_SCRIPTS, next to the binary, ~/.dream-ai/scripts and ./scripts.
+// PATH is searched after these.
+func clipScriptCandidates() []string {
+	var candidates []string
+	if dir := os.Getenv("DREAM_AI_SCRIPTS"); dir != "" {
+		candidates = append(candidates, filepath.Join(documents.ExpandHome(dir), clipScriptName))
+	}
+	if exePath, err := os.Executable(); err == nil {
+		exeDir := filepath.Dir(exePath)
+		candidates = append(candidates,
+			filepath.Join(exeDir, clipScriptName),
+			filepath.Join(exeDir, "scripts", clipScriptName),
+			filepath.Join(exeDir, "..", "scripts", clipScriptName),
+		)
+	}
+	return append(candidates,
+		filepath.Join(documents.ExpandHome("~/.dream-ai/scripts"), clipScriptName),
+		filepath.Join("scripts", clipScriptName),
+	)
+}
+
+// findCLIPScript returns the first CLIP2 script found among the candidates
+// or on PATH, and the places searched. The path is empty if there is none.
+func findCLIPScript() (path string, searched []string) {
+	searched = clipScriptCandidates()
+	for _, candidate := range searched {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return filepath.Clean(candidate), searched
+		}
+	}
+	searched = append(searched, "PATH")
+	if found, err := exec.LookPath(clipScriptName); err == nil {
+		return found, searched
+	}
+	return "", searched
+}
+
+// logCLIPScript logs which CLIP2 script image processing will use, or warns
+// that without one captions and embeddings are placeholders
+func logCLIPScript(path string, searched []string, configured bool) {
+	switch {
+	case path == "":
+		log.Printf("warning: no CLIP2 script (%s) found in %s; image processing will use placeholder captions and embeddings. Set clip2.script_path or $DREAM_AI_SCRIPTS",
+			clipScriptName, strings.Join(searched, ", "))
+	case configured:
+		if _, err := os.Stat(path); err != nil {
+			log.Printf("warning: clip2.script_path %s: %v; image processing will use placeholder captions and embeddings", path, err)
+			return
+		}
+		log.Printf("CLIP2 script: %s (clip2.script_path)", path)
+	default:
+		log.Printf("CLIP2 script: %s (found automatically)", path)
+	}
+}
@@ -1,19 +1,36 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/dream-ai/cli/config"
 	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/dream-ai/cli/internal/trace"
 	"github.com/dream-ai/cli/internal/tui"
+	"github.com/dream-ai/cli/migrations"
 )
 
 func main() {
 	var (
-		migrateFlag = flag.Bool("migrate", false, "Run database migrations")
+		migrateFlag   = flag.Bool("migrate", false, "Run database migrations")
+		baselineFlag  = flag.Int("migrate-baseline", 0, "With -migrate, record migrations up to this version as already applied by hand before running the rest (detected from the tables if not given)")
+		rollbackFlag  = flag.Int("rollback", -1, "Revert applied migrations newer than this version with their .down.sql files, newest first, and exit (0 reverts all)")
+		queryFlag     = flag.String("query", "", "Answer a single question and exit")
+		summarizeFlag = flag.String("summarize", "", "Summarize a topic across all sources with citations and exit")
+		retrieveFlag  = flag.String("retrieve", "", "Show the chunks and images retrieved for a query, without answering, and exit")
+		topFlag       = flag.Int("top", 0, "With -summarize or -retrieve, the number of passages to retrieve (default rag.summary_top_k or processing.top_k)")
+		jsonFlag      = flag.Bool("json", false, "With -query, -summarize or -retrieve, print the result as JSON")
+		serveFlag     = flag.String("serve", "", "Serve the RAG pipeline over HTTP on this address (e.g. :8080)")
+		profileFlag   = flag.String("profile", "", "Use the named config profile's schema and embedding model")
+		forceFlag     = flag.Bool("force", false, "Ingest documents even if the embedding model differs from the index's")
+		selfTestFlag  = flag.Bool("selftest", false, "Check that embedding, storage and search work end to end and exit")
+		readOnlyFlag  = flag.Bool("readonly", false, "Disable adding, deleting and reprocessing documents and saving settings in the TUI")
 	)
 	flag.Parse()
 
@@ -24,9 +41,52 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *profileFlag != "" {
+		if err := cfg.ApplyProfile(*profileFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error selecting profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if _, err := ollama.NormalizeBaseURL(cfg.Ollama.BaseURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Error in config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *forceFlag {
+		cfg.Embeddings.OnModelChange = "warn"
+	}
+
+	// Find the CLIP2 script if not set; which one is used is logged once
+	// the log is set up
+	scriptConfigured := cfg.CLIP2.ScriptPath != ""
+	var scriptsSearched []string
+	if !scriptConfigured {
+		cfg.CLIP2.ScriptPath, scriptsSearched = findCLIPScript()
+	}
+	if *readOnlyFlag {
+		cfg.ReadOnly = true
+	}
+	trace.SetEnabled(cfg.Logging.Trace)
+
+	if cfg.Startup.WaitForDependencies {
+		if err := waitForDependencies(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Roll back migrations if requested
+	if *rollbackFlag >= 0 {
+		if err := rollbackMigrations(cfg.Database.ConnectionString, cfg.Database.Schema, *rollbackFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error rolling back migrations: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Run migrations if requested
 	if *migrateFlag {
-		if err := runMigrations(cfg.Database.ConnectionString); err != nil {
+		if err := runMigrations(cfg.Database.ConnectionString, cfg.Database.Schema, *baselineFlag, true); err != nil {
 			fmt.Fprintf(os.Stderr, "Error running migrations: %v\n", err)
 			os.Exit(1)
 		}
@@ -34,80 +94,209 @@ func main() {
 		return
 	}
 
+	// Check the embed, store and search loop without starting the TUI
+	if *selfTestFlag {
+		if err := runSelfTest(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Self-test passed")
+		return
+	}
+
+	// Answer a single question without starting the TUI
+	if *queryFlag != "" {
+		if err := runQuery(cfg, *queryFlag, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running query: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Show what a query retrieves, for tuning, without starting the TUI
+	if *retrieveFlag != "" {
+		topK := *topFlag
+		if topK <= 0 {
+			topK = cfg.Processing.TopK
+		}
+		if err := runRetrieve(cfg, *retrieveFlag, topK, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running retrieval: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Serve queries over HTTP without starting the TUI
+	if *serveFlag != "" {
+		if err := runServe(cfg, *serveFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Summarize a topic across the corpus without starting the TUI
+	if *summarizeFlag != "" {
+		topK := *topFlag
+		if topK <= 0 {
+			topK = cfg.RAG.SummaryTopK
+		}
+		if err := runSummary(cfg, *summarizeFlag, topK, *jsonFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running summary: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Ensure image directory exists
 	if err := os.MkdirAll(cfg.Paths.ImageDir, 0755); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating image directory: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Set CLIP2 script path if not set
-	if cfg.CLIP2.ScriptPath == "" {
-		// Try to find the script relative to the binary
-		exePath, err := os.Executable()
-		if err == nil {
-			scriptPath := filepath.Join(filepath.Dir(exePath), "..", "scripts", "clip2_process.py")
-			if _, err := os.Stat(scriptPath); err == nil {
-				cfg.CLIP2.ScriptPath = scriptPath
-			}
-		}
-		// Fallback: try relative to current directory
-		if cfg.CLIP2.ScriptPath == "" {
-			scriptPath := filepath.Join("scripts", "clip2_process.py")
-			if _, err := os.Stat(scriptPath); err == nil {
-				cfg.CLIP2.ScriptPath = scriptPath
-			}
+	// Run migrations on startup if needed, but never change the schema of
+	// a database opened read-only
+	if !cfg.ReadOnly {
+		if err := ensureMigrations(cfg.Database.ConnectionString, cfg.Database.Schema); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Migration check failed: %v\n", err)
+			// Continue anyway - the schema may still be usable
 		}
 	}
 
-	// Run migrations on startup if needed
-	if err := ensureMigrations(cfg.Database.ConnectionString); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Migration check failed: %v\n", err)
-		// Continue anyway - migrations might already be applied
+	// The TUI owns the terminal, so log warnings to a file instead
+	logFile, err := openLogFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else {
+		defer logFile.Close()
+		log.SetOutput(logFile)
 	}
+	logCLIPScript(cfg.CLIP2.ScriptPath, scriptsSearched, scriptConfigured)
+
+	// Warnings printed by C libraries go to the log too. Without a log file
+	// the log is stderr itself, so they are dropped.
+	restoreStderr := captureStderr(logFile == nil || cfg.Logging.LibraryOutput == "discard")
 
 	// Create and run TUI
 	app, err := tui.NewApp(cfg)
 	if err != nil {
+		restoreStderr()
 		fmt.Fprintf(os.Stderr, "Error initializing app: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := app.Run(); err != nil {
+	err = app.Run()
+	restoreStderr()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running app: %v\n", err)
 		os.Exit(1)
 	}
+
+	if cfg.Display.UsageSummary {
+		fmt.Print(app.UsageSummary())
+	}
+	if cfg.Metrics.Enabled {
+		if err := app.WriteMetrics(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
 }
 
-// runMigrations runs database migrations
-func runMigrations(connString string) error {
-	db, err := db.New(connString)
+// openLogFile opens ~/.dream-ai/dream-ai.log for appending
+func openLogFile() (*os.File, error) {
+	logDir := filepath.Join(os.Getenv("HOME"), ".dream-ai")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(logDir, "dream-ai.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return f, nil
+}
+
+// runMigrations applies the embedded migrations that schema_migrations does
+// not record yet. A baseline above zero first records the migrations up to
+// that version as applied, for a database migrated by hand with psql; with
+// 0 it is detected from the tables when no migrations are recorded.
+func runMigrations(connString, schema string, baseline int, verbose bool) error {
+	database, err := db.Connect(connString, schema)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer db.Close()
+	defer database.Close()
 
-	// Get migration directory
-	migrationDir := "migrations"
-	if _, err := os.Stat(migrationDir); os.IsNotExist(err) {
-		// Try relative to executable
-		exePath, err := os.Executable()
-		if err == nil {
-			migrationDir = filepath.Join(filepath.Dir(exePath), "..", "migrations")
+	all, err := db.LoadMigrations(migrations.Files)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if baseline == 0 {
+		// A database migrated by hand is recorded once, from its tables
+		if baseline, err = database.DetectBaseline(ctx, all); err != nil {
+			return err
+		}
+		if baseline > 0 {
+			message := fmt.Sprintf("Found migrations up to version %d applied without being recorded; recording them", baseline)
+			if verbose {
+				fmt.Println(message)
+			} else {
+				log.Print(message)
+			}
+		}
+	}
+	if baseline > 0 {
+		if err := database.Baseline(ctx, all, baseline); err != nil {
+			return err
 		}
 	}
 
-	// TODO: Migrations need to be run manually for now
-	// Run: psql postgres -f migrations/00001_init_schema.up.sql
-	// Or use a migration tool that supports pgx directly
-	fmt.Printf("Note: Please run migrations manually:\n")
-	fmt.Printf("  psql postgres -f %s\n", migrationDir+"/00001_init_schema.up.sql")
-	fmt.Printf("Or install pgvector extension: CREATE EXTENSION IF NOT EXISTS vector;\n")
+	applied, err := database.Migrate(ctx, all)
+	for _, m := range applied {
+		if verbose {
+			fmt.Printf("Applied %s\n", m.Name)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if verbose && len(applied) == 0 {
+		fmt.Println("Database is up to date")
+	}
+	return nil
+}
 
+// rollbackMigrations reverts the applied migrations newer than
+// target and prints the migrations it reverted
+func rollbackMigrations(connString, schema string, target int) error {
+	database, err := db.Connect(connString, schema)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close()
+
+	all, err := db.LoadMigrations(migrations.Files)
+	if err != nil {
+		return err
+	}
+
+	reverted, err := database.Rollback(context.Background(), all, target)
+	for _, m := range reverted {
+		fmt.Printf("Reverted %s\n", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(reverted) == 0 {
+		fmt.Printf("No migrations newer than version %d are applied\n", target)
+	} else {
+		fmt.Printf("Rolled back to version %d\n", target)
+	}
 	return nil
 }
 
-// ensureMigrations checks and runs migrations if needed
-func ensureMigrations(connString string) error {
-	// Try to run migrations - if they fail, they might already be applied
-	return runMigrations(connString)
+// ensureMigrations applies any pending migrations before the app starts
+func ensureMigrations(connString, schema string) error {
+	return runMigrations(connString, schema, 0, false)
 }
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dream-ai/cli/config"
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/embeddings"
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/dream-ai/cli/internal/rag"
+	"github.com/google/uuid"
+)
+
+// pipeline holds the RAG components used by the non-interactive modes
+type pipeline struct {
+	cfg            *config.Config
+	db             *db.DB
+	textEmb        *embeddings.TextEmbedder
+	retriever      *rag.Retriever
+	contextBuilder *rag.ContextBuilder
+	ollamaClient   *ollama.Client
+	modelSelector  *ollama.ModelSelector
+}
+
+// newPipeline connects to the database and wires up the RAG components
+func newPipeline(cfg *config.Config) (*pipeline, error) {
+	database, err := db.New(cfg.Database.ConnectionString, cfg.Database.Schema)
+	if errors.Is(err, db.ErrNotMigrated) {
+		return nil, fmt.Errorf("%w; run %s first", err, cfg.MigrateCommand())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if cfg.Database.Schema != "" {
+		if err := database.EnsureEmbeddingDimension(context.Background(), cfg.Embeddings.Dimension); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("schema %s: %w", cfg.Database.Schema, err)
+		}
+	}
+
+	textEmb := embeddings.NewTextEmbedder(cfg.Ollama.BaseURL, cfg.Embeddings.TextModel)
+	textEmb.SetTimeout(cfg.Embeddings.Timeout)
+	textEmb.SetDimension(cfg.Embeddings.Dimension)
+	// Among fallback models, keep to the one the index was built with
+	if model, err := database.IndexEmbeddingModel(context.Background()); err == nil && model != "" {
+		textEmb.PreferModel(model)
+	}
+	contextBuilder := rag.NewContextBuilder(cfg.RAG.MaxContextLength)
+	if err := contextBuilder.SetOrder(cfg.RAG.ContextOrder); err != nil {
+		database.Close()
+		return nil, err
+	}
+	contextBuilder.SetPersona(cfg.RAG.Persona)
+	contextBuilder.SetIncludeImages(cfg.RAG.IncludeImages)
+	contextBuilder.SetLabelSources(cfg.RAG.LabelSources)
+	contextBuilder.SetMarkGeneralKnowledge(cfg.RAG.MarkGeneralKnowledge)
+	for _, t := range cfg.RAG.PromptTemplates {
+		if err := contextBuilder.AddPromptTemplate(t.Match, t.Template); err != nil {
+			database.Close()
+			return nil, err
+		}
+	}
+	ollamaClient := ollama.NewClient(cfg.Ollama.BaseURL)
+	ollamaClient.SetMaxConcurrentGenerations(cfg.Ollama.MaxConcurrentGenerations)
+	ollamaClient.SetTimeout(cfg.Chat.Timeout)
+	reranker, err := rag.NewReranker(cfg.RAG.Reranker)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	retriever := rag.NewRetriever(database, textEmb, cfg.Processing.TopK)
+	retriever.SetReranker(reranker, cfg.RAG.CandidateMultiplier)
+	retriever.SetNeighborWindow(cfg.RAG.NeighborWindow)
+	retriever.SetDedupe(cfg.RAG.DedupeResults)
+	if err := retriever.SetMode(cfg.RAG.Mode); err != nil {
+		database.Close()
+		return nil, err
+	}
+	retriever.SetPreviewChars(cfg.RAG.DebugPreviewChars)
+	retriever.SetImageMaxDistance(cfg.RAG.ImageMaxDistance)
+	if cfg.RAG.AdaptiveTopK {
+		retriever.SetTokenBudget(cfg.RAG.MaxContextLength, cfg.RAG.MaxTopK)
+	}
+	if cfg.Processing.SummarizeDocuments {
+		retriever.SetDocumentSummaries(cfg.RAG.SummaryDocuments)
+	}
+	if cfg.CLIP2.CaptionOnly {
+		retriever.SetCaptionSearch(true)
+	} else if cfg.RAG.ImageSearch {
+		imageEmb := embeddings.NewImageEmbedder(cfg.CLIP2.PythonPath)
+		if cfg.CLIP2.ScriptPath != "" {
+			imageEmb.SetScriptPath(cfg.CLIP2.ScriptPath)
+		}
+		retriever.SetImageEmbedder(imageEmb)
+	}
+
+	// Summaries of long queries and paraphrases come from the default model
+	modelSelector := ollama.NewModelSelector(ollamaClient)
+	defaultModel := func() string {
+		model, _ := modelSelector.GetDefaultModel(context.Background(), cfg.Ollama.DefaultModel)
+		return model
+	}
+	retriever.SetLongQuery(cfg.RAG.LongQuery, cfg.RAG.MaxQueryChars, rag.NewLLMCondenser(ollamaClient, defaultModel))
+	if cfg.RAG.MultiQuery {
+		retriever.SetMultiQuery(rag.NewLLMParaphraser(ollamaClient, defaultModel), cfg.RAG.MultiQueryCount)
+	}
+
+	return &pipeline{
+		cfg:            cfg,
+		db:             database,
+		textEmb:        textEmb,
+		retriever:      retriever,
+		contextBuilder: contextBuilder,
+		ollamaClient:   ollamaClient,
+		modelSelector:  modelSelector,
+	}, nil
+}
+
+// Close releases the pipeline's database connection
+func (p *pipeline) Close() {
+	p.db.Close()
+}
+
+// preparedQuery is a query ready to send to the model
+type preparedQuery struct {
+	query    string
+	model    string
+	system   string
+	prompt   string
+	sources  []string
+	cacheKey string // Set when answer caching is enabled
+	chunkIDs []uuid.UUID
+	// contextTruncated is set when the context was cut to fit the budget
+	contextTruncated bool
+	// noSources is set when retrieval found nothing to answer from
+	noSources bool
+}
+
+// prepare retrieves context for query and builds its prompt. An empty model
+// selects the configured default.
+func (p *pipeline) prepare(ctx context.Context, query, model string) (*preparedQuery, error) {
+	if model == "" {
+		var err error
+		model, err = p.modelSelector.GetDefaultModel(ctx, p.cfg.Ollama.DefaultModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select model: %w", err)
+		}
+	}
+
+	result, err := p.retriever.Retrieve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	builder := p.contextBuilder.ForModel(model)
+	if numCtx, err := p.ollamaClient.ContextLength(ctx, model); err == nil {
+		builder = builder.FitToWindow(numCtx, p.cfg.RAG.ContextWindowFraction)
+	}
+	contextText, truncated := builder.BuildContext(result)
+
+	sources := p.retriever.Sources(ctx, result)
+	if sources == nil {
+		sources = []string{}
+	}
+	prepared := &preparedQuery{
+		query:            query,
+		model:            model,
+		system:           builder.BuildSystemPrompt(),
+		prompt:           builder.BuildPrompt(contextText, query),
+		sources:          sources,
+		chunkIDs:         rag.ChunkIDs(result),
+		contextTruncated: truncated,
+		noSources:        result.Empty(),
+	}
+	if p.cfg.RAG.CacheAnswers {
+		prepared.cacheKey = rag.AnswerCacheKey(query, model, builder, result)
+	}
+	return prepared, nil
+}
+
+// cachedAnswer returns the cached answer for a prepared query, if caching
+// is enabled and there is one
+func (p *pipeline) cachedAnswer(ctx context.Context, prepared *preparedQuery) (string, bool) {
+	if prepared.cacheKey == "" {
+		return "", false
+	}
+	answer, ok, err := p.db.GetCachedAnswer(ctx, prepared.cacheKey)
+	return answer, ok && err == nil
+}
+
+// storeAnswer caches a generated answer when caching is enabled
+func (p *pipeline) storeAnswer(ctx context.Context, prepared *preparedQuery, answer string) {
+	if prepared.cacheKey == "" {
+		return
+	}
+	p.db.PutCachedAnswer(ctx, prepared.cacheKey, prepared.query, prepared.model, answer, prepared.chunkIDs)
+}
+
+// queryResult is the -json output of a query
+type queryResult struct {
+	Query   string   `json:"query"`
+	Model   string   `json:"model"`
+	Answer  string   `json:"answer"`
+	Sources []string `json:"sources"`
+	// Truncated is set when generation broke off and Answer is partial
+	Truncated bool `json:"truncated,omitempty"`
+	// ContextTruncated is set when the retrieved context was cut to fit
+	// rag.max_context_length, so the answer rests on part of it
+	ContextTruncated bool `json:"context_truncated,omitempty"`
+	// NoSources is set when nothing relevant was retrieved, so Answer is
+	// from the model's general knowledge, or rag.NoSourcesAnswer when
+	// rag.no_results is "refuse"
+	NoSources bool `json:"no_sources,omitempty"`
+}
+
+// refuses reports whether prepared is to be answered with
+// rag.NoSourcesAnswer instead of generating
+func (p *pipeline) refuses(prepared *preparedQuery) bool {
+	return prepared.noSources && p.cfg.RAG.NoResults == "refuse"
+}
+
+// runQuery answers a single question. The answer is streamed to stdout as it
+// is generated, followed by its sources; with asJSON the complete result is
+// written as one JSON object instead.
+func runQuery(cfg *config.Config, query string, asJSON bool) error {
+	p, err := newPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Chat.Timeout)
+	defer cancel()
+	prepared, err := p.prepare(ctx, query, "")
+	if err != nil {
+		return ollama.CheckTimeout(ctx, err, cfg.Chat.Timeout)
+	}
+
+	var answer strings.Builder
+	truncated := false
+	onChunk := func(chunk string) {
+		if asJSON {
+			answer.WriteString(chunk)
+			return
+		}
+		fmt.Print(chunk)
+	}
+	if prepared.noSources && !asJSON {
+		fmt.Fprintln(os.Stderr, "Note: "+rag.NoSourcesNote)
+	}
+	if p.refuses(prepared) {
+		onChunk(rag.NoSourcesAnswer)
+	} else if cached, ok := p.cachedAnswer(ctx, prepared); ok {
+		onChunk(cached)
+	} else {
+		var full strings.Builder
+		err = p.ollamaClient.GenerateStream(ctx, &ollama.GenerateRequest{
+			Model:  prepared.model,
+			System: prepared.system,
+			Prompt: prepared.prompt,
+		}, func(chunk string) {
+			full.WriteString(chunk)
+			onChunk(chunk)
+		})
+		switch {
+		case errors.Is(err, ollama.ErrTruncatedResponse) && full.Len() > 0:
+			// Keep the partial answer, but do not cache it
+			fmt.Fprintf(os.Stderr, "\nWarning: %v\n", err)
+			truncated = true
+		case err != nil:
+			return fmt.Errorf("failed to generate answer: %w", ollama.CheckTimeout(ctx, err, cfg.Chat.Timeout))
+		default:
+			p.storeAnswer(ctx, prepared, full.String())
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(queryResult{
+			Query:            query,
+			Model:            prepared.model,
+			Answer:           answer.String(),
+			Sources:          prepared.sources,
+			Truncated:        truncated,
+			ContextTruncated: prepared.contextTruncated,
+			NoSources:        prepared.noSources,
+		})
+	}
+
+	fmt.Println()
+	if prepared.contextTruncated {
+		fmt.Fprintln(os.Stderr, "Note: context truncated; consider raising rag.max_context_length or narrowing your question")
+	}
+	if len(prepared.sources) > 0 {
+		fmt.Println("\nSources:")
+		for _, source := range prepared.sources {
+			fmt.Printf("  - %s\n", source)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dream-ai/cli/config"
+	"github.com/dream-ai/cli/internal/rag"
+)
+
+// retrievedChunk is a chunk in the -retrieve -json output
+type retrievedChunk struct {
+	Source     string  `json:"source"`
+	ChunkIndex int     `json:"chunk_index"`
+	Section    string  `json:"section,omitempty"`
+	Distance   float64 `json:"distance"`
+	Content    string  `json:"content"`
+}
+
+// retrievedImage is an image in the -retrieve -json output
+type retrievedImage struct {
+	File     string  `json:"file"`
+	Caption  string  `json:"caption"`
+	Distance float64 `json:"distance"`
+	Source   string  `json:"source,omitempty"`
+	Page     int     `json:"page,omitempty"`
+	Location string  `json:"location,omitempty"`
+	Origin   string  `json:"-"`
+}
+
+// retrieveResult is the -retrieve -json output
+type retrieveResult struct {
+	Query  string           `json:"query"`
+	Chunks []retrievedChunk `json:"chunks"`
+	Images []retrievedImage `json:"images"`
+}
+
+// runRetrieve runs only the retrieval half of the pipeline for a query and
+// prints the topK chunks (source, position, distance and a snippet) and any
+// images, without generating an answer. With asJSON the chunks are given in
+// full.
+func runRetrieve(cfg *config.Config, query string, topK int, asJSON bool) error {
+	p, err := newPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	result, err := p.retriever.RetrieveN(context.Background(), query, topK)
+	if err != nil {
+		return err
+	}
+
+	out := retrieveResult{Query: query, Chunks: []retrievedChunk{}, Images: []retrievedImage{}}
+	for _, chunk := range result.Chunks {
+		source, ok := result.SourceNames[chunk.DocumentID]
+		if !ok {
+			source = chunk.DocumentID.String()
+		}
+		out.Chunks = append(out.Chunks, retrievedChunk{
+			Source:     source,
+			ChunkIndex: chunk.ChunkIndex,
+			Section:    chunk.Section,
+			Distance:   chunk.Distance,
+			Content:    chunk.Content,
+		})
+	}
+	for _, img := range result.Images {
+		image := retrievedImage{
+			File:     img.FilePath,
+			Caption:  img.Caption,
+			Distance: img.Distance,
+			Source:   result.SourceNames[img.DocumentID],
+			Location: img.SourceLocation,
+			Origin:   rag.ImageOrigin(img, result.SourceNames),
+		}
+		if img.SourcePage != nil {
+			image.Page = *img.SourcePage
+		}
+		out.Images = append(out.Images, image)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	fmt.Printf("Chunks (%d):\n", len(out.Chunks))
+	for i, chunk := range out.Chunks {
+		location := fmt.Sprintf("#%d", chunk.ChunkIndex)
+		if chunk.Section != "" {
+			location += " " + chunk.Section
+		}
+		fmt.Printf("%2d. %s %s  distance %.4f\n    %s\n", i+1, chunk.Source, location, chunk.Distance,
+			rag.Preview(chunk.Content, cfg.RAG.DebugPreviewChars))
+	}
+	if len(out.Images) > 0 {
+		fmt.Printf("\nImages (%d):\n", len(out.Images))
+		for i, img := range out.Images {
+			fmt.Printf("%2d. %s  distance %.4f\n", i+1, filepath.Base(img.File), img.Distance)
+			if img.Origin != "" {
+				fmt.Printf("    %s\n", img.Origin)
+			}
+			fmt.Printf("    %s\n", img.Caption)
+		}
+	}
+	return nil
+}
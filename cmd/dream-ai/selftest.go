@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dream-ai/cli/config"
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/google/uuid"
+)
+
+// selfTestText is the known passage the self-test indexes; its made-up words
+// keep it from resembling anything in a real corpus
+const selfTestText = "The zorblax lighthouse on Quimbry Island guides ships with a violet beam that pulses every eleven seconds."
+
+// selfTestQuery should retrieve selfTestText and nothing ranked above it
+const selfTestQuery = "What color is the beam of the zorblax lighthouse on Quimbry Island?"
+
+// runSelfTest checks the embed, store, search and retrieve loop end to end:
+// it indexes a temporary document with known text, asks a question about it
+// and checks the passage comes back, then deletes the document. Each step is
+// reported as PASS or FAIL.
+func runSelfTest(cfg *config.Config) error {
+	p, err := newPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	ctx := context.Background()
+	step := func(name string, err error) error {
+		if err != nil {
+			fmt.Printf("FAIL  %s: %v\n", name, err)
+			return fmt.Errorf("self-test failed at %s: %w", name, err)
+		}
+		fmt.Printf("PASS  %s\n", name)
+		return nil
+	}
+
+	if err := step("database connection", p.db.Ping(ctx)); err != nil {
+		return err
+	}
+
+	embedding, err := p.textEmb.Embed(ctx, selfTestText)
+	if err := step(fmt.Sprintf("embed text with %s", p.textEmb.Model()), err); err != nil {
+		return err
+	}
+
+	// The document is never parsed, but its type must be one the documents
+	// table accepts in every schema version
+	id := uuid.New()
+	doc, err := p.db.CreateDocument(ctx, "selftest://"+id.String(), "selftest-"+id.String(), "pdf")
+	if err := step("create temporary document", err); err != nil {
+		return err
+	}
+	defer func() {
+		step("remove temporary document", p.db.DeleteDocument(ctx, doc.ID))
+	}()
+
+	chunk := &db.Chunk{
+		ID:         uuid.New(),
+		DocumentID: doc.ID,
+		Content:    selfTestText,
+		Embedding:  embedding,
+	}
+	if err := step("store chunk", p.db.InsertChunk(ctx, chunk)); err != nil {
+		return err
+	}
+
+	result, err := p.retriever.Retrieve(ctx, selfTestQuery)
+	if err := step("search", err); err != nil {
+		return err
+	}
+
+	err = fmt.Errorf("the test passage was not among the %d results", len(result.Chunks))
+	for _, c := range result.Chunks {
+		if c.DocumentID == doc.ID {
+			err = nil
+			break
+		}
+	}
+	return step("retrieve test passage", err)
+}
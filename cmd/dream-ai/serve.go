@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dream-ai/cli/config"
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/dream-ai/cli/internal/rag"
+	"github.com/dream-ai/cli/internal/trace"
+)
+
+// queryRequest is the body of POST /query
+type queryRequest struct {
+	Query string `json:"query"`
+	Model string `json:"model"` // Optional; defaults to the configured model
+}
+
+// componentStatus reports whether one dependency is reachable
+type componentStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// healthResponse is the body of GET /health
+type healthResponse struct {
+	Status   string          `json:"status"` // ok or degraded
+	Database componentStatus `json:"database"`
+	Ollama   componentStatus `json:"ollama"`
+}
+
+// runServe serves the RAG pipeline over HTTP on addr until interrupted
+func runServe(cfg *config.Config, addr string) error {
+	p, err := newPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /query", p.handleQuery)
+	mux.HandleFunc("GET /health", p.handleHealth)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving on %s (POST /query, GET /health)\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return nil
+}
+
+// handleQuery answers a question with its sources
+func (p *pipeline) handleQuery(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), p.cfg.Chat.Timeout)
+	defer cancel()
+	ctx, requestID := trace.Start(ctx)
+	w.Header().Set("X-Request-ID", requestID)
+
+	var req queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("query is required"))
+		return
+	}
+
+	prepared, err := p.prepare(ctx, req.Query, req.Model)
+	if err != nil {
+		err = ollama.CheckTimeout(ctx, err, p.cfg.Chat.Timeout)
+		code := http.StatusInternalServerError
+		if errors.Is(err, ollama.ErrGenerationTimeout) {
+			code = http.StatusGatewayTimeout
+		}
+		writeError(w, code, trace.Error(ctx, err))
+		return
+	}
+
+	truncated := false
+	answer, ok := p.cachedAnswer(ctx, prepared)
+	if p.refuses(prepared) {
+		answer, ok = rag.NoSourcesAnswer, true
+	}
+	if !ok {
+		answer, err = p.ollamaClient.Generate(ctx, &ollama.GenerateRequest{
+			Model:  prepared.model,
+			System: prepared.system,
+			Prompt: prepared.prompt,
+		})
+		switch {
+		case errors.Is(err, ollama.ErrTruncatedResponse) && answer != "":
+			truncated = true
+		case err != nil:
+			err = ollama.CheckTimeout(ctx, err, p.cfg.Chat.Timeout)
+			code := http.StatusBadGateway
+			if errors.Is(err, ollama.ErrGenerationTimeout) {
+				code = http.StatusGatewayTimeout
+			}
+			writeError(w, code, trace.Error(ctx, fmt.Errorf("failed to generate answer: %w", err)))
+			return
+		default:
+			p.storeAnswer(ctx, prepared, answer)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, queryResult{
+		Query:            req.Query,
+		Model:            prepared.model,
+		Answer:           answer,
+		Sources:          prepared.sources,
+		Truncated:        truncated,
+		ContextTruncated: prepared.contextTruncated,
+		NoSources:        prepared.noSources,
+	})
+}
+
+// handleHealth reports database and Ollama reachability. It responds 503
+// when either is down.
+func (p *pipeline) handleHealth(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	health := healthResponse{
+		Status:   "ok",
+		Database: componentStatus{OK: true},
+		Ollama:   componentStatus{OK: true},
+	}
+	if err := p.db.Ping(ctx); err != nil {
+		health.Database = componentStatus{Error: err.Error()}
+	}
+	if _, err := p.modelSelector.ListModels(ctx); err != nil {
+		health.Ollama = componentStatus{Error: err.Error()}
+	}
+
+	code := http.StatusOK
+	if !health.Database.OK || !health.Ollama.OK {
+		health.Status = "degraded"
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, health)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON {"error": ...} response
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
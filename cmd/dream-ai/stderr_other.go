@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// captureStderr does nothing on platforms without dup2; library warnings
+// go to the terminal
+func captureStderr(discard bool) func() {
+	return func() {}
+}
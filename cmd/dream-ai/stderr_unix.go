@@ -0,0 +1,126 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dream-ai/cli/internal/documents"
+	"golang.org/x/sys/unix"
+)
+
+// syncMarker starts the lines stderrSync prints to find where the output
+// read so far ends
+const syncMarker = "\x00dream-ai-sync "
+
+// captureStderr points file descriptor 2, where C libraries such as MuPDF
+// print their warnings, at a pipe, so they cannot draw over the TUI. Lines
+// printed while a document is processed go to its processing log; the rest
+// are written to the log, or dropped if discard is set. This is done once
+// for the whole process rather than around each document, so it is safe
+// however many documents are processed at once. Panics and fatal runtime
+// errors are still printed on the terminal. The returned function points
+// descriptor 2 back at the terminal.
+func captureStderr(discard bool) func() {
+	saved, err := unix.Dup(2)
+	if err != nil {
+		log.Printf("warning: library output not captured: %v", err)
+		return func() {}
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		unix.Close(saved)
+		log.Printf("warning: library output not captured: %v", err)
+		return func() {}
+	}
+	terminal := os.NewFile(uintptr(saved), "stderr")
+	// The runtime writes crash reports to descriptor 2, which is about to
+	// be the pipe, and exits before they could be read from it
+	if err := debug.SetCrashOutput(terminal, debug.CrashOptions{}); err != nil {
+		log.Printf("warning: crash reports may be lost: %v", err)
+	}
+	if err := unix.Dup2(int(w.Fd()), 2); err != nil {
+		debug.SetCrashOutput(nil, debug.CrashOptions{})
+		terminal.Close()
+		r.Close()
+		w.Close()
+		log.Printf("warning: library output not captured: %v", err)
+		return func() {}
+	}
+	// Descriptor 2 now holds the write end, which closes when it is restored
+	w.Close()
+
+	s := &stderrSync{waiting: make(map[int]chan struct{})}
+	go func() {
+		defer r.Close()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if id, ok := strings.CutPrefix(line, syncMarker); ok {
+				s.done(id)
+				continue
+			}
+			if !documents.LibraryOutputLine(line) && !discard {
+				log.Printf("library: %s", line)
+			}
+		}
+	}()
+	documents.SetLibraryOutputSync(s.sync)
+
+	return func() {
+		documents.SetLibraryOutputSync(nil)
+		unix.Dup2(saved, 2)
+		debug.SetCrashOutput(nil, debug.CrashOptions{})
+		terminal.Close()
+	}
+}
+
+// stderrSync lets a caller wait until the captured output printed before it
+// has been read, by printing a numbered marker line and waiting for it to
+// come out of the pipe
+type stderrSync struct {
+	mu      sync.Mutex
+	next    int
+	waiting map[int]chan struct{}
+}
+
+// sync prints a marker and waits, for at most a second, for it to be read
+func (s *stderrSync) sync() {
+	s.mu.Lock()
+	id := s.next
+	s.next++
+	read := make(chan struct{})
+	s.waiting[id] = read
+	s.mu.Unlock()
+
+	fmt.Fprintf(os.Stderr, "%s%d\n", syncMarker, id)
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		s.mu.Lock()
+		delete(s.waiting, id)
+		s.mu.Unlock()
+	}
+}
+
+// done releases the caller waiting for the marker with the given number
+func (s *stderrSync) done(marker string) {
+	id, err := strconv.Atoi(marker)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if read, ok := s.waiting[id]; ok {
+		close(read)
+		delete(s.waiting, id)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dream-ai/cli/config"
+	"github.com/dream-ai/cli/internal/ollama"
+)
+
+// runSummary retrieves the topK most relevant passages on a topic across the
+// whole corpus and asks the model for a structured overview citing them by
+// source number. The numbered sources are listed after the summary.
+func runSummary(cfg *config.Config, topic string, topK int, asJSON bool) error {
+	p, err := newPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Chat.Timeout)
+	defer cancel()
+	model, err := p.modelSelector.GetDefaultModel(ctx, cfg.Ollama.DefaultModel)
+	if err != nil {
+		return fmt.Errorf("failed to select model: %w", err)
+	}
+
+	result, err := p.retriever.RetrieveN(ctx, topic, topK)
+	if err != nil {
+		return err
+	}
+	if len(result.Chunks) == 0 {
+		return fmt.Errorf("no passages found for %q; process some documents first", topic)
+	}
+
+	builder := p.contextBuilder
+	if numCtx, err := p.ollamaClient.ContextLength(ctx, model); err == nil {
+		builder = builder.FitToWindow(numCtx, cfg.RAG.ContextWindowFraction)
+	}
+	groups := p.retriever.GroupBySource(ctx, result.Chunks)
+	prompt := builder.BuildSummaryPrompt(topic, groups)
+
+	sources := make([]string, 0, len(groups))
+	for i, group := range groups {
+		sources = append(sources, fmt.Sprintf("[%d] %s (%d passages)", i+1, group.Source, len(group.Chunks)))
+	}
+
+	var answer strings.Builder
+	err = p.ollamaClient.GenerateStream(ctx, &ollama.GenerateRequest{
+		Model:  model,
+		System: builder.BuildSummarySystemPrompt(),
+		Prompt: prompt,
+	}, func(chunk string) {
+		if asJSON {
+			answer.WriteString(chunk)
+			return
+		}
+		fmt.Print(chunk)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate summary: %w", ollama.CheckTimeout(ctx, err, cfg.Chat.Timeout))
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(queryResult{
+			Query:   topic,
+			Model:   model,
+			Answer:  answer.String(),
+			Sources: sources,
+		})
+	}
+
+	fmt.Println()
+	fmt.Println("\nSources:")
+	for _, source := range sources {
+		fmt.Printf("  %s\n", source)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dream-ai/cli/config"
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/ollama"
+)
+
+// maxWaitBackoff caps the pause between attempts to reach a dependency
+const maxWaitBackoff = 10 * time.Second
+
+// waitForDependencies blocks until the database and Ollama both respond,
+// retrying with doubling backoff for up to startup.wait_timeout, so the app
+// can start alongside them rather than exit because they are not up yet
+func waitForDependencies(cfg *config.Config) error {
+	deadline := time.Now().Add(cfg.Startup.WaitTimeout)
+	ollamaClient := ollama.NewClient(cfg.Ollama.BaseURL)
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"database", func(ctx context.Context) error {
+			// Reachable is enough; a schema not migrated yet is reported
+			// once the app starts
+			database, err := db.Connect(cfg.Database.ConnectionString, cfg.Database.Schema)
+			if err != nil {
+				return err
+			}
+			database.Close()
+			return nil
+		}},
+		{"Ollama", func(ctx context.Context) error {
+			_, err := ollama.NewModelSelector(ollamaClient).ListModels(ctx)
+			return err
+		}},
+	}
+
+	for _, c := range checks {
+		backoff := time.Second
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := c.check(ctx)
+			cancel()
+			if err == nil {
+				break
+			}
+			if time.Now().Add(backoff).After(deadline) {
+				return fmt.Errorf("gave up waiting for %s after %s: %w", c.name, cfg.Startup.WaitTimeout, err)
+			}
+			fmt.Fprintf(os.Stderr, "Waiting for %s (retrying in %s): %v\n", c.name, backoff, err)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxWaitBackoff)
+		}
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,27 +14,264 @@ import (
 type Config struct {
 	Database struct {
 		ConnectionString string `yaml:"connection_string"`
+		Schema           string `yaml:"schema,omitempty"` // Postgres schema holding the tables; empty uses public
+		// Corpora are schemas, each a separately migrated set of tables,
+		// that can be made the active corpus at runtime with Ctrl+O
+		Corpora []string `yaml:"corpora,omitempty"`
 	} `yaml:"database"`
 	Ollama struct {
 		BaseURL      string `yaml:"base_url"`
 		DefaultModel string `yaml:"default_model"`
+		// StripThinkTags removes reasoning blocks (e.g. <think>...</think>)
+		// from chat answers; they can still be shown with Ctrl+T
+		StripThinkTags bool     `yaml:"strip_think_tags"`
+		ThinkTags      []string `yaml:"think_tags"`
+		// MaxConcurrentGenerations caps generation requests in flight at
+		// once; further requests wait for one to finish
+		MaxConcurrentGenerations int `yaml:"max_concurrent_generations"`
 	} `yaml:"ollama"`
 	Embeddings struct {
+		// TextModel is an Ollama embedding model, or a comma-separated list
+		// of fallbacks to try in order
 		TextModel string `yaml:"text_model"`
+		Dimension int    `yaml:"dimension"` // Vector dimension produced by text_model
+		// OnModelChange is what ingestion does when text_model no longer
+		// includes the model the index was built with: refuse, or warn and
+		// ingest anyway (as -force does)
+		OnModelChange string `yaml:"on_model_change"`
+		// OnDimensionMismatch is what the TUI does when a search fails
+		// because the stored embeddings differ in dimension from the
+		// query's: prompt to rebuild them, or rebuild straight away
+		OnDimensionMismatch string `yaml:"on_dimension_mismatch"`
+		// Timeout bounds each embedding request, so an unresponsive Ollama
+		// fails the document instead of hanging the import
+		Timeout time.Duration `yaml:"timeout"`
 	} `yaml:"embeddings"`
 	Processing struct {
 		ChunkSize    int `yaml:"chunk_size"`
-		ChunkOverlap int `yaml:"chunk_overlap"`
+		// ChunkOverlapPercent is the percent of each chunk repeated at the
+		// start of the next, 0-90
+		ChunkOverlapPercent int `yaml:"chunk_overlap_percent"`
+		// LegacyChunkOverlap is the pre-rename chunk_overlap key, read so
+		// old configs keep working; Load moves it to ChunkOverlapPercent
+		LegacyChunkOverlap *int `yaml:"chunk_overlap,omitempty"`
+		MinChunkChars int `yaml:"min_chunk_chars"` // Shorter trailing chunks are merged into the previous one; 0 disables this
 		TopK         int `yaml:"top_k"`
+		ImageFormat  string `yaml:"image_format"` // png or jpeg, for rendered page images
+		JPEGQuality  int    `yaml:"jpeg_quality"` // 1-100, used when image_format is jpeg
+		ThumbnailSize int   `yaml:"thumbnail_size"` // Longer side of image previews in pixels; 0 disables them
+		ImageConcurrency int `yaml:"image_concurrency"` // Images captioned and embedded at once, each by its own CLIP process
+		InsertBatchSize int `yaml:"insert_batch_size"` // Chunk and image rows inserted and committed per batch
+		QuickScan bool `yaml:"quick_scan"` // Skip hashing processed files whose size and modification time are unchanged
+		// Batch imports retry documents that fail transiently (network,
+		// timeout) up to RetryAttempts times in total, doubling the backoff
+		RetryAttempts int           `yaml:"retry_attempts"`
+		RetryBackoff  time.Duration `yaml:"retry_backoff"`
+		// SummarizeDocuments has the chat model summarize each document at
+		// ingestion; the embedded summaries let retrieval find the relevant
+		// documents first, then search their chunks
+		SummarizeDocuments bool `yaml:"summarize_documents"`
+		// Normalize cleans up extracted text before chunking; each rule can
+		// be turned off separately
+		Normalize struct {
+			Dehyphenate        bool `yaml:"dehyphenate"`         // "inter-\npretation" -> "interpretation"
+			CollapseWhitespace bool `yaml:"collapse_whitespace"` // Drop invisible characters, squeeze blank space
+			StripPageNumbers   bool `yaml:"strip_page_numbers"`  // Remove lines such as "12" or "Page 12"
+		} `yaml:"normalize"`
 	} `yaml:"processing"`
+	RAG struct {
+		ContextOrder     string `yaml:"context_order"`      // relevance, reverse, or alternating
+		MaxContextLength int    `yaml:"max_context_length"` // Context budget in tokens
+		// AdaptiveTopK retrieves as many chunks as fit max_context_length,
+		// up to MaxTopK, in place of processing.top_k
+		AdaptiveTopK bool `yaml:"adaptive_top_k"`
+		MaxTopK      int  `yaml:"max_top_k"`
+		// ContextWindowFraction caps the budget to this share of the active
+		// model's context window (from /api/show); 0 disables the cap
+		ContextWindowFraction float64 `yaml:"context_window_fraction"`
+		SummaryTopK           int     `yaml:"summary_top_k"` // Chunks retrieved for -summarize and sampled for document summaries
+		// SummaryDocuments is how many documents, found by summary, have
+		// their chunks searched when processing.summarize_documents is on
+		SummaryDocuments int `yaml:"summary_documents"`
+		// CacheAnswers reuses the stored answer when the same question is
+		// asked of the same model with the same retrieved chunks
+		CacheAnswers bool `yaml:"cache_answers"`
+		// Reranker reorders search candidates: "" (none) or "keyword".
+		// CandidateMultiplier fetches topK times this many candidates for it
+		// to choose from, trading compute for recall.
+		Reranker            string `yaml:"reranker"`
+		CandidateMultiplier int    `yaml:"candidate_multiplier"`
+		// Mode is how questions are searched: vector, hybrid (vector
+		// results filtered by keyword) or auto (hybrid only for questions
+		// naming rare terms such as proper nouns)
+		Mode string `yaml:"mode"`
+		// Persona opens the system prompt sent with every question; empty
+		// uses the built-in dream interpretation expert
+		Persona string `yaml:"persona"`
+		// PromptTemplates lay out the question for models whose names match
+		// their patterns; the first match wins and other models get the
+		// built-in layout
+		PromptTemplates []PromptTemplate `yaml:"prompt_templates,omitempty"`
+		// NeighborWindow includes this many chunks before and after each
+		// retrieved chunk in the context, from the same document
+		NeighborWindow int `yaml:"neighbor_window"`
+		// DedupeResults drops retrieved chunks that repeat an earlier
+		// result's text (or nearly its embedding), refilling top_k
+		DedupeResults bool `yaml:"dedupe_results"`
+		// MultiQuery asks the chat model for MultiQueryCount paraphrases of
+		// each question and fuses their search results with the question's
+		MultiQuery      bool `yaml:"multi_query"`
+		MultiQueryCount int  `yaml:"multi_query_count"`
+		// Questions longer than MaxQueryChars are embedded for search as
+		// LongQuery says: "average" (in pieces) or "summarize" (a summary by
+		// the chat model); the model still answers the whole question
+		MaxQueryChars int    `yaml:"max_query_chars"`
+		LongQuery     string `yaml:"long_query"`
+		// ImageSearch also searches images, embedding each query with
+		// CLIP2's text encoder (a Python run per question)
+		ImageSearch bool `yaml:"image_search"`
+		// ImageMaxDistance leaves out found images farther than this cosine
+		// distance from the question; 0 keeps the top_k images however
+		// weakly they match
+		ImageMaxDistance float64 `yaml:"image_max_distance"`
+		// IncludeImages describes retrieved images to the model as
+		// numbered captions
+		IncludeImages bool `yaml:"include_images"`
+		// NoResults is what happens when retrieval finds nothing: "answer"
+		// from the model's general knowledge, noted as such, or "refuse"
+		NoResults string `yaml:"no_results"`
+		// LabelSources names each excerpt's source document in the context
+		LabelSources bool `yaml:"label_sources"`
+		// MarkGeneralKnowledge asks the model to tag what it draws from its
+		// own knowledge, which the chat shows dimmed
+		MarkGeneralKnowledge bool `yaml:"mark_general_knowledge"`
+		// DebugPreviewChars cuts chunks shown in the debug panel and trace
+		// log to this many characters; 0 shows them whole
+		DebugPreviewChars int `yaml:"debug_preview_chars"`
+	} `yaml:"rag"`
 	CLIP2 struct {
 		PythonPath string `yaml:"python_path"`
 		ScriptPath string `yaml:"script_path"`
+		// CaptionLanguage is the language image captions are stored in, to
+		// match the documents; captions the script cannot write in it are
+		// translated by the chat model. Empty keeps the captioner's own.
+		CaptionLanguage string `yaml:"caption_language"`
+		// CaptionOnly stores image captions, embedded with the text model
+		// so text search finds them, and no CLIP image vectors
+		CaptionOnly bool `yaml:"caption_only"`
 	} `yaml:"clip2"`
+	// Profiles select fully isolated indexes, chosen with -profile
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+	// Profile is the name of the profile applied, if any
+	Profile string `yaml:"-"`
 	Paths struct {
 		DocumentsDirs []string `yaml:"documents_dirs"` // Multiple document directories
 		ImageDir      string   `yaml:"image_dir"`
+		Watch         bool     `yaml:"watch"` // Auto-ingest new files added to documents dirs
+		Exclude       []string `yaml:"exclude"` // Glob patterns for files never ingested, e.g. "sample*.pdf"
+		ExportDir     string   `yaml:"export_dir"` // Where extracted document text is written
 	} `yaml:"paths"`
+	Display struct {
+		// CleanTitles shows file names as readable titles when a document
+		// has no metadata title
+		CleanTitles bool `yaml:"clean_titles"`
+		// UsageSummary prints documents processed, chats sent and token
+		// counts for the session when the TUI exits
+		UsageSummary bool `yaml:"usage_summary"`
+		// DocumentSort is the initial order of the Documents view: date,
+		// name, type or status
+		DocumentSort string `yaml:"document_sort"`
+		// DocumentsPerPage pages the Documents view; 0 lists every document
+		DocumentsPerPage int `yaml:"documents_per_page"`
+		// HistoryPerPage pages the History view; 0 lists every conversation
+		HistoryPerPage int `yaml:"history_per_page"`
+		// Theme is the built-in color theme: dark, light or high-contrast
+		Theme string `yaml:"theme"`
+		// Colors overrides the theme's color for a role (text, muted,
+		// accent, emphasis, success, warning, error, border, background,
+		// banner) with a color name or #rrggbb
+		Colors map[string]string `yaml:"colors,omitempty"`
+		// Wrap wraps long lines in text panes instead of clipping them
+		Wrap bool `yaml:"wrap"`
+		// CorpusLabel is what the TUI calls a corpus, e.g. "Knowledge base"
+		CorpusLabel string `yaml:"corpus_label"`
+	} `yaml:"display"`
+	Logging struct {
+		// Trace logs when each processing, retrieval and generation
+		// operation starts and finishes, tagged with its request ID
+		Trace bool `yaml:"trace"`
+		// LibraryOutput is where warnings printed by the PDF library and
+		// other C code go while the TUI runs: log or discard
+		LibraryOutput string `yaml:"library_output"`
+	} `yaml:"logging"`
+	Chat struct {
+		// Timeout bounds answering a question, from retrieval to the end
+		// of generation; raise it for large models running on CPU
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"chat"`
+	Startup struct {
+		// WaitForDependencies retries reaching the database and Ollama at
+		// startup, with doubling backoff, for up to WaitTimeout before
+		// giving up
+		WaitForDependencies bool          `yaml:"wait_for_dependencies"`
+		WaitTimeout         time.Duration `yaml:"wait_timeout"`
+	} `yaml:"startup"`
+	Metrics struct {
+		// Enabled appends a record of each TUI session (query count,
+		// retrieval and generation latency, cache hit rate, tokens) to File
+		// on quit. It is off by default and nothing is sent anywhere.
+		Enabled bool   `yaml:"enabled"`
+		File    string `yaml:"file"` // JSON Lines file, one session per line
+	} `yaml:"metrics"`
+	// ReadOnly disables everything in the TUI that changes documents, the
+	// index or settings, leaving chat and search; -readonly sets it
+	ReadOnly bool `yaml:"read_only"`
+}
+
+// Profile is a named (schema, embedding model, dimension) triple giving an
+// isolated index, e.g. for A/B comparison of embedding setups
+type Profile struct {
+	Schema    string `yaml:"schema"`
+	TextModel string `yaml:"text_model"`
+	Dimension int    `yaml:"dimension"`
+}
+
+// PromptTemplate is the prompt layout for chat models whose names match
+// Match, a glob such as "llama3*". {context} and {question} in Template are
+// replaced with the retrieved context and the user's question.
+type PromptTemplate struct {
+	Match    string `yaml:"match"`
+	Template string `yaml:"template"`
+}
+
+// ApplyProfile overrides the database schema and embedding settings with
+// those of the named profile
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+	if profile.Schema == "" {
+		return fmt.Errorf("profile %s has no schema", name)
+	}
+
+	c.Profile = name
+	c.Database.Schema = profile.Schema
+	if profile.TextModel != "" {
+		c.Embeddings.TextModel = profile.TextModel
+	}
+	if profile.Dimension > 0 {
+		c.Embeddings.Dimension = profile.Dimension
+	}
+	return nil
+}
+
+// MigrateCommand returns the command that migrates the configured schema
+func (c *Config) MigrateCommand() string {
+	if c.Profile != "" {
+		return "dream-ai -profile " + c.Profile + " -migrate"
+	}
+	return "dream-ai -migrate"
 }
 
 // Load loads configuration from file or returns defaults
@@ -52,10 +291,40 @@ func Load() (*Config, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return cfg, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := cfg.migrateChunkOverlap(data); err != nil {
+		return cfg, err
+	}
 
 	return cfg, nil
 }
 
+// migrateChunkOverlap moves the old processing.chunk_overlap key, already a
+// percentage, to processing.chunk_overlap_percent. The new key wins when a
+// config sets both.
+func (c *Config) migrateChunkOverlap(data []byte) error {
+	legacy := c.Processing.LegacyChunkOverlap
+	if legacy == nil {
+		return nil
+	}
+	c.Processing.LegacyChunkOverlap = nil
+
+	var probe struct {
+		Processing struct {
+			ChunkOverlapPercent *int `yaml:"chunk_overlap_percent"`
+		} `yaml:"processing"`
+	}
+	if err := yaml.Unmarshal(data, &probe); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if probe.Processing.ChunkOverlapPercent != nil {
+		log.Printf("warning: processing.chunk_overlap is deprecated and ignored in favor of chunk_overlap_percent")
+		return nil
+	}
+	log.Printf("warning: processing.chunk_overlap is deprecated; rename it to chunk_overlap_percent")
+	c.Processing.ChunkOverlapPercent = *legacy
+	return nil
+}
+
 // Save saves configuration to file
 func (c *Config) Save() error {
 	configDir := filepath.Join(os.Getenv("HOME"), ".dream-ai")
@@ -72,6 +341,18 @@ func (c *Config) Save() error {
 	return os.WriteFile(configPath, data, 0644)
 }
 
+// Update re-reads the config file, applies edit to it and saves it. Values
+// the process set on its loaded config, such as a profile's schema, -force
+// or a detected script path, are not written back.
+func Update(edit func(c *Config)) error {
+	c, err := Load()
+	if err != nil {
+		return err
+	}
+	edit(c)
+	return c.Save()
+}
+
 // Default returns default configuration
 func Default() *Config {
 	cfg := &Config{}
@@ -79,10 +360,41 @@ func Default() *Config {
 	cfg.Database.ConnectionString = "postgres://postgres@localhost/postgres?sslmode=disable"
 	cfg.Ollama.BaseURL = "http://localhost:11434"
 	cfg.Ollama.DefaultModel = ""
+	cfg.Ollama.StripThinkTags = false
+	cfg.Ollama.ThinkTags = []string{"think"}
+	cfg.Ollama.MaxConcurrentGenerations = 1
 	cfg.Embeddings.TextModel = "nomic-embed-text"
+	cfg.Embeddings.Dimension = 768
+	cfg.Embeddings.OnModelChange = "refuse"
+	cfg.Embeddings.OnDimensionMismatch = "prompt"
+	cfg.Embeddings.Timeout = 30 * time.Second
 	cfg.Processing.ChunkSize = 512
-	cfg.Processing.ChunkOverlap = 50
+	cfg.Processing.ChunkOverlapPercent = 50
 	cfg.Processing.TopK = 5
+	cfg.Processing.ImageFormat = "png"
+	cfg.Processing.JPEGQuality = 85
+	cfg.Processing.ThumbnailSize = 256
+	cfg.Processing.ImageConcurrency = 1
+	cfg.Processing.InsertBatchSize = 100
+	cfg.Processing.Normalize.Dehyphenate = true
+	cfg.Processing.Normalize.CollapseWhitespace = true
+	cfg.Processing.RetryAttempts = 3
+	cfg.Processing.RetryBackoff = 2 * time.Second
+	cfg.RAG.ContextOrder = "relevance"
+	cfg.RAG.MaxContextLength = 2000
+	cfg.RAG.MaxTopK = 20
+	cfg.RAG.ContextWindowFraction = 0.5
+	cfg.RAG.SummaryTopK = 20
+	cfg.RAG.SummaryDocuments = 5
+	cfg.RAG.Mode = "vector"
+	cfg.RAG.CandidateMultiplier = 1
+	cfg.RAG.IncludeImages = true
+	cfg.RAG.NoResults = "answer"
+	cfg.RAG.LabelSources = true
+	cfg.RAG.DebugPreviewChars = 200
+	cfg.RAG.MultiQueryCount = 3
+	cfg.RAG.MaxQueryChars = 2000
+	cfg.RAG.LongQuery = "average"
 	cfg.CLIP2.PythonPath = "python3"
 	cfg.CLIP2.ScriptPath = ""
 	
@@ -92,6 +404,18 @@ func Default() *Config {
 		filepath.Join(homeDir, ".config", "dream-ai", "documents"),
 	}
 	cfg.Paths.ImageDir = filepath.Join(os.TempDir(), "dream-ai-images")
+	cfg.Paths.ExportDir = filepath.Join(homeDir, ".dream-ai", "exports")
+	cfg.Display.CleanTitles = true
+	cfg.Display.DocumentSort = "date"
+	cfg.Display.DocumentsPerPage = 100
+	cfg.Display.HistoryPerPage = 50
+	cfg.Display.Theme = "dark"
+	cfg.Display.Wrap = true
+	cfg.Display.CorpusLabel = "Corpus"
+	cfg.Logging.LibraryOutput = "log"
+	cfg.Chat.Timeout = 5 * time.Minute
+	cfg.Startup.WaitTimeout = time.Minute
+	cfg.Metrics.File = filepath.Join(homeDir, ".dream-ai", "metrics.jsonl")
 	
 	return cfg
 }
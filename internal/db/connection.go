@@ -2,19 +2,56 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	pgxvec "github.com/pgvector/pgvector-go/pgx"
 )
 
+// defaultInsertBatchSize is the rows sent per batch by the batch inserts
+const defaultInsertBatchSize = 100
+
+// schemaKey is where a connection's custom data records the schema its
+// search_path was set for
+const schemaKey = "schema"
+
 // DB wraps the database connection pool
 type DB struct {
 	pool *pgxpool.Pool
+
+	schemaMu sync.RWMutex
+	schema   string
+
+	insertBatchSize int // Rows per batch in InsertChunksBatch and InsertImagesBatch
 }
 
-// New creates a new database connection
-func New(connString string) (*DB, error) {
+// ErrNotMigrated is returned when the schema to use has no tables yet
+var ErrNotMigrated = errors.New("schema has not been migrated")
+
+// New creates a new database connection. If schema is set, it is created if
+// needed and every connection resolves tables in it before public (where
+// the vector extension lives). SetSchema changes it later. The schema must
+// hold migrated tables, or ErrNotMigrated is returned, so that an isolated
+// profile never falls through to the tables in public.
+func New(connString, schema string) (*DB, error) {
+	db, err := Connect(connString, schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.checkMigrated(context.Background(), schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Connect creates a new database connection like New, without requiring
+// the schema to be migrated, for running the migrations
+func Connect(connString, schema string) (*DB, error) {
 	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse connection string: %w", err)
@@ -24,6 +61,41 @@ func New(connString string) (*DB, error) {
 	config.MaxConnLifetime = time.Hour
 	config.MaxConnIdleTime = time.Minute * 30
 
+	db := &DB{schema: schema, insertBatchSize: defaultInsertBatchSize}
+
+	// Register the vector type on each new connection, for COPY's binary
+	// format. Before the migrations have created the extension there is
+	// none to register.
+	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, `SELECT to_regtype('vector') IS NOT NULL`).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check for the vector type: %w", err)
+		}
+		if !exists {
+			return nil
+		}
+		return pgxvec.RegisterTypes(ctx, conn)
+	}
+
+	// Connections are pointed at the current schema as they are acquired,
+	// so a switch applies to pooled connections too
+	config.PrepareConn = func(ctx context.Context, conn *pgx.Conn) (bool, error) {
+		schema := db.Schema()
+		data := conn.PgConn().CustomData()
+		if current, ok := data[schemaKey].(string); ok && current == schema {
+			return true, nil
+		}
+		searchPath := "DEFAULT"
+		if schema != "" {
+			searchPath = pgx.Identifier{schema}.Sanitize() + ", public"
+		}
+		if _, err := conn.Exec(ctx, "SET search_path TO "+searchPath); err != nil {
+			return false, fmt.Errorf("failed to set search_path: %w", err)
+		}
+		data[schemaKey] = schema
+		return true, nil
+	}
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
@@ -34,10 +106,30 @@ func New(connString string) (*DB, error) {
 	defer cancel()
 
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{pool: pool}, nil
+	if schema != "" {
+		if _, err := pool.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{schema}.Sanitize()); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to create schema %s: %w", schema, err)
+		}
+	}
+
+	db.pool = pool
+	return db, nil
+}
+
+// SetInsertBatchSize sets how many rows the batch inserts send and commit
+// at a time. Smaller batches hold locks for less time and lose less work
+// on a late failure; larger ones make fewer round trips. Values below 1
+// restore the default.
+func (db *DB) SetInsertBatchSize(n int) {
+	if n < 1 {
+		n = defaultInsertBatchSize
+	}
+	db.insertBatchSize = n
 }
 
 // Pool returns the underlying connection pool
@@ -45,6 +137,56 @@ func (db *DB) Pool() *pgxpool.Pool {
 	return db.pool
 }
 
+// Schema returns the schema the connection targets, or "" for public
+func (db *DB) Schema() string {
+	db.schemaMu.RLock()
+	defer db.schemaMu.RUnlock()
+	return db.schema
+}
+
+// SetSchema switches the connection to another schema ("" for public), so
+// that later queries read and write its tables. The schema must already
+// hold migrated tables. Queries in flight finish against the old schema.
+func (db *DB) SetSchema(ctx context.Context, schema string) error {
+	if err := db.checkMigrated(ctx, schema); err != nil {
+		if errors.Is(err, ErrNotMigrated) {
+			return fmt.Errorf("%w; run the migrations for it first", err)
+		}
+		return err
+	}
+
+	db.schemaMu.Lock()
+	db.schema = schema
+	db.schemaMu.Unlock()
+	return nil
+}
+
+// checkMigrated returns ErrNotMigrated unless schema ("" for public) holds
+// the documents table
+func (db *DB) checkMigrated(ctx context.Context, schema string) error {
+	name := schema
+	if name == "" {
+		name = "public"
+	}
+	var migrated bool
+	err := db.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = 'documents')`,
+		name,
+	).Scan(&migrated)
+	if err != nil {
+		return fmt.Errorf("failed to check schema %s: %w", name, err)
+	}
+	if !migrated {
+		return fmt.Errorf("%w: schema %s has no tables", ErrNotMigrated, name)
+	}
+	return nil
+}
+
+// Ping checks that the database is reachable
+func (db *DB) Ping(ctx context.Context) error {
+	return db.pool.Ping(ctx)
+}
+
 // Close closes the database connection pool
 func (db *DB) Close() {
 	db.pool.Close()
@@ -0,0 +1,381 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnrecordedSchema is returned by Migrate when the tables exist but no
+// migrations are recorded, as when they were created by running the SQL
+// files by hand; DetectBaseline finds, and Baseline records, those already
+// applied
+var ErrUnrecordedSchema = errors.New("schema was migrated without recording it")
+
+// Migration is one numbered schema change, read from a pair of
+// NNNNN_name.up.sql and NNNNN_name.down.sql files
+type Migration struct {
+	Version int
+	Name    string // File name without .up.sql, e.g. 00001_init_schema
+	Up      string
+	Down    string
+}
+
+// LoadMigrations reads the migrations in files, in version order
+func LoadMigrations(files fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range names {
+		name, up := strings.CutSuffix(file, ".up.sql")
+		if !up {
+			var down bool
+			if name, down = strings.CutSuffix(file, ".down.sql"); !down {
+				continue
+			}
+		}
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: name does not start with a version number", file)
+		}
+		sql, err := fs.ReadFile(files, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", m.Name, name, version)
+		}
+		if up {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no .up.sql", m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ensureMigrationsTable creates the vector extension, in public where every
+// schema finds it, and the table recording applied migrations in the current
+// schema
+func (db *DB) ensureMigrationsTable(ctx context.Context) error {
+	if _, err := db.pool.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS vector WITH SCHEMA public`); err != nil {
+		return fmt.Errorf("failed to create vector extension: %w", err)
+	}
+	_, err := db.pool.Exec(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+		     version INTEGER PRIMARY KEY,
+		     name TEXT NOT NULL,
+		     applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+		 )`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// AppliedMigrations returns the versions recorded in schema_migrations
+func (db *DB) AppliedMigrations(ctx context.Context) (map[int]bool, error) {
+	rows, err := db.pool.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// Migrate applies the migrations not yet recorded in schema_migrations, in
+// version order, and returns those it applied. Each runs in a transaction
+// with its record, so a failing migration is rolled back and stops the run
+// with the ones before it kept.
+func (db *DB) Migrate(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := db.AppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		exists, err := db.hasTable(ctx, "documents")
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: the documents table exists but schema_migrations is empty; run -migrate -migrate-baseline N, N being the last migration applied by hand", ErrUnrecordedSchema)
+		}
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := db.applyMigration(ctx, m); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// applyMigration runs a migration's up SQL and records it, in one transaction
+func (db *DB) applyMigration(ctx context.Context, m Migration) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", m.Name, err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Without arguments the SQL is sent as a simple query, which may hold
+	// several statements
+	if _, err := tx.Exec(ctx, m.Up); err != nil {
+		return fmt.Errorf("migration %s failed: %w", m.Name, err)
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+		m.Version, m.Name,
+	); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+// Rollback reverts the applied migrations above version target, newest
+// first, and returns those it reverted. Each down migration runs in a
+// transaction with the removal of its record; one that fails, or has no
+// .down.sql, stops the rollback with the ones before it kept.
+func (db *DB) Rollback(ctx context.Context, migrations []Migration, target int) ([]Migration, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := db.AppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+	var versions []int
+	for version := range applied {
+		if version > target {
+			versions = append(versions, version)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	var done []Migration
+	for _, version := range versions {
+		m, ok := byVersion[version]
+		if !ok {
+			return done, fmt.Errorf("migration %d is applied but not known to this build", version)
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %s has no .down.sql", m.Name)
+		}
+		if err := db.revertMigration(ctx, m); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// revertMigration runs a migration's down SQL and removes its record, in one
+// transaction
+func (db *DB) revertMigration(ctx context.Context, m Migration) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin rollback of %s: %w", m.Name, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, m.Down); err != nil {
+		return fmt.Errorf("rollback of %s failed: %w", m.Name, err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+		return fmt.Errorf("failed to remove record of migration %s: %w", m.Name, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rollback of %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+// Baseline records the migrations up to and including version as applied
+// without running them, for a schema created by running the SQL files by
+// hand
+func (db *DB) Baseline(ctx context.Context, migrations []Migration, version int) error {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+	batch := &pgx.Batch{}
+	for _, m := range migrations {
+		if m.Version <= version {
+			batch.Queue(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING`, m.Version, m.Name)
+		}
+	}
+	if err := db.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to record baseline migrations: %w", err)
+	}
+	return nil
+}
+
+// migrationMarkers tell whether each migration has been applied to a schema
+// that was migrated by hand: by a table or column it adds, or a constraint
+// it drops. A new migration needs a marker for DetectBaseline to see it.
+var migrationMarkers = map[int]func(ctx context.Context, db *DB) (bool, error){
+	1:  tableMarker("documents"),
+	2:  columnMarker("documents", "error_message"),
+	3:  columnMarker("chunks", "tags"),
+	4:  columnMarker("documents", "title"),
+	5:  columnMarker("chunks", "section"),
+	6:  columnMarker("documents", "chunk_overlap"),
+	7:  tableMarker("answer_cache"),
+	8:  columnMarker("documents", "processing_log"),
+	9:  columnMarker("images", "thumbnail_path"),
+	10: tableMarker("import_progress"),
+	11: columnMarker("documents", "embedding_model"),
+	12: tableMarker("document_summaries"),
+	13: columnMarker("images", "source_location"),
+	14: columnMarker("images", "caption_embedding"),
+	15: columnMarker("documents", "file_mtime"),
+	16: columnMarker("images", "status"),
+	17: func(ctx context.Context, db *DB) (bool, error) {
+		exists, err := db.hasConstraint(ctx, "documents", "documents_file_type_check")
+		return !exists, err
+	},
+}
+
+// tableMarker returns a marker that checks for a table
+func tableMarker(table string) func(ctx context.Context, db *DB) (bool, error) {
+	return func(ctx context.Context, db *DB) (bool, error) {
+		return db.hasTable(ctx, table)
+	}
+}
+
+// columnMarker returns a marker that checks for a column
+func columnMarker(table, column string) func(ctx context.Context, db *DB) (bool, error) {
+	return func(ctx context.Context, db *DB) (bool, error) {
+		return db.hasColumn(ctx, table, column)
+	}
+}
+
+// DetectBaseline returns the last migration applied to a schema whose
+// tables exist but whose migrations are not recorded, as when the SQL files
+// were run by hand, for passing to Baseline. Migrations are checked in
+// order up to the first whose marker is missing. It returns 0 when there is
+// nothing to record: migrations are recorded already, or there are no
+// tables yet.
+func (db *DB) DetectBaseline(ctx context.Context, migrations []Migration) (int, error) {
+	if err := db.ensureMigrationsTable(ctx); err != nil {
+		return 0, err
+	}
+	applied, err := db.AppliedMigrations(ctx)
+	if err != nil || len(applied) > 0 {
+		return 0, err
+	}
+
+	baseline := 0
+	for _, m := range migrations {
+		marker, ok := migrationMarkers[m.Version]
+		if !ok {
+			break
+		}
+		found, err := marker(ctx, db)
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			break
+		}
+		baseline = m.Version
+	}
+	return baseline, nil
+}
+
+// hasTable reports whether the current schema has a table of the given name
+func (db *DB) hasTable(ctx context.Context, table string) (bool, error) {
+	var exists bool
+	err := db.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2)`,
+		db.currentSchema(), table,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for table %s: %w", table, err)
+	}
+	return exists, nil
+}
+
+// hasColumn reports whether a table in the current schema has a column of
+// the given name
+func (db *DB) hasColumn(ctx context.Context, table, column string) (bool, error) {
+	var exists bool
+	err := db.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 AND column_name = $3)`,
+		db.currentSchema(), table, column,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for column %s.%s: %w", table, column, err)
+	}
+	return exists, nil
+}
+
+// hasConstraint reports whether a table in the current schema has a
+// constraint of the given name
+func (db *DB) hasConstraint(ctx context.Context, table, constraint string) (bool, error) {
+	var exists bool
+	err := db.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE table_schema = $1 AND table_name = $2 AND constraint_name = $3)`,
+		db.currentSchema(), table, constraint,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for constraint %s on %s: %w", constraint, table, err)
+	}
+	return exists, nil
+}
+
+// currentSchema returns the schema tables are looked up in
+func (db *DB) currentSchema() string {
+	if schema := db.Schema(); schema != "" {
+		return schema
+	}
+	return "public"
+}
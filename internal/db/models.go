@@ -15,6 +15,19 @@ type Document struct {
 	FileType    string
 	ProcessedAt *time.Time
 	ErrorMessage *string
+	Tags        []string
+	Title       string // From document metadata; empty if it has none
+	// ChunkSize and ChunkOverlap are the settings the document was last
+	// chunked with; 0 if unknown
+	ChunkSize    int
+	ChunkOverlap int
+	// EmbeddingModel is the text model the chunks were last embedded
+	// with; empty if unknown
+	EmbeddingModel string
+	// FileSize and FileModTime are the file's size and modification time
+	// when it was last hashed; nil if not yet recorded
+	FileSize    *int64
+	FileModTime *time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
@@ -25,19 +38,45 @@ type Chunk struct {
 	DocumentID uuid.UUID
 	ChunkIndex int
 	Content    string
+	Section    string // Nearest enclosing outline entry, if the document has one
 	Embedding  *pgvector.Vector
 	CreatedAt  time.Time
+	Distance   float64 // Cosine distance to the query; set by similarity search only
 }
 
+// Image processing statuses
+const (
+	ImagePending = "pending" // Not yet captioned and embedded
+	ImageFailed  = "failed"  // Captioning or embedding failed; see Image.Error
+	ImageDone    = "done"
+)
+
 // Image represents an image with caption and embedding
 type Image struct {
 	ID         uuid.UUID
 	DocumentID uuid.UUID
 	ImageIndex int
 	FilePath   string
+	// ThumbnailPath is a small preview of the image for browsing; empty if
+	// thumbnails are disabled or one could not be made
+	ThumbnailPath string
+	// SourcePage is the 1-based page the image was rendered from, or nil
+	// (EPUB images extracted from the archive have no page)
+	SourcePage *int
+	// SourceLocation is where the image is stored in its document, such as
+	// its path inside an EPUB; empty for page renders
+	SourceLocation string
 	Caption    string
 	Embedding  *pgvector.Vector
+	// CaptionEmbedding is the caption embedded with the text model, for
+	// finding the image by text search; nil unless captions are embedded
+	CaptionEmbedding *pgvector.Vector
+	// Status is ImageDone once captioned and embedded, or ImageFailed with
+	// the reason in Error; ImagePending if never attempted
+	Status     string
+	Error      string
 	CreatedAt  time.Time
+	Distance   float64 // Cosine distance to the query; set by similarity search only
 }
 
 // Conversation represents a chat interaction
@@ -2,50 +2,82 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/pgvector/pgvector-go"
+	pgxvec "github.com/pgvector/pgvector-go/pgx"
 )
 
-// GetDocumentByHash retrieves a document by its file hash
-func (db *DB) GetDocumentByHash(ctx context.Context, hash string) (*Document, error) {
+// ErrDimensionMismatch is returned by searches when the query's embedding
+// and the stored ones differ in dimension, as after the embedding model
+// changes; searching works again once the embeddings are rebuilt
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// documentColumns is the documents column list read by scanDocument
+const documentColumns = `id, file_path, file_hash, file_type, processed_at, error_message, tags, title, chunk_size, chunk_overlap, embedding_model, file_size, file_mtime, created_at, updated_at`
+
+// scanDocument scans a row selected with documentColumns
+func scanDocument(row pgx.Row) (*Document, error) {
 	var doc Document
-	err := db.pool.QueryRow(ctx,
-		`SELECT id, file_path, file_hash, file_type, processed_at, error_message, created_at, updated_at
-		 FROM documents WHERE file_hash = $1`,
-		hash,
-	).Scan(
+	err := row.Scan(
 		&doc.ID, &doc.FilePath, &doc.FileHash, &doc.FileType,
-		&doc.ProcessedAt, &doc.ErrorMessage, &doc.CreatedAt, &doc.UpdatedAt,
+		&doc.ProcessedAt, &doc.ErrorMessage, &doc.Tags, &doc.Title,
+		&doc.ChunkSize, &doc.ChunkOverlap, &doc.EmbeddingModel, &doc.FileSize, &doc.FileModTime,
+		&doc.CreatedAt, &doc.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// GetDocumentByHash retrieves a document by its file hash
+func (db *DB) GetDocumentByHash(ctx context.Context, hash string) (*Document, error) {
+	doc, err := scanDocument(db.pool.QueryRow(ctx,
+		`SELECT `+documentColumns+` FROM documents WHERE file_hash = $1`,
+		hash,
+	))
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document by hash: %w", err)
 	}
-	return &doc, nil
+	return doc, nil
+}
+
+// GetDocumentByPath retrieves a document by its file path
+func (db *DB) GetDocumentByPath(ctx context.Context, filePath string) (*Document, error) {
+	doc, err := scanDocument(db.pool.QueryRow(ctx,
+		`SELECT `+documentColumns+` FROM documents WHERE file_path = $1`,
+		filePath,
+	))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by path: %w", err)
+	}
+	return doc, nil
 }
 
 // CreateDocument creates a new document record
 func (db *DB) CreateDocument(ctx context.Context, filePath, fileHash, fileType string) (*Document, error) {
-	var doc Document
-	err := db.pool.QueryRow(ctx,
+	doc, err := scanDocument(db.pool.QueryRow(ctx,
 		`INSERT INTO documents (file_path, file_hash, file_type)
 		 VALUES ($1, $2, $3)
-		 RETURNING id, file_path, file_hash, file_type, processed_at, error_message, created_at, updated_at`,
+		 RETURNING `+documentColumns,
 		filePath, fileHash, fileType,
-	).Scan(
-		&doc.ID, &doc.FilePath, &doc.FileHash, &doc.FileType,
-		&doc.ProcessedAt, &doc.ErrorMessage, &doc.CreatedAt, &doc.UpdatedAt,
-	)
+	))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create document: %w", err)
 	}
-	return &doc, nil
+	return doc, nil
 }
 
 // UpdateDocumentProcessed updates the processed_at timestamp
@@ -57,6 +89,123 @@ func (db *DB) UpdateDocumentProcessed(ctx context.Context, docID uuid.UUID) erro
 	return err
 }
 
+// UpdateDocumentChunking records the chunk settings and embedding model a
+// document was processed with
+func (db *DB) UpdateDocumentChunking(ctx context.Context, docID uuid.UUID, chunkSize, chunkOverlap int, embeddingModel string) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE documents SET chunk_size = $1, chunk_overlap = $2, embedding_model = $3, updated_at = NOW() WHERE id = $4`,
+		chunkSize, chunkOverlap, embeddingModel, docID,
+	)
+	return err
+}
+
+// IndexEmbeddingModel returns the embedding model most documents were last
+// processed with, or "" if none is recorded
+func (db *DB) IndexEmbeddingModel(ctx context.Context) (string, error) {
+	var model string
+	err := db.pool.QueryRow(ctx,
+		`SELECT embedding_model FROM documents WHERE embedding_model <> ''
+		 GROUP BY embedding_model ORDER BY COUNT(*) DESC LIMIT 1`,
+	).Scan(&model)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get index embedding model: %w", err)
+	}
+	return model, nil
+}
+
+// UpdateDocumentLog replaces the log of a document's last processing run
+func (db *DB) UpdateDocumentLog(ctx context.Context, docID uuid.UUID, processingLog string) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE documents SET processing_log = $1 WHERE id = $2`,
+		processingLog, docID,
+	)
+	return err
+}
+
+// GetDocumentLog returns the log of a document's last processing run
+func (db *DB) GetDocumentLog(ctx context.Context, docID uuid.UUID) (string, error) {
+	var processingLog string
+	err := db.pool.QueryRow(ctx,
+		`SELECT processing_log FROM documents WHERE id = $1`,
+		docID,
+	).Scan(&processingLog)
+	if err != nil {
+		return "", fmt.Errorf("failed to get processing log: %w", err)
+	}
+	return processingLog, nil
+}
+
+// GetDocumentCounts returns the number of chunks and images stored for a document
+func (db *DB) GetDocumentCounts(ctx context.Context, docID uuid.UUID) (chunks, images int, err error) {
+	err = db.pool.QueryRow(ctx,
+		`SELECT (SELECT COUNT(*) FROM chunks WHERE document_id = $1),
+		        (SELECT COUNT(*) FROM images WHERE document_id = $1)`,
+		docID,
+	).Scan(&chunks, &images)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count document chunks: %w", err)
+	}
+	return chunks, images, nil
+}
+
+// UpdateDocumentHash records a new file hash for a document being updated in place
+func (db *DB) UpdateDocumentHash(ctx context.Context, docID uuid.UUID, fileHash string) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE documents SET file_hash = $1, processed_at = NULL, updated_at = NOW() WHERE id = $2`,
+		fileHash, docID,
+	)
+	return err
+}
+
+// UpdateDocumentFileStat records the size and modification time of a
+// document's file as of its last hash
+func (db *DB) UpdateDocumentFileStat(ctx context.Context, docID uuid.UUID, size int64, modTime time.Time) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE documents SET file_size = $1, file_mtime = $2 WHERE id = $3`,
+		size, modTime, docID,
+	)
+	return err
+}
+
+// UpdateDocumentTitle sets the title read from a document's metadata
+func (db *DB) UpdateDocumentTitle(ctx context.Context, docID uuid.UUID, title string) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE documents SET title = $1, updated_at = NOW() WHERE id = $2`,
+		title, docID,
+	)
+	return err
+}
+
+// UpdateDocumentTags replaces a document's tags and propagates them to its chunks
+func (db *DB) UpdateDocumentTags(ctx context.Context, docID uuid.UUID, tags []string) error {
+	if tags == nil {
+		tags = []string{}
+	}
+
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE documents SET tags = $1, updated_at = NOW() WHERE id = $2`,
+		tags, docID,
+	); err != nil {
+		return fmt.Errorf("failed to update document tags: %w", err)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE chunks SET tags = $1 WHERE document_id = $2`,
+		tags, docID,
+	); err != nil {
+		return fmt.Errorf("failed to update chunk tags: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
 // UpdateDocumentError updates the error_message for a document
 func (db *DB) UpdateDocumentError(ctx context.Context, docID uuid.UUID, errorMsg string) error {
 	_, err := db.pool.Exec(ctx,
@@ -69,71 +218,387 @@ func (db *DB) UpdateDocumentError(ctx context.Context, docID uuid.UUID, errorMsg
 // InsertChunk inserts a text chunk with embedding
 func (db *DB) InsertChunk(ctx context.Context, chunk *Chunk) error {
 	_, err := db.pool.Exec(ctx,
-		`INSERT INTO chunks (id, document_id, chunk_index, content, embedding)
-		 VALUES ($1, $2, $3, $4, $5)`,
-		chunk.ID, chunk.DocumentID, chunk.ChunkIndex, chunk.Content, chunk.Embedding,
+		`INSERT INTO chunks (id, document_id, chunk_index, content, section, embedding, tags)
+		 VALUES ($1, $2, $3, $4, $5, $6, (SELECT tags FROM documents WHERE id = $2))`,
+		chunk.ID, chunk.DocumentID, chunk.ChunkIndex, chunk.Content, chunk.Section, chunk.Embedding,
 	)
 	return err
 }
 
-// InsertChunksBatch inserts multiple chunks in a transaction
+// InsertChunksBatch inserts multiple chunks in batches of the insert batch
+// size, each committed on its own. When there is more than one batch, each
+// is sent with COPY instead of a statement per chunk.
 func (db *DB) InsertChunksBatch(ctx context.Context, chunks []*Chunk) error {
-	batch := &pgx.Batch{}
-	for _, chunk := range chunks {
+	if len(chunks) > db.insertBatchSize {
+		return db.copyChunks(ctx, chunks)
+	}
+	return db.insertBatched(ctx, len(chunks), "chunk", func(batch *pgx.Batch, i int) {
+		chunk := chunks[i]
 		batch.Queue(
-			`INSERT INTO chunks (id, document_id, chunk_index, content, embedding)
-			 VALUES ($1, $2, $3, $4, $5)`,
-			chunk.ID, chunk.DocumentID, chunk.ChunkIndex, chunk.Content, chunk.Embedding,
+			`INSERT INTO chunks (id, document_id, chunk_index, content, section, embedding, tags)
+			 VALUES ($1, $2, $3, $4, $5, $6, (SELECT tags FROM documents WHERE id = $2))`,
+			chunk.ID, chunk.DocumentID, chunk.ChunkIndex, chunk.Content, chunk.Section, chunk.Embedding,
 		)
+	})
+}
+
+// copyChunks inserts chunks with the COPY protocol, which streams rows rather
+// than sending a statement per chunk. COPY cannot run the tags subquery of
+// the INSERT path, so each document's tags are looked up first.
+func (db *DB) copyChunks(ctx context.Context, chunks []*Chunk) error {
+	docIDs := make([]uuid.UUID, 0, 1)
+	tags := make(map[uuid.UUID][]string)
+	for _, chunk := range chunks {
+		if _, ok := tags[chunk.DocumentID]; !ok {
+			tags[chunk.DocumentID] = []string{}
+			docIDs = append(docIDs, chunk.DocumentID)
+		}
+	}
+	rows, err := db.pool.Query(ctx, `SELECT id, tags FROM documents WHERE id = ANY($1)`, docIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get document tags: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var docTags []string
+		if err := rows.Scan(&id, &docTags); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan document tags: %w", err)
+		}
+		tags[id] = docTags
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get document tags: %w", err)
+	}
+
+	conn, err := db.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
 	}
-	br := db.pool.SendBatch(ctx, batch)
-	defer br.Close()
+	defer conn.Release()
 
-	for i := 0; i < len(chunks); i++ {
-		_, err := br.Exec()
+	// COPY only sends the binary format, which needs the vector type. It is
+	// registered as connections open, unless they opened before the vector
+	// extension was created.
+	if _, ok := conn.Conn().TypeMap().TypeForName("vector"); !ok {
+		if err := pgxvec.RegisterTypes(ctx, conn.Conn()); err != nil {
+			return fmt.Errorf("failed to register vector type: %w", err)
+		}
+	}
+
+	// Each batch is its own COPY and so its own transaction, as in
+	// insertBatched
+	for start := 0; start < len(chunks); start += db.insertBatchSize {
+		batch := chunks[start:min(start+db.insertBatchSize, len(chunks))]
+		_, err = conn.CopyFrom(ctx,
+			pgx.Identifier{"chunks"},
+			[]string{"id", "document_id", "chunk_index", "content", "section", "embedding", "tags"},
+			pgx.CopyFromSlice(len(batch), func(i int) ([]any, error) {
+				chunk := batch[i]
+				return []any{chunk.ID, chunk.DocumentID, chunk.ChunkIndex, chunk.Content, chunk.Section, chunk.Embedding, tags[chunk.DocumentID]}, nil
+			}),
+		)
 		if err != nil {
-			return fmt.Errorf("failed to insert chunk %d: %w", i, err)
+			return fmt.Errorf("failed to copy chunks %d-%d: %w", start, start+len(batch)-1, err)
 		}
 	}
 	return nil
 }
 
-// InsertImage inserts an image with caption and embedding
-func (db *DB) InsertImage(ctx context.Context, img *Image) error {
+// insertBatched sends the insert queued by queue for each of n rows, in
+// batches of at most insertBatchSize. A batch is one implicit transaction,
+// so a failure keeps the rows of the batches before it and no batch holds
+// locks for the whole insert.
+func (db *DB) insertBatched(ctx context.Context, n int, what string, queue func(batch *pgx.Batch, i int)) error {
+	for start := 0; start < n; start += db.insertBatchSize {
+		end := min(start+db.insertBatchSize, n)
+		batch := &pgx.Batch{}
+		for i := start; i < end; i++ {
+			queue(batch, i)
+		}
+
+		br := db.pool.SendBatch(ctx, batch)
+		for i := start; i < end; i++ {
+			if _, err := br.Exec(); err != nil {
+				br.Close()
+				return fmt.Errorf("failed to insert %s %d: %w", what, i, err)
+			}
+		}
+		if err := br.Close(); err != nil {
+			return fmt.Errorf("failed to insert %ss %d-%d: %w", what, start, end-1, err)
+		}
+	}
+	return nil
+}
+
+// GetChunksByDocument retrieves all chunks for a document ordered by index.
+// Embeddings are not loaded.
+func (db *DB) GetChunksByDocument(ctx context.Context, docID uuid.UUID) ([]*Chunk, error) {
+	rows, err := db.pool.Query(ctx,
+		`SELECT id, document_id, chunk_index, content, section, created_at
+		 FROM chunks WHERE document_id = $1 ORDER BY chunk_index`,
+		docID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []*Chunk
+	for rows.Next() {
+		var chunk Chunk
+		if err := rows.Scan(
+			&chunk.ID, &chunk.DocumentID, &chunk.ChunkIndex,
+			&chunk.Content, &chunk.Section, &chunk.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		chunks = append(chunks, &chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// GetChunkRange returns a document's chunks with chunk_index from first to
+// last inclusive, in order
+func (db *DB) GetChunkRange(ctx context.Context, docID uuid.UUID, first, last int) ([]*Chunk, error) {
+	rows, err := db.pool.Query(ctx,
+		`SELECT id, document_id, chunk_index, content, section, created_at
+		 FROM chunks WHERE document_id = $1 AND chunk_index BETWEEN $2 AND $3
+		 ORDER BY chunk_index`,
+		docID, first, last,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunk range: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []*Chunk
+	for rows.Next() {
+		var chunk Chunk
+		if err := rows.Scan(
+			&chunk.ID, &chunk.DocumentID, &chunk.ChunkIndex,
+			&chunk.Content, &chunk.Section, &chunk.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		chunks = append(chunks, &chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// UpdateChunkPosition moves an existing chunk to a new position and section
+// within its document
+func (db *DB) UpdateChunkPosition(ctx context.Context, chunkID uuid.UUID, chunkIndex int, section string) error {
 	_, err := db.pool.Exec(ctx,
-		`INSERT INTO images (id, document_id, image_index, file_path, caption, embedding)
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
-		img.ID, img.DocumentID, img.ImageIndex, img.FilePath, img.Caption, img.Embedding,
+		`UPDATE chunks SET chunk_index = $1, section = $2 WHERE id = $3`,
+		chunkIndex, section, chunkID,
 	)
 	return err
 }
 
-// InsertImagesBatch inserts multiple images in a transaction
-func (db *DB) InsertImagesBatch(ctx context.Context, images []*Image) error {
-	batch := &pgx.Batch{}
-	for _, img := range images {
-		batch.Queue(
-			`INSERT INTO images (id, document_id, image_index, file_path, caption, embedding)
-			 VALUES ($1, $2, $3, $4, $5, $6)`,
-			img.ID, img.DocumentID, img.ImageIndex, img.FilePath, img.Caption, img.Embedding,
-		)
-	}
-	br := db.pool.SendBatch(ctx, batch)
-	defer br.Close()
+// UpdateChunkEmbedding replaces the embedding of an existing chunk
+func (db *DB) UpdateChunkEmbedding(ctx context.Context, chunkID uuid.UUID, embedding *pgvector.Vector) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE chunks SET embedding = $1 WHERE id = $2`,
+		embedding, chunkID,
+	)
+	return err
+}
 
-	for i := 0; i < len(images); i++ {
-		_, err := br.Exec()
+// BeginEmbeddingRebuild adds staging columns of dim dimensions for new chunk
+// embeddings and, if captions is set, new image caption embeddings, dropping
+// any left by an interrupted rebuild. The embeddings in use are untouched
+// until FinishEmbeddingRebuild swaps the staged ones in.
+func (db *DB) BeginEmbeddingRebuild(ctx context.Context, dim int, captions bool) error {
+	if err := db.AbortEmbeddingRebuild(ctx); err != nil {
+		return err
+	}
+	_, err := db.pool.Exec(ctx, fmt.Sprintf(`ALTER TABLE chunks ADD COLUMN embedding_next vector(%d)`, dim))
+	if err != nil {
+		return fmt.Errorf("failed to add staging embedding column: %w", err)
+	}
+	_, err = db.pool.Exec(ctx, fmt.Sprintf(`ALTER TABLE document_summaries ADD COLUMN embedding_next vector(%d)`, dim))
+	if err != nil {
+		return fmt.Errorf("failed to add staging summary embedding column: %w", err)
+	}
+	if captions {
+		_, err = db.pool.Exec(ctx, fmt.Sprintf(`ALTER TABLE images ADD COLUMN caption_embedding_next vector(%d)`, dim))
 		if err != nil {
-			return fmt.Errorf("failed to insert image %d: %w", i, err)
+			return fmt.Errorf("failed to add staging caption embedding column: %w", err)
+		}
+	}
+	return nil
+}
+
+// AbortEmbeddingRebuild drops the staging columns of an unfinished rebuild,
+// leaving the embeddings in use as they were
+func (db *DB) AbortEmbeddingRebuild(ctx context.Context) error {
+	_, err := db.pool.Exec(ctx,
+		`ALTER TABLE chunks DROP COLUMN IF EXISTS embedding_next;
+		 ALTER TABLE document_summaries DROP COLUMN IF EXISTS embedding_next;
+		 ALTER TABLE images DROP COLUMN IF EXISTS caption_embedding_next`)
+	if err != nil {
+		return fmt.Errorf("failed to drop staging embedding columns: %w", err)
+	}
+	return nil
+}
+
+// StageChunkEmbedding stores a chunk's rebuilt embedding in the staging
+// column
+func (db *DB) StageChunkEmbedding(ctx context.Context, chunkID uuid.UUID, embedding *pgvector.Vector) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE chunks SET embedding_next = $1 WHERE id = $2`,
+		embedding, chunkID,
+	)
+	return err
+}
+
+// StageSummaryEmbedding stores a document summary's rebuilt embedding in
+// the staging column
+func (db *DB) StageSummaryEmbedding(ctx context.Context, docID uuid.UUID, embedding *pgvector.Vector) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE document_summaries SET embedding_next = $1 WHERE document_id = $2`,
+		embedding, docID,
+	)
+	return err
+}
+
+// StageCaptionEmbedding stores an image's rebuilt caption embedding in the
+// staging column
+func (db *DB) StageCaptionEmbedding(ctx context.Context, imageID uuid.UUID, embedding *pgvector.Vector) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE images SET caption_embedding_next = $1 WHERE id = $2`,
+		embedding, imageID,
+	)
+	return err
+}
+
+// FinishEmbeddingRebuild replaces the chunk and document summary
+// embeddings, and the caption embeddings if they were staged, with the
+// staged ones in one transaction, recreating the vector indexes on the
+// replaced columns. When the dimension changes, unstaged caption embeddings
+// are cleared, as EnsureEmbeddingDimension does for an empty index.
+func (db *DB) FinishEmbeddingRebuild(ctx context.Context, dim int, captions bool) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin embedding swap: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var current int
+	err = tx.QueryRow(ctx,
+		`SELECT atttypmod FROM pg_attribute
+		 WHERE attrelid = 'chunks'::regclass AND attname = 'embedding'`,
+	).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to read embedding dimension: %w", err)
+	}
+
+	if err := swapStagedColumn(ctx, tx, "chunks", "embedding", "embedding_next"); err != nil {
+		return err
+	}
+	if err := swapStagedColumn(ctx, tx, "document_summaries", "embedding", "embedding_next"); err != nil {
+		return err
+	}
+	if captions {
+		if err := swapStagedColumn(ctx, tx, "images", "caption_embedding", "caption_embedding_next"); err != nil {
+			return err
+		}
+	}
+
+	if current != dim && !captions {
+		statements := []string{
+			`UPDATE images SET caption_embedding = NULL`,
+			fmt.Sprintf(`ALTER TABLE images ALTER COLUMN caption_embedding TYPE vector(%d)`, dim),
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("failed to change embedding dimension: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit embedding swap: %w", err)
+	}
+	return nil
+}
+
+// swapStagedColumn replaces column with staged, keeping the indexes built
+// on column
+func swapStagedColumn(ctx context.Context, tx pgx.Tx, table, column, staged string) error {
+	rows, err := tx.Query(ctx,
+		`SELECT pg_get_indexdef(i.indexrelid)
+		 FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		 WHERE i.indrelid = $1::regclass AND a.attname = $2`,
+		table, column,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes on %s.%s: %w", table, column, err)
+	}
+	indexes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to read indexes on %s.%s: %w", table, column, err)
+	}
+
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, table, column),
+		fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN %s TO %s`, table, staged, column),
+	}
+	statements = append(statements, indexes...)
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("failed to swap in rebuilt %s.%s: %w", table, column, err)
 		}
 	}
 	return nil
 }
 
+// DeleteChunks deletes the chunks with the given IDs
+func (db *DB) DeleteChunks(ctx context.Context, chunkIDs []uuid.UUID) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+	_, err := db.pool.Exec(ctx, `DELETE FROM chunks WHERE id = ANY($1)`, chunkIDs)
+	return err
+}
+
+// InsertImage inserts an image with caption and embedding
+func (db *DB) InsertImage(ctx context.Context, img *Image) error {
+	_, err := db.pool.Exec(ctx,
+		`INSERT INTO images (id, document_id, image_index, file_path, thumbnail_path, source_page, source_location, caption, embedding, caption_embedding, status, error_message)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		img.ID, img.DocumentID, img.ImageIndex, img.FilePath, img.ThumbnailPath, img.SourcePage, img.SourceLocation, img.Caption, img.Embedding, img.CaptionEmbedding, imageStatus(img), img.Error,
+	)
+	return err
+}
+
+// imageStatus returns the status to store for img: its own, or else done
+func imageStatus(img *Image) string {
+	if img.Status == "" {
+		return ImageDone
+	}
+	return img.Status
+}
+
+// InsertImagesBatch inserts multiple images in batches of the insert batch
+// size, each committed on its own
+func (db *DB) InsertImagesBatch(ctx context.Context, images []*Image) error {
+	return db.insertBatched(ctx, len(images), "image", func(batch *pgx.Batch, i int) {
+		img := images[i]
+		batch.Queue(
+			`INSERT INTO images (id, document_id, image_index, file_path, thumbnail_path, source_page, source_location, caption, embedding, caption_embedding, status, error_message)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			img.ID, img.DocumentID, img.ImageIndex, img.FilePath, img.ThumbnailPath, img.SourcePage, img.SourceLocation, img.Caption, img.Embedding, img.CaptionEmbedding, imageStatus(img), img.Error,
+		)
+	})
+}
+
 // SearchSimilarChunks finds similar chunks using vector similarity
 func (db *DB) SearchSimilarChunks(ctx context.Context, embedding *pgvector.Vector, limit int) ([]*Chunk, error) {
 	rows, err := db.pool.Query(ctx,
-		`SELECT id, document_id, chunk_index, content, embedding, created_at
+		`SELECT id, document_id, chunk_index, content, section, embedding, created_at, embedding <=> $1
 		 FROM chunks
 		 WHERE embedding IS NOT NULL
 		 ORDER BY embedding <=> $1
@@ -141,7 +606,7 @@ func (db *DB) SearchSimilarChunks(ctx context.Context, embedding *pgvector.Vecto
 		embedding, limit,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search chunks: %w", err)
+		return nil, searchError("chunks", err)
 	}
 	defer rows.Close()
 
@@ -150,26 +615,117 @@ func (db *DB) SearchSimilarChunks(ctx context.Context, embedding *pgvector.Vecto
 		var chunk Chunk
 		if err := rows.Scan(
 			&chunk.ID, &chunk.DocumentID, &chunk.ChunkIndex,
-			&chunk.Content, &chunk.Embedding, &chunk.CreatedAt,
+			&chunk.Content, &chunk.Section, &chunk.Embedding, &chunk.CreatedAt, &chunk.Distance,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan chunk: %w", err)
 		}
 		chunks = append(chunks, &chunk)
 	}
-	return chunks, rows.Err()
+	return chunks, searchError("chunks", rows.Err())
+}
+
+// SearchSimilarChunksInDocuments is SearchSimilarChunks restricted to the
+// given documents and to documents with no summary, which a search of
+// summaries could not have picked
+func (db *DB) SearchSimilarChunksInDocuments(ctx context.Context, embedding *pgvector.Vector, docIDs []uuid.UUID, limit int) ([]*Chunk, error) {
+	rows, err := db.pool.Query(ctx,
+		`SELECT id, document_id, chunk_index, content, section, embedding, created_at, embedding <=> $1
+		 FROM chunks
+		 WHERE embedding IS NOT NULL
+		   AND (document_id = ANY($2) OR document_id NOT IN (SELECT document_id FROM document_summaries))
+		 ORDER BY embedding <=> $1
+		 LIMIT $3`,
+		embedding, docIDs, limit,
+	)
+	if err != nil {
+		return nil, searchError("chunks", err)
+	}
+	defer rows.Close()
+
+	var chunks []*Chunk
+	for rows.Next() {
+		var chunk Chunk
+		if err := rows.Scan(
+			&chunk.ID, &chunk.DocumentID, &chunk.ChunkIndex,
+			&chunk.Content, &chunk.Section, &chunk.Embedding, &chunk.CreatedAt, &chunk.Distance,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		chunks = append(chunks, &chunk)
+	}
+	return chunks, searchError("chunks", rows.Err())
+}
+
+// UpsertDocumentSummary stores a document's summary and its embedding,
+// replacing any earlier summary
+func (db *DB) UpsertDocumentSummary(ctx context.Context, docID uuid.UUID, summary, modelName string, embedding *pgvector.Vector) error {
+	_, err := db.pool.Exec(ctx,
+		`INSERT INTO document_summaries (document_id, summary, model_name, embedding)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (document_id) DO UPDATE
+		 SET summary = EXCLUDED.summary, model_name = EXCLUDED.model_name,
+		     embedding = EXCLUDED.embedding, created_at = NOW()`,
+		docID, summary, modelName, embedding,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store document summary: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentSummaries returns the summary of each summarized document, by
+// document ID
+func (db *DB) GetDocumentSummaries(ctx context.Context) (map[uuid.UUID]string, error) {
+	rows, err := db.pool.Query(ctx, `SELECT document_id, summary FROM document_summaries`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var summary string
+		if err := rows.Scan(&id, &summary); err != nil {
+			return nil, fmt.Errorf("failed to scan document summary: %w", err)
+		}
+		summaries[id] = summary
+	}
+	return summaries, rows.Err()
+}
+
+// SearchDocumentSummaries returns the IDs of the limit documents whose
+// summaries are most similar to embedding, most similar first
+func (db *DB) SearchDocumentSummaries(ctx context.Context, embedding *pgvector.Vector, limit int) ([]uuid.UUID, error) {
+	rows, err := db.pool.Query(ctx,
+		`SELECT document_id FROM document_summaries
+		 WHERE embedding IS NOT NULL
+		 ORDER BY embedding <=> $1
+		 LIMIT $2`,
+		embedding, limit,
+	)
+	if err != nil {
+		return nil, searchError("document summaries", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan document summary: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, searchError("document summaries", rows.Err())
 }
 
 // SearchSimilarImages finds similar images using vector similarity
 // Note: This requires a 512-dim embedding (CLIP2), not 768-dim (text embeddings)
 func (db *DB) SearchSimilarImages(ctx context.Context, embedding *pgvector.Vector, limit int) ([]*Image, error) {
-	// Check embedding dimension - images use 512-dim, text uses 768-dim
-	if embedding != nil && len(embedding.Slice()) != 512 {
-		// Return empty result if dimension mismatch instead of error
-		return []*Image{}, nil
-	}
 
 	rows, err := db.pool.Query(ctx,
-		`SELECT id, document_id, image_index, file_path, caption, embedding, created_at
+		`SELECT id, document_id, image_index, file_path, thumbnail_path, source_page, source_location, caption, embedding, created_at, embedding <=> $1
 		 FROM images
 		 WHERE embedding IS NOT NULL
 		 ORDER BY embedding <=> $1
@@ -177,11 +733,37 @@ func (db *DB) SearchSimilarImages(ctx context.Context, embedding *pgvector.Vecto
 		embedding, limit,
 	)
 	if err != nil {
-		// Check if it's a dimension mismatch error
-		if strings.Contains(err.Error(), "different vector dimensions") {
-			return []*Image{}, nil
+		return nil, searchError("images", err)
+	}
+	defer rows.Close()
+
+	var images []*Image
+	for rows.Next() {
+		var img Image
+		if err := rows.Scan(
+			&img.ID, &img.DocumentID, &img.ImageIndex,
+			&img.FilePath, &img.ThumbnailPath, &img.SourcePage, &img.SourceLocation, &img.Caption, &img.Embedding, &img.CreatedAt, &img.Distance,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan image: %w", err)
 		}
-		return nil, fmt.Errorf("failed to search images: %w", err)
+		images = append(images, &img)
+	}
+	return images, searchError("images", rows.Err())
+}
+
+// SearchImagesByCaption finds the images whose captions are most similar to
+// a query embedded with the text model
+func (db *DB) SearchImagesByCaption(ctx context.Context, embedding *pgvector.Vector, limit int) ([]*Image, error) {
+	rows, err := db.pool.Query(ctx,
+		`SELECT id, document_id, image_index, file_path, thumbnail_path, source_page, source_location, caption, embedding, created_at, caption_embedding <=> $1
+		 FROM images
+		 WHERE caption_embedding IS NOT NULL
+		 ORDER BY caption_embedding <=> $1
+		 LIMIT $2`,
+		embedding, limit,
+	)
+	if err != nil {
+		return nil, searchError("image captions", err)
 	}
 	defer rows.Close()
 
@@ -190,13 +772,26 @@ func (db *DB) SearchSimilarImages(ctx context.Context, embedding *pgvector.Vecto
 		var img Image
 		if err := rows.Scan(
 			&img.ID, &img.DocumentID, &img.ImageIndex,
-			&img.FilePath, &img.Caption, &img.Embedding, &img.CreatedAt,
+			&img.FilePath, &img.ThumbnailPath, &img.SourcePage, &img.SourceLocation, &img.Caption, &img.Embedding, &img.CreatedAt, &img.Distance,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan image: %w", err)
 		}
 		images = append(images, &img)
 	}
-	return images, rows.Err()
+	return images, searchError("image captions", rows.Err())
+}
+
+// searchError wraps an error from a similarity search of what, marking it
+// with ErrDimensionMismatch when pgvector reports differing dimensions. A
+// nil error stays nil.
+func searchError(what string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if strings.Contains(err.Error(), "different vector dimensions") {
+		return fmt.Errorf("failed to search %s: %w: %w", what, ErrDimensionMismatch, err)
+	}
+	return fmt.Errorf("failed to search %s: %w", what, err)
 }
 
 // SaveConversation saves a conversation record
@@ -207,34 +802,169 @@ func (db *DB) SaveConversation(ctx context.Context, conv *Conversation) error {
 		conv.ID, conv.UserMessage, conv.AssistantMessage, conv.ModelName,
 		conv.ContextChunkIDs, conv.ContextImageIDs,
 	)
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// ConversationRange limits conversation queries to those created at or after
+// Since and before Until; a zero time leaves that end open
+type ConversationRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// args returns the range's ends as query arguments, NULL for open ends
+func (r ConversationRange) args() (since, until any) {
+	if !r.Since.IsZero() {
+		since = r.Since
+	}
+	if !r.Until.IsZero() {
+		until = r.Until
+	}
+	return since, until
+}
+
+// conversationRangeWhere filters conversations to the range passed as $1 and $2
+const conversationRangeWhere = `WHERE ($1::timestamp IS NULL OR created_at >= $1)
+		   AND ($2::timestamp IS NULL OR created_at < $2)`
+
+// GetRecentConversations retrieves one page of the conversations in the
+// range, newest first. A limit of 0 returns all of them from offset.
+func (db *DB) GetRecentConversations(ctx context.Context, r ConversationRange, limit, offset int) ([]*Conversation, error) {
+	since, until := r.args()
+	var limitArg any // NULL is no limit
+	if limit > 0 {
+		limitArg = limit
+	}
+	rows, err := db.pool.Query(ctx,
+		`SELECT id, user_message, assistant_message, model_name, context_chunk_ids, context_image_ids, created_at
+		 FROM conversations
+		 `+conversationRangeWhere+`
+		 ORDER BY created_at DESC, id LIMIT $3 OFFSET $4`,
+		since, until, limitArg, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var convs []*Conversation
+	for rows.Next() {
+		var conv Conversation
+		if err := rows.Scan(
+			&conv.ID, &conv.UserMessage, &conv.AssistantMessage, &conv.ModelName,
+			&conv.ContextChunkIDs, &conv.ContextImageIDs, &conv.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		convs = append(convs, &conv)
+	}
+	return convs, rows.Err()
+}
+
+// CountConversations returns the number of conversations in the range, for
+// paging through them with GetRecentConversations
+func (db *DB) CountConversations(ctx context.Context, r ConversationRange) (int, error) {
+	since, until := r.args()
+	var count int
+	err := db.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM conversations `+conversationRangeWhere,
+		since, until,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count conversations: %w", err)
+	}
+	return count, nil
+}
+
+// GetCachedAnswer returns the cached answer for a cache key, or "" and false
+// on a miss
+func (db *DB) GetCachedAnswer(ctx context.Context, key string) (string, bool, error) {
+	var answer string
+	err := db.pool.QueryRow(ctx,
+		`SELECT answer FROM answer_cache WHERE cache_key = $1`,
+		key,
+	).Scan(&answer)
+	if err == pgx.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get cached answer: %w", err)
+	}
+	return answer, true, nil
+}
+
+// PutCachedAnswer stores an answer under a cache key. chunkIDs are the
+// chunks it was built from; deleting any of them invalidates the entry.
+func (db *DB) PutCachedAnswer(ctx context.Context, key, query, model, answer string, chunkIDs []uuid.UUID) error {
+	_, err := db.pool.Exec(ctx,
+		`INSERT INTO answer_cache (cache_key, query, model_name, answer, chunk_ids)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (cache_key) DO UPDATE
+		 SET answer = EXCLUDED.answer, created_at = NOW()`,
+		key, query, model, answer, chunkIDs,
+	)
+	return err
+}
+
+// GetImportProgress returns the files completed by an unfinished directory
+// import; it is empty when the last import ran to completion
+func (db *DB) GetImportProgress(ctx context.Context) (map[string]bool, error) {
+	rows, err := db.pool.Query(ctx, `SELECT file_path FROM import_progress`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import progress: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan import progress: %w", err)
+		}
+		done[path] = true
+	}
+	return done, rows.Err()
+}
+
+// MarkImportProgress records that the current directory import has
+// finished with a file
+func (db *DB) MarkImportProgress(ctx context.Context, filePath string) error {
+	_, err := db.pool.Exec(ctx,
+		`INSERT INTO import_progress (file_path) VALUES ($1)
+		 ON CONFLICT (file_path) DO UPDATE SET completed_at = NOW()`,
+		filePath,
+	)
+	return err
+}
+
+// ClearImportProgress forgets import progress once an import completes
+func (db *DB) ClearImportProgress(ctx context.Context) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM import_progress`)
 	return err
 }
 
 // GetDocumentByID retrieves a document by its ID
 func (db *DB) GetDocumentByID(ctx context.Context, id uuid.UUID) (*Document, error) {
-	var doc Document
-	err := db.pool.QueryRow(ctx,
-		`SELECT id, file_path, file_hash, file_type, processed_at, error_message, created_at, updated_at
-		 FROM documents WHERE id = $1`,
+	doc, err := scanDocument(db.pool.QueryRow(ctx,
+		`SELECT `+documentColumns+` FROM documents WHERE id = $1`,
 		id,
-	).Scan(
-		&doc.ID, &doc.FilePath, &doc.FileHash, &doc.FileType,
-		&doc.ProcessedAt, &doc.ErrorMessage, &doc.CreatedAt, &doc.UpdatedAt,
-	)
+	))
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document by ID: %w", err)
 	}
-	return &doc, nil
+	return doc, nil
 }
 
 // GetAllDocuments retrieves all documents
 func (db *DB) GetAllDocuments(ctx context.Context) ([]*Document, error) {
 	rows, err := db.pool.Query(ctx,
-		`SELECT id, file_path, file_hash, file_type, processed_at, error_message, created_at, updated_at
-		 FROM documents ORDER BY created_at DESC`,
+		`SELECT `+documentColumns+` FROM documents ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get documents: %w", err)
@@ -243,18 +973,90 @@ func (db *DB) GetAllDocuments(ctx context.Context) ([]*Document, error) {
 
 	var docs []*Document
 	for rows.Next() {
-		var doc Document
-		if err := rows.Scan(
-			&doc.ID, &doc.FilePath, &doc.FileHash, &doc.FileType,
-			&doc.ProcessedAt, &doc.ErrorMessage, &doc.CreatedAt, &doc.UpdatedAt,
-		); err != nil {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// Document sort orders for ListDocuments
+const (
+	SortByDate   = "date"   // Newest first
+	SortByName   = "name"   // Title, or file name without a title
+	SortByType   = "type"   // File type, then name
+	SortByStatus = "status" // Failed, then unprocessed, then processed; then name
+)
+
+// DocumentSorts lists the sort orders in the order a view cycles through them
+var DocumentSorts = []string{SortByDate, SortByName, SortByType, SortByStatus}
+
+// documentSortName orders by the title, or the file name when there is none
+const documentSortName = `lower(COALESCE(NULLIF(title, ''), regexp_replace(file_path, '^.*/', '')))`
+
+// documentOrderBy maps each sort order to its ORDER BY clause
+var documentOrderBy = map[string]string{
+	SortByDate: `created_at DESC`,
+	SortByName: documentSortName,
+	SortByType: `file_type, ` + documentSortName,
+	SortByStatus: `CASE WHEN processed_at IS NOT NULL THEN 2
+		WHEN COALESCE(error_message, '') <> '' THEN 0 ELSE 1 END, ` + documentSortName,
+}
+
+// ListDocuments retrieves one page of documents in the given sort order
+// (SortByDate if unknown). A limit of 0 returns all documents from offset.
+func (db *DB) ListDocuments(ctx context.Context, sort string, limit, offset int) ([]*Document, error) {
+	orderBy, ok := documentOrderBy[sort]
+	if !ok {
+		orderBy = documentOrderBy[SortByDate]
+	}
+	var limitArg any // NULL is no limit
+	if limit > 0 {
+		limitArg = limit
+	}
+	rows, err := db.pool.Query(ctx,
+		`SELECT `+documentColumns+` FROM documents ORDER BY `+orderBy+`, id LIMIT $1 OFFSET $2`,
+		limitArg, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*Document
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan document: %w", err)
 		}
-		docs = append(docs, &doc)
+		docs = append(docs, doc)
 	}
 	return docs, rows.Err()
 }
 
+// CountDocuments returns the number of documents
+func (db *DB) CountDocuments(ctx context.Context) (int, error) {
+	var count int
+	if err := db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM documents`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return count, nil
+}
+
+// CountDocumentStatus returns the number of documents and how many of them
+// have been processed, without fetching their rows
+func (db *DB) CountDocumentStatus(ctx context.Context) (total, processed int, err error) {
+	err = db.pool.QueryRow(ctx,
+		`SELECT COUNT(*), COUNT(processed_at) FROM documents`,
+	).Scan(&total, &processed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return total, processed, nil
+}
+
 // DeleteDocument deletes a document and its associated chunks/images
 func (db *DB) DeleteDocument(ctx context.Context, docID uuid.UUID) error {
 	_, err := db.pool.Exec(ctx, `DELETE FROM documents WHERE id = $1`, docID)
@@ -264,13 +1066,33 @@ func (db *DB) DeleteDocument(ctx context.Context, docID uuid.UUID) error {
 // GetImagesByDocument retrieves all images for a document
 func (db *DB) GetImagesByDocument(ctx context.Context, docID uuid.UUID) ([]*Image, error) {
 	rows, err := db.pool.Query(ctx,
-		`SELECT id, document_id, image_index, file_path, caption, embedding, created_at
-		 FROM images WHERE document_id = $1 ORDER BY image_index`,
+		`SELECT `+imageColumns+` FROM images WHERE document_id = $1 ORDER BY image_index`,
 		docID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get images: %w", err)
 	}
+	return scanImages(rows)
+}
+
+// GetFailedImages retrieves the images whose captioning or embedding failed,
+// by document
+func (db *DB) GetFailedImages(ctx context.Context) ([]*Image, error) {
+	rows, err := db.pool.Query(ctx,
+		`SELECT `+imageColumns+` FROM images WHERE status = $1 ORDER BY document_id, image_index`,
+		ImageFailed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed images: %w", err)
+	}
+	return scanImages(rows)
+}
+
+// imageColumns is the images column list read by scanImages
+const imageColumns = `id, document_id, image_index, file_path, thumbnail_path, source_page, source_location, caption, embedding, caption_embedding, status, error_message, created_at`
+
+// scanImages scans and closes rows selected with imageColumns
+func scanImages(rows pgx.Rows) ([]*Image, error) {
 	defer rows.Close()
 
 	var images []*Image
@@ -278,7 +1100,8 @@ func (db *DB) GetImagesByDocument(ctx context.Context, docID uuid.UUID) ([]*Imag
 		var img Image
 		if err := rows.Scan(
 			&img.ID, &img.DocumentID, &img.ImageIndex,
-			&img.FilePath, &img.Caption, &img.Embedding, &img.CreatedAt,
+			&img.FilePath, &img.ThumbnailPath, &img.SourcePage, &img.SourceLocation, &img.Caption, &img.Embedding, &img.CaptionEmbedding,
+			&img.Status, &img.Error, &img.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan image: %w", err)
 		}
@@ -287,11 +1110,26 @@ func (db *DB) GetImagesByDocument(ctx context.Context, docID uuid.UUID) ([]*Imag
 	return images, rows.Err()
 }
 
-// UpdateImage updates an image with caption and embedding
-func (db *DB) UpdateImage(ctx context.Context, imageID uuid.UUID, caption string, embedding *pgvector.Vector) error {
+// DeleteImagesByDocument deletes all images for a document
+func (db *DB) DeleteImagesByDocument(ctx context.Context, docID uuid.UUID) error {
+	_, err := db.pool.Exec(ctx, `DELETE FROM images WHERE document_id = $1`, docID)
+	return err
+}
+
+// UpdateImage updates an image with caption and embeddings, marking it done
+func (db *DB) UpdateImage(ctx context.Context, imageID uuid.UUID, caption string, embedding, captionEmbedding *pgvector.Vector) error {
+	_, err := db.pool.Exec(ctx,
+		`UPDATE images SET caption = $1, embedding = $2, caption_embedding = $3, status = $4, error_message = '' WHERE id = $5`,
+		caption, embedding, captionEmbedding, ImageDone, imageID,
+	)
+	return err
+}
+
+// UpdateImageFailed marks an image failed, recording why
+func (db *DB) UpdateImageFailed(ctx context.Context, imageID uuid.UUID, reason string) error {
 	_, err := db.pool.Exec(ctx,
-		`UPDATE images SET caption = $1, embedding = $2 WHERE id = $3`,
-		caption, embedding, imageID,
+		`UPDATE images SET status = $1, error_message = $2 WHERE id = $3`,
+		ImageFailed, reason, imageID,
 	)
 	return err
 }
@@ -339,3 +1177,120 @@ func (db *DB) GetStats(ctx context.Context) (totalChunks, totalImages, totalWord
 
 	return totalChunks, totalImages, totalWords, totalPages, pagesWithImages, nil
 }
+
+// EmbeddingDimensions counts the embeddings in table ("chunks" or "images")
+// by their dimension. A consistent index has a single entry; more than one
+// means some rows were embedded with a different model.
+func (db *DB) EmbeddingDimensions(ctx context.Context, table string) (map[int]int, error) {
+	if table != "chunks" && table != "images" {
+		return nil, fmt.Errorf("no embedding column in table %q", table)
+	}
+	rows, err := db.pool.Query(ctx, fmt.Sprintf(
+		`SELECT vector_dims(embedding), COUNT(*) FROM %s
+		 WHERE embedding IS NOT NULL GROUP BY 1`, table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count embedding dimensions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var dim, count int
+		if err := rows.Scan(&dim, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding dimension: %w", err)
+		}
+		counts[dim] = count
+	}
+	return counts, rows.Err()
+}
+
+// EnsureEmbeddingDimension makes the chunks embedding column hold vectors of
+// dim dimensions. An empty column is altered in place; a populated column of
+// a different dimension is an error, since its embeddings must be rebuilt.
+// Document summaries and image captions, embedded with the same model, are
+// resized to match even when the chunks already are, dropping any of
+// another dimension.
+func (db *DB) EnsureEmbeddingDimension(ctx context.Context, dim int) error {
+	var current int
+	err := db.pool.QueryRow(ctx,
+		`SELECT atttypmod FROM pg_attribute
+		 WHERE attrelid = 'chunks'::regclass AND attname = 'embedding'`,
+	).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to read embedding dimension: %w", err)
+	}
+
+	if current != dim {
+		var populated bool
+		err = db.pool.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM chunks WHERE embedding IS NOT NULL)`,
+		).Scan(&populated)
+		if err != nil {
+			return fmt.Errorf("failed to check existing embeddings: %w", err)
+		}
+		if populated {
+			return fmt.Errorf("chunks hold %d-dim embeddings but %d-dim are configured; rebuild the index", current, dim)
+		}
+
+		_, err = db.pool.Exec(ctx, fmt.Sprintf(`ALTER TABLE chunks ALTER COLUMN embedding TYPE vector(%d)`, dim))
+		if err != nil {
+			return fmt.Errorf("failed to change embedding dimension: %w", err)
+		}
+	}
+
+	if err := db.alignEmbeddingColumn(ctx, "document_summaries", "embedding", dim,
+		`DELETE FROM document_summaries`); err != nil {
+		return err
+	}
+	// So are image captions; the images themselves are kept
+	return db.alignEmbeddingColumn(ctx, "images", "caption_embedding", dim,
+		`UPDATE images SET caption_embedding = NULL`)
+}
+
+// alignEmbeddingColumn resizes a vector column to dim dimensions if it has
+// another, running clear first to drop the vectors it holds, which no
+// longer match the index. A table or column that does not exist is skipped.
+func (db *DB) alignEmbeddingColumn(ctx context.Context, table, column string, dim int, clear string) error {
+	var current int
+	err := db.pool.QueryRow(ctx,
+		`SELECT atttypmod FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = $2`,
+		table, column,
+	).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && current == dim) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s.%s dimension: %w", table, column, err)
+	}
+
+	if _, err := db.pool.Exec(ctx, clear); err != nil {
+		return fmt.Errorf("failed to clear %s.%s: %w", table, column, err)
+	}
+	_, err = db.pool.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s TYPE vector(%d)`, table, column, dim))
+	if err != nil {
+		return fmt.Errorf("failed to change %s.%s dimension: %w", table, column, err)
+	}
+	return nil
+}
+
+// maintenanceStatements rebuild the vector indexes, whose ivfflat lists are
+// fixed when built, and refresh planner statistics after bulk changes
+var maintenanceStatements = []string{
+	`REINDEX INDEX idx_chunks_embedding`,
+	`REINDEX INDEX idx_images_embedding`,
+	`VACUUM ANALYZE chunks`,
+	`VACUUM ANALYZE images`,
+}
+
+// Maintain reindexes the vector indexes and vacuums and analyzes the chunks
+// and images tables, calling progress before each statement. VACUUM cannot
+// run in a transaction, so each statement runs on its own.
+func (db *DB) Maintain(ctx context.Context, progress func(step string)) error {
+	for _, stmt := range maintenanceStatements {
+		progress(stmt)
+		if _, err := db.pool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to run %s: %w", stmt, err)
+		}
+	}
+	return nil
+}
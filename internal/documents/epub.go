@@ -0,0 +1,162 @@
+package documents
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// epubImageTypes maps the extensions of the EPUB core raster image types to
+// their media types; SVG is left out as it cannot be captioned
+var epubImageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// opfPackage is the part of an EPUB package document (content.opf) used for
+// metadata and reading order
+type opfPackage struct {
+	Titles   []string `xml:"metadata>title"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+
+	dir string // Directory of the package document within the archive
+}
+
+// readPackage finds and parses an EPUB's package document, located through
+// META-INF/container.xml or, failing that, the first .opf file
+func readPackage(r *zip.Reader) (*opfPackage, error) {
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	opfPath := ""
+	if f, ok := files["META-INF/container.xml"]; ok {
+		var container struct {
+			Rootfiles []struct {
+				FullPath string `xml:"full-path,attr"`
+			} `xml:"rootfiles>rootfile"`
+		}
+		if err := decodeXMLFile(f, &container); err == nil && len(container.Rootfiles) > 0 {
+			opfPath = container.Rootfiles[0].FullPath
+		}
+	}
+	if _, ok := files[opfPath]; !ok {
+		opfPath = ""
+		for _, f := range r.File {
+			if strings.HasSuffix(f.Name, ".opf") {
+				opfPath = f.Name
+				break
+			}
+		}
+	}
+	if opfPath == "" {
+		return nil, fmt.Errorf("no package document found")
+	}
+
+	var pkg opfPackage
+	if err := decodeXMLFile(files[opfPath], &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", opfPath, err)
+	}
+	pkg.dir = path.Dir(opfPath)
+	return &pkg, nil
+}
+
+// title returns the package's dc:title, or ""
+func (pkg *opfPackage) title() string {
+	if len(pkg.Titles) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(pkg.Titles[0])
+}
+
+// spinePaths returns the archive paths of the content documents in reading
+// order. Spine entries missing from the manifest are skipped.
+func (pkg *opfPackage) spinePaths() []string {
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = item.Href
+	}
+
+	var paths []string
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		paths = append(paths, pkg.resolve(href))
+	}
+	return paths
+}
+
+// imageTypes returns the media types of the raster images declared in the
+// manifest, by archive path
+func (pkg *opfPackage) imageTypes() map[string]string {
+	types := make(map[string]string)
+	for _, item := range pkg.Manifest {
+		mediaType := strings.ToLower(strings.TrimSpace(item.MediaType))
+		for _, t := range epubImageTypes {
+			if t == mediaType {
+				types[pkg.resolve(item.Href)] = mediaType
+				break
+			}
+		}
+	}
+	return types
+}
+
+// resolve returns the archive path of a manifest href, which is relative to
+// the package document and may be URL-escaped
+func (pkg *opfPackage) resolve(href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(pkg.dir, href)
+}
+
+// epubImageExt reports whether an archive file is an image to extract and
+// the extension to save it with. mediaType is the type the manifest declares
+// for it, if any; otherwise the file's own extension decides, wherever in the
+// archive it is.
+func epubImageExt(name, mediaType string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if mediaType == "" {
+		_, ok := epubImageTypes[ext]
+		return ext, ok
+	}
+	if epubImageTypes[ext] == mediaType {
+		return ext, true
+	}
+	// Misnamed or extensionless: save it under its declared type
+	for e, t := range epubImageTypes {
+		if t == mediaType && e != ".jpeg" {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// decodeXMLFile decodes an XML file from the archive into v
+func decodeXMLFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
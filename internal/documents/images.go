@@ -0,0 +1,100 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/pgvector/pgvector-go"
+)
+
+// SetCaptionOnly makes image processing store only captions, embedded with
+// the text model so that ordinary text search finds them, and skip CLIP
+// image vectors
+func (p *Processor) SetCaptionOnly(captionOnly bool) {
+	p.captionOnly = captionOnly
+	p.imageEmb.SetCaptionOnly(captionOnly)
+}
+
+// embedCaption embeds an image caption with the text model in caption-only
+// mode; otherwise it returns nil
+func (p *Processor) embedCaption(ctx context.Context, caption string) (*pgvector.Vector, error) {
+	if !p.captionOnly || strings.TrimSpace(caption) == "" {
+		return nil, nil
+	}
+	embedding, err := p.textEmb.Embed(ctx, caption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed caption: %w", err)
+	}
+	return embedding, nil
+}
+
+// forEachImage calls fn for each index below n on up to imageConcurrency
+// goroutines, returning once all calls have finished. Indexes not yet
+// started when ctx is done are skipped.
+func (p *Processor) forEachImage(ctx context.Context, n int, fn func(i int)) {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(p.imageConcurrency, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= n || ctx.Err() != nil {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// EmbedImages captions and embeds stored images that have no embedding yet,
+// such as those extracted while CLIP was unavailable or that failed, and
+// saves the results; images that fail are marked failed with the reason.
+// Up to the image concurrency are processed at once; progress is called
+// after each image, one call at a time, with the image and its error if it
+// failed. It returns how many images were updated.
+func (p *Processor) EmbedImages(ctx context.Context, images []*db.Image, progress func(img *db.Image, err error)) int {
+	end, err := p.beginIngest()
+	if err != nil {
+		for _, img := range images {
+			if progress != nil {
+				progress(img, err)
+			}
+		}
+		return 0
+	}
+	defer end()
+
+	var mu sync.Mutex
+	updated := 0
+	p.forEachImage(ctx, len(images), func(i int) {
+		img := images[i]
+		caption, embedding, err := p.imageEmb.ProcessImage(ctx, img.FilePath)
+		var captionEmbedding *pgvector.Vector
+		if err == nil {
+			captionEmbedding, err = p.embedCaption(ctx, caption)
+		}
+		if err == nil {
+			err = p.db.UpdateImage(ctx, img.ID, caption, embedding, captionEmbedding)
+		} else if ctx.Err() == nil {
+			p.db.UpdateImageFailed(ctx, img.ID, err.Error())
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err == nil {
+			updated++
+		}
+		if progress != nil {
+			progress(img, err)
+		}
+	})
+	return updated
+}
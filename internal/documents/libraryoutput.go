@@ -0,0 +1,69 @@
+package documents
+
+import (
+	"strings"
+	"sync"
+)
+
+// libraryOutput collects the lines C libraries such as MuPDF print on file
+// descriptor 2 while documents are processed, so that each document's
+// processing log keeps what was printed about it. Descriptor 2 is shared by
+// the whole process, so a line printed while several documents are
+// processed at once is kept by each of them.
+var libraryOutput struct {
+	mu   sync.Mutex
+	docs map[*strings.Builder]bool // Output of each run in progress
+	sync func()                    // Waits for the lines printed so far; nil if not captured
+}
+
+// SetLibraryOutputSync enables attributing library output to documents.
+// sync must return once every line printed on descriptor 2 before it was
+// called has been passed to LibraryOutputLine. nil disables attribution.
+func SetLibraryOutputSync(sync func()) {
+	libraryOutput.mu.Lock()
+	defer libraryOutput.mu.Unlock()
+	libraryOutput.sync = sync
+}
+
+// LibraryOutputLine adds a line read from descriptor 2 to the output of the
+// documents being processed, and reports whether there were any
+func LibraryOutputLine(line string) bool {
+	libraryOutput.mu.Lock()
+	defer libraryOutput.mu.Unlock()
+	for out := range libraryOutput.docs {
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+	return len(libraryOutput.docs) > 0
+}
+
+// watchLibraryOutput starts collecting library output for a processing run.
+// The returned function stops and returns what was printed in between.
+func watchLibraryOutput() func() string {
+	libraryOutput.mu.Lock()
+	if libraryOutput.sync == nil {
+		libraryOutput.mu.Unlock()
+		return func() string { return "" }
+	}
+	out := &strings.Builder{}
+	if libraryOutput.docs == nil {
+		libraryOutput.docs = make(map[*strings.Builder]bool)
+	}
+	libraryOutput.docs[out] = true
+	libraryOutput.mu.Unlock()
+
+	return func() string {
+		libraryOutput.mu.Lock()
+		sync := libraryOutput.sync
+		libraryOutput.mu.Unlock()
+		// Lines still in the pipe belong to this run too
+		if sync != nil {
+			sync()
+		}
+
+		libraryOutput.mu.Lock()
+		defer libraryOutput.mu.Unlock()
+		delete(libraryOutput.docs, out)
+		return strings.TrimSpace(out.String())
+	}
+}
@@ -0,0 +1,73 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dream-ai/cli/internal/trace"
+)
+
+// processingLog collects a readable record of one processing run, stored
+// with the document so what happened to it can be inspected later
+type processingLog struct {
+	b        strings.Builder
+	start    time.Time
+	done     func(error)   // Ends the run's trace
+	library  func() string // Stops collecting library output and returns it
+	chunks   int           // Chunks the document has after the run
+	images   int           // Images the run stored
+	warnings []string
+}
+
+// newProcessingLog starts a log for a run of the given kind. The run is
+// traced under the request ID of ctx, which the log records.
+func newProcessingLog(ctx context.Context, kind, filePath string) *processingLog {
+	l := &processingLog{start: time.Now(), done: trace.Begin(ctx, kind+" "+filePath), library: watchLibraryOutput()}
+	l.printf("%s: %s", kind, filePath)
+	if id := trace.ID(ctx); id != "" {
+		l.printf("request %s", id)
+	}
+	return l
+}
+
+// printf adds a timestamped line to the log
+func (l *processingLog) printf(format string, args ...interface{}) {
+	l.b.WriteString(time.Now().Format("15:04:05 "))
+	l.b.WriteString(fmt.Sprintf(format, args...))
+	l.b.WriteString("\n")
+}
+
+// warnf adds a warning line to the log and keeps the warning for the
+// run's result
+func (l *processingLog) warnf(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	l.warnings = append(l.warnings, warning)
+	l.printf("warning: %s", warning)
+}
+
+// fill copies the run's counts and warnings into r, if r is non-nil
+func (l *processingLog) fill(r *ProcessResult) {
+	if r == nil {
+		return
+	}
+	r.Chunks = l.chunks
+	r.Images = l.images
+	r.Warnings = l.warnings
+}
+
+// finish records the outcome of the run and returns the complete log
+func (l *processingLog) finish(err error) string {
+	l.done(err)
+	if output := l.library(); output != "" {
+		l.printf("library output:\n%s", output)
+	}
+	elapsed := time.Since(l.start).Round(time.Millisecond)
+	if err != nil {
+		l.printf("failed after %s: %v", elapsed, err)
+	} else {
+		l.printf("finished in %s", elapsed)
+	}
+	return l.b.String()
+}
@@ -0,0 +1,78 @@
+package documents
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NormalizeOptions selects the clean-up rules applied to extracted text
+// before it is chunked
+type NormalizeOptions struct {
+	Dehyphenate        bool // Rejoin words hyphenated across line breaks
+	CollapseWhitespace bool // Drop invisible characters and squeeze runs of blank space
+	StripPageNumbers   bool // Remove lines holding nothing but a page number
+}
+
+var (
+	// A letter, a hyphen at the end of a line, and a lowercase letter
+	// continuing the word on the next line
+	hyphenBreakPattern = regexp.MustCompile(`(\p{L})-[ \t]*\r?\n[ \t]*(\p{Ll})`)
+	// Lines such as "12", "- 12 -", "Page 12" or "12 of 300", and nothing
+	// else, so a line starting with a number such as a year is kept
+	pageNumberPattern = regexp.MustCompile(`(?im)^[ \t]*(?:page[ \t]+)?[-–—]?[ \t]*\d{1,4}(?:[ \t]+of[ \t]+\d{1,4})?[ \t]*[-–—]?[ \t]*(?:\r?\n|$)`)
+	spaceRunPattern   = regexp.MustCompile(`[ \t\f\v\x{00A0}]+`)
+	blankLinesPattern = regexp.MustCompile(`\n[ \t]*(?:\n[ \t]*)+\n`)
+)
+
+// invisibleChars are removed by CollapseWhitespace: soft hyphens,
+// zero-width spaces and joiners, and byte order marks
+var invisibleChars = strings.NewReplacer(
+	"\u00ad", "",
+	"\u200b", "",
+	"\u200c", "",
+	"\u200d", "",
+	"\ufeff", "",
+)
+
+// enabled reports whether any rule is on
+func (o NormalizeOptions) enabled() bool {
+	return o.Dehyphenate || o.CollapseWhitespace || o.StripPageNumbers
+}
+
+// NormalizeText applies the enabled rules to text
+func NormalizeText(text string, opts NormalizeOptions) string {
+	if opts.StripPageNumbers {
+		text = pageNumberPattern.ReplaceAllString(text, "")
+	}
+	if opts.CollapseWhitespace {
+		text = invisibleChars.Replace(text)
+	}
+	if opts.Dehyphenate {
+		text = hyphenBreakPattern.ReplaceAllString(text, "$1$2")
+	}
+	if opts.CollapseWhitespace {
+		text = spaceRunPattern.ReplaceAllString(text, " ")
+		text = blankLinesPattern.ReplaceAllString(text, "\n\n")
+	}
+	return text
+}
+
+// normalizeParsed normalizes a parsed document's text in place. The text is
+// normalized piecewise between section offsets so the offsets can be moved
+// to the same places in the new text.
+func normalizeParsed(parsed *ParsedDocument, opts NormalizeOptions) {
+	if !opts.enabled() {
+		return
+	}
+
+	var b strings.Builder
+	prev := 0
+	for i, section := range parsed.Sections {
+		offset := min(max(section.Offset, prev), len(parsed.Text))
+		b.WriteString(NormalizeText(parsed.Text[prev:offset], opts))
+		prev = offset
+		parsed.Sections[i].Offset = b.Len()
+	}
+	b.WriteString(NormalizeText(parsed.Text[prev:], opts))
+	parsed.Text = b.String()
+}
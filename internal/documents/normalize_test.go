@@ -0,0 +1,30 @@
+package documents
+
+import "testing"
+
+// TestNormalizeTextPageNumbers strips lines holding just a page number and
+// keeps lines that only start with a number
+func TestNormalizeTextPageNumbers(t *testing.T) {
+	opts := NormalizeOptions{StripPageNumbers: true}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"bare number", "end of page\n12\nnext page", "end of page\nnext page"},
+		{"dashed", "end of page\n  - 12 -  \nnext page", "end of page\nnext page"},
+		{"page label", "end of page\nPage 12\nnext page", "end of page\nnext page"},
+		{"of total", "end of page\n12 of 300\r\nnext page", "end of page\nnext page"},
+		{"last line", "end of page\n12", "end of page\n"},
+		{"year starting a line", "1984 was the year\nit began", "1984 was the year\nit began"},
+		{"number starting a line", "end of page\n12 dreams of falling\n", "end of page\n12 dreams of falling\n"},
+		{"numbered heading", "Chapter\n3. Water\n", "Chapter\n3. Water\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeText(tt.text, opts); got != tt.want {
+				t.Errorf("NormalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
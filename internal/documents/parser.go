@@ -2,19 +2,43 @@ package documents
 
 import (
 	"archive/zip"
+	"bytes"
+	"errors"
 	"fmt"
+	"image/jpeg"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/gen2brain/go-fitz"
 )
 
+// Errors describing why a document could not be processed. They prefix the
+// error_message stored for a document so the UI can tell them apart.
+var (
+	ErrEmptyFile         = errors.New("empty file")
+	ErrCorruptFile       = errors.New("corrupt or truncated file")
+	ErrPasswordProtected = errors.New("password-protected document")
+	ErrUnsupportedType   = errors.New("unsupported file type")
+)
+
 // ParsedDocument contains extracted text and images from a document
 type ParsedDocument struct {
-	Text   string
-	Images []ImageData
+	Title    string // From the document's metadata, if any
+	Text     string
+	Images   []ImageData
+	Sections []Section // Outline entries in text order, if the document has an outline
+	Pages    int       // Pages (or EPUB content documents) read
+	Warnings []string  // Problems that did not stop parsing, e.g. an unreadable page
+}
+
+// Section is an outline (table of contents) entry
+type Section struct {
+	Title  string
+	Page   int // 1-based page the section starts on
+	Offset int // Byte offset in Text where the section's page starts
 }
 
 // ImageData contains image file path and data
@@ -22,6 +46,8 @@ type ImageData struct {
 	Index    int
 	FilePath string
 	Data     []byte
+	Page     int    // 1-based page the image was rendered from; 0 if none
+	Location string // Where the image is stored in the document, e.g. its EPUB archive path
 }
 
 // Parser interface for document parsing
@@ -29,14 +55,63 @@ type Parser interface {
 	Parse(filePath string) (*ParsedDocument, error)
 }
 
+// Page image formats
+const (
+	ImageFormatPNG  = "png"
+	ImageFormatJPEG = "jpeg"
+)
+
+// pageRenderer renders document pages to encoded images
+type pageRenderer struct {
+	format  string // ImageFormatPNG or ImageFormatJPEG
+	quality int    // JPEG quality, 1-100
+}
+
+// setFormat sets the output format; unknown formats fall back to PNG
+func (r *pageRenderer) setFormat(format string, quality int) {
+	r.format = ImageFormatPNG
+	if format == ImageFormatJPEG || format == "jpg" {
+		r.format = ImageFormatJPEG
+	}
+	if quality < 1 || quality > 100 {
+		quality = jpeg.DefaultQuality
+	}
+	r.quality = quality
+}
+
+// render renders a page at 150 DPI (a reasonable quality/size balance) and
+// returns the encoded image and its file extension
+func (r *pageRenderer) render(doc *fitz.Document, page int) ([]byte, string, error) {
+	if r.format != ImageFormatJPEG {
+		data, err := doc.ImagePNG(page, 150.0)
+		return data, ".png", err
+	}
+
+	img, err := doc.ImageDPI(page, 150.0)
+	if err != nil {
+		return nil, "", err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: r.quality}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), ".jpg", nil
+}
+
 // PDFParser parses PDF files
 type PDFParser struct {
 	imageDir string
+	renderer pageRenderer
 }
 
 // NewPDFParser creates a new PDF parser
 func NewPDFParser(imageDir string) *PDFParser {
-	return &PDFParser{imageDir: imageDir}
+	return &PDFParser{imageDir: imageDir, renderer: pageRenderer{format: ImageFormatPNG}}
+}
+
+// SetImageFormat sets the format (png or jpeg) used for rendered page images
+func (p *PDFParser) SetImageFormat(format string, quality int) {
+	p.renderer.setFormat(format, quality)
 }
 
 // Parse extracts text and images from a PDF file
@@ -49,51 +124,108 @@ func (p *PDFParser) Parse(filePath string) (*ParsedDocument, error) {
 
 	var textParts []string
 	var images []ImageData
+	var warnings []string
 	imageIndex := 0
 
+	// pageOffsets[i] is where page i starts in the joined text
+	pageOffsets := make([]int, doc.NumPage())
+	textLen := 0
+
 	// Extract text and images from each page
 	for i := 0; i < doc.NumPage(); i++ {
+		pageOffsets[i] = textLen
 		text, err := doc.Text(i)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("page %d: failed to extract text: %v", i+1, err))
+		}
 		if err == nil && strings.TrimSpace(text) != "" {
+			if len(textParts) > 0 {
+				textLen += len("\n\n")
+			}
 			textParts = append(textParts, text)
+			textLen += len(text)
 		}
 		
-		// Extract page as image using ImagePNG (returns []byte PNG data)
-		// Use 150 DPI for reasonable quality/size balance
-		imgData, err := doc.ImagePNG(i, 150.0)
+		// Extract page as image in the configured format
+		imgData, ext, err := p.renderer.render(doc, i)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("page %d: failed to render image: %v", i+1, err))
+		}
 		if err == nil && len(imgData) > 0 {
 			// Save page as image
 			baseName := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
 			// Sanitize baseName for filesystem
 			baseName = strings.ReplaceAll(baseName, " ", "_")
 			baseName = strings.ReplaceAll(baseName, "/", "_")
-			imgPath := filepath.Join(p.imageDir, fmt.Sprintf("pdf_%s_page%d.png", baseName, i))
+			imgPath := filepath.Join(p.imageDir, fmt.Sprintf("pdf_%s_page%d%s", baseName, i, ext))
 			
 			if err := os.WriteFile(imgPath, imgData, 0644); err == nil {
 				images = append(images, ImageData{
 					Index:    imageIndex,
 					FilePath: imgPath,
 					Data:     imgData,
+					Page:     i + 1,
 				})
 				imageIndex++
+			} else {
+				warnings = append(warnings, fmt.Sprintf("page %d: failed to save image: %v", i+1, err))
 			}
 		}
 	}
 
 	return &ParsedDocument{
-		Text:   strings.Join(textParts, "\n\n"),
-		Images: images,
+		Title:    strings.TrimSpace(doc.Metadata()["title"]),
+		Text:     strings.Join(textParts, "\n\n"),
+		Images:   images,
+		Sections: outlineSections(doc, pageOffsets),
+		Pages:    doc.NumPage(),
+		Warnings: warnings,
 	}, nil
 }
 
+// outlineSections maps a document's outline to sections positioned in its
+// text. Entries that do not point at a page of the document are skipped.
+func outlineSections(doc *fitz.Document, pageOffsets []int) []Section {
+	outline, err := doc.ToC()
+	if err != nil {
+		return nil
+	}
+
+	var sections []Section
+	for _, entry := range outline {
+		title := strings.Join(strings.Fields(entry.Title), " ")
+		if title == "" || entry.Page < 0 || entry.Page >= len(pageOffsets) {
+			continue
+		}
+		sections = append(sections, Section{
+			Title:  title,
+			Page:   entry.Page + 1,
+			Offset: pageOffsets[entry.Page],
+		})
+	}
+
+	// Outlines are usually in page order already; a stable sort keeps a
+	// chapter before its first subsection when both start on the same page
+	sort.SliceStable(sections, func(a, b int) bool {
+		return sections[a].Offset < sections[b].Offset
+	})
+	return sections
+}
+
 // EPUBParser parses EPUB files using go-fitz (which supports EPUB)
 type EPUBParser struct {
 	imageDir string
+	renderer pageRenderer
 }
 
 // NewEPUBParser creates a new EPUB parser
 func NewEPUBParser(imageDir string) *EPUBParser {
-	return &EPUBParser{imageDir: imageDir}
+	return &EPUBParser{imageDir: imageDir, renderer: pageRenderer{format: ImageFormatPNG}}
+}
+
+// SetImageFormat sets the format (png or jpeg) used for rendered page images
+func (p *EPUBParser) SetImageFormat(format string, quality int) {
+	p.renderer.setFormat(format, quality)
 }
 
 // Parse extracts text and images from an EPUB file using go-fitz
@@ -107,43 +239,71 @@ func (p *EPUBParser) Parse(filePath string) (*ParsedDocument, error) {
 
 	var textParts []string
 	var images []ImageData
+	var warnings []string
 	imageIndex := 0
 
 	// Extract text and images from each page
 	for i := 0; i < doc.NumPage(); i++ {
 		text, err := doc.Text(i)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("page %d: failed to extract text: %v", i+1, err))
+		}
 		if err == nil && strings.TrimSpace(text) != "" {
 			textParts = append(textParts, text)
 		}
 		
-		// Extract page as image using ImagePNG (returns []byte)
-		// Use 150 DPI for reasonable quality/size balance
-		imgData, err := doc.ImagePNG(i, 150.0)
+		// Extract page as image in the configured format
+		imgData, ext, err := p.renderer.render(doc, i)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("page %d: failed to render image: %v", i+1, err))
+		}
 		if err == nil && len(imgData) > 0 {
 			// Save page as image
 			baseName := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
 			// Sanitize baseName for filesystem
 			baseName = strings.ReplaceAll(baseName, " ", "_")
 			baseName = strings.ReplaceAll(baseName, "/", "_")
-			imgPath := filepath.Join(p.imageDir, fmt.Sprintf("pdf_%s_page%d.png", baseName, i))
+			imgPath := filepath.Join(p.imageDir, fmt.Sprintf("pdf_%s_page%d%s", baseName, i, ext))
 			
 			if err := os.WriteFile(imgPath, imgData, 0644); err == nil {
 				images = append(images, ImageData{
 					Index:    imageIndex,
 					FilePath: imgPath,
 					Data:     imgData,
+					Page:     i + 1,
 				})
 				imageIndex++
+			} else {
+				warnings = append(warnings, fmt.Sprintf("page %d: failed to save image: %v", i+1, err))
 			}
 		}
 	}
 
 	return &ParsedDocument{
-		Text:   strings.Join(textParts, "\n\n"),
-		Images: images,
+		Title:    strings.TrimSpace(doc.Metadata()["title"]),
+		Text:     strings.Join(textParts, "\n\n"),
+		Images:   images,
+		Pages:    doc.NumPage(),
+		Warnings: warnings,
 	}, nil
 }
 
+// describeParseError wraps a parser error with the file name and a friendly
+// classification where one applies
+func describeParseError(filePath string, err error) error {
+	name := filepath.Base(filePath)
+	switch {
+	case errors.Is(err, fitz.ErrNeedsPassword):
+		return fmt.Errorf("%w: %s requires a password to open", ErrPasswordProtected, name)
+	case errors.Is(err, fitz.ErrOpenDocument),
+		errors.Is(err, zip.ErrFormat),
+		errors.Is(err, zip.ErrChecksum),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: %s could not be read: %w", ErrCorruptFile, name, err)
+	}
+	return fmt.Errorf("failed to parse %s: %w", name, err)
+}
+
 // extractTextFromHTML performs basic HTML tag removal and entity decoding
 func extractTextFromHTML(html string) string {
 	// Simple approach: remove HTML tags and decode entities
@@ -210,56 +370,102 @@ func (p *EPUBParserV2) Parse(filePath string) (*ParsedDocument, error) {
 
 	var textParts []string
 	var images []ImageData
+	var warnings []string
 	imageIndex := 0
+	var title string
 
+	files := make(map[string]*zip.File, len(r.File))
 	for _, f := range r.File {
-		// Extract HTML/XHTML files
-		if strings.HasSuffix(f.Name, ".html") || strings.HasSuffix(f.Name, ".xhtml") || strings.HasSuffix(f.Name, ".htm") {
-			rc, err := f.Open()
-			if err != nil {
-				continue
-			}
-			html, err := io.ReadAll(rc)
-			rc.Close()
-			if err != nil {
-				continue
+		files[f.Name] = f
+	}
+
+	// Read content documents in spine (reading) order, falling back to
+	// archive order when the package document is missing or unreadable
+	var contentDocs []*zip.File
+	pkg, err := readPackage(&r.Reader)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("no reading order, using archive order: %v", err))
+	} else {
+		title = pkg.title()
+		for _, name := range pkg.spinePaths() {
+			if f, ok := files[name]; ok {
+				contentDocs = append(contentDocs, f)
+			} else {
+				warnings = append(warnings, fmt.Sprintf("%s: listed in spine but missing from archive", name))
 			}
-			text := extractTextFromHTML(string(html))
-			if strings.TrimSpace(text) != "" {
-				textParts = append(textParts, text)
+		}
+	}
+	if len(contentDocs) == 0 {
+		for _, f := range r.File {
+			if strings.HasSuffix(f.Name, ".html") || strings.HasSuffix(f.Name, ".xhtml") || strings.HasSuffix(f.Name, ".htm") {
+				contentDocs = append(contentDocs, f)
 			}
 		}
+	}
 
-		// Extract images
-		if strings.HasPrefix(f.Name, "OEBPS/Images/") || strings.HasPrefix(f.Name, "images/") || strings.Contains(f.Name, ".jpg") || strings.Contains(f.Name, ".png") || strings.Contains(f.Name, ".jpeg") {
-			rc, err := f.Open()
-			if err != nil {
-				continue
-			}
-			imgData, err := io.ReadAll(rc)
-			rc.Close()
-			if err != nil {
-				continue
-			}
+	// Extract HTML/XHTML files
+	for _, f := range contentDocs {
+		rc, err := f.Open()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: failed to open: %v", f.Name, err))
+			continue
+		}
+		html, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: failed to read: %v", f.Name, err))
+			continue
+		}
+		text := extractTextFromHTML(string(html))
+		if strings.TrimSpace(text) != "" {
+			textParts = append(textParts, text)
+		}
+	}
 
-			ext := filepath.Ext(f.Name)
-			if ext == "" {
-				ext = ".png"
-			}
-			imgPath := filepath.Join(p.imageDir, fmt.Sprintf("epub_%s_%d%s", filepath.Base(filePath), imageIndex, ext))
-			if err := os.WriteFile(imgPath, imgData, 0644); err == nil {
-				images = append(images, ImageData{
-					Index:    imageIndex,
-					FilePath: imgPath,
-					Data:     imgData,
-				})
-				imageIndex++
-			}
+	// Extract images, as declared in the manifest or by their extension
+	var imageTypes map[string]string
+	if pkg != nil {
+		imageTypes = pkg.imageTypes()
+	}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		ext, ok := epubImageExt(f.Name, imageTypes[f.Name])
+		if !ok {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: failed to open: %v", f.Name, err))
+			continue
+		}
+		imgData, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: failed to read: %v", f.Name, err))
+			continue
+		}
+
+		imgPath := filepath.Join(p.imageDir, fmt.Sprintf("epub_%s_%d%s", filepath.Base(filePath), imageIndex, ext))
+		if err := os.WriteFile(imgPath, imgData, 0644); err == nil {
+			images = append(images, ImageData{
+				Index:    imageIndex,
+				FilePath: imgPath,
+				Data:     imgData,
+				Location: f.Name,
+			})
+			imageIndex++
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s: failed to save image: %v", f.Name, err))
 		}
 	}
 
 	return &ParsedDocument{
-		Text:   strings.Join(textParts, "\n\n"),
-		Images: images,
+		Title:    title,
+		Text:     strings.Join(textParts, "\n\n"),
+		Images:   images,
+		Pages:    len(contentDocs),
+		Warnings: warnings,
 	}, nil
 }
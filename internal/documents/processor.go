@@ -3,26 +3,63 @@ package documents
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/dream-ai/cli/internal/db"
 	"github.com/dream-ai/cli/internal/embeddings"
+	"github.com/pgvector/pgvector-go"
 )
 
+// maxChunkOverlap is the largest chunk overlap, in percent, that still lets
+// each chunk move the split forward
+const maxChunkOverlap = 90
+
+// ErrEmbeddingModelChanged is returned when ingesting would store chunks
+// embedded with a different model than the existing index
+var ErrEmbeddingModelChanged = errors.New("embedding model changed")
+
+// ErrRebuildInProgress is returned by ingestion while RebuildEmbeddings
+// runs, since chunks stored then would miss the rebuilt vectors
+var ErrRebuildInProgress = errors.New("an embedding rebuild is in progress; try again when it finishes")
+
 // Processor handles document processing with incremental updates
 type Processor struct {
 	db         *db.DB
 	textEmb    *embeddings.TextEmbedder
 	imageEmb   *embeddings.ImageEmbedder
-	pdfParser  *PDFParser
-	epubParser Parser // Use interface to support both EPUBParser and EPUBParserV2
+	parsers    map[string]Parser // By file type, from the Parsers registry
+	chunkMu    sync.Mutex // Guards chunkSize and chunkOverlap, which Settings changes
 	chunkSize  int
 	chunkOverlap int
+	minChunkChars int
+	thumbnailSize int // Longer side of image thumbnails in pixels; 0 disables them
+	imageConcurrency int // Images captioned and embedded at once, each by its own CLIP process
+	captionOnly bool // Store captions embedded with the text model instead of CLIP image vectors
+	quickScan bool // Skip hashing processed files whose size and modification time are unchanged
+	normalization NormalizeOptions
+	// indexModel is the embedding model the index was built with, set when
+	// the text embedder cannot use it; ingestion is refused while it is set.
+	// It holds a string, and is changed while documents are processed.
+	indexModel atomic.Value
+	summarizer Summarizer // Summarizes each document for document-level retrieval; nil skips it
+	// ingest is held for reading by each ingestion and for writing by
+	// RebuildEmbeddings, so that neither starts while the other runs
+	ingest sync.RWMutex
+
+	retryAttempts int
+	retryBackoff  time.Duration
+
+	processed atomic.Int64 // Documents created or updated since startup
 }
 
 // NewProcessor creates a new document processor
@@ -33,76 +70,281 @@ func NewProcessor(
 	imageDir string,
 	chunkSize, chunkOverlap int,
 ) *Processor {
-	return &Processor{
+	p := &Processor{
 		db:          db,
 		textEmb:     textEmb,
 		imageEmb:    imageEmb,
-		pdfParser:   NewPDFParser(imageDir),
-		epubParser:  NewEPUBParserV2(imageDir), // Use zip-based parser for EPUB 3.0 support
-		chunkSize:   chunkSize,
-		chunkOverlap: chunkOverlap,
+		parsers:     Parsers.NewParsers(imageDir),
+		retryAttempts: 1,
+		imageConcurrency: 1,
+	}
+	p.SetChunking(chunkSize, chunkOverlap)
+	return p
+}
+
+// SetChunking sets the chunk size and overlap percent used for documents
+// processed from now on; documents already stored keep their chunks until
+// reprocessed. An overlap outside 0-90% is clamped.
+func (p *Processor) SetChunking(chunkSize, chunkOverlap int) {
+	if chunkOverlap < 0 || chunkOverlap > maxChunkOverlap {
+		clamped := max(0, min(chunkOverlap, maxChunkOverlap))
+		log.Printf("warning: chunk_overlap_percent %d%% is outside 0-%d%%, using %d%%", chunkOverlap, maxChunkOverlap, clamped)
+		chunkOverlap = clamped
 	}
+	p.chunkMu.Lock()
+	defer p.chunkMu.Unlock()
+	p.chunkSize = chunkSize
+	p.chunkOverlap = chunkOverlap
 }
 
-// ProcessDocument processes a document if it's new or changed
-func (p *Processor) ProcessDocument(ctx context.Context, filePath string) error {
+// chunking returns the chunk size and overlap percent set by SetChunking
+func (p *Processor) chunking() (size, overlap int) {
+	p.chunkMu.Lock()
+	defer p.chunkMu.Unlock()
+	return p.chunkSize, p.chunkOverlap
+}
+
+// SetImageFormat sets the format (png or jpeg) and JPEG quality used when
+// writing rendered page images
+func (p *Processor) SetImageFormat(format string, quality int) {
+	for _, parser := range p.parsers {
+		if renderer, ok := parser.(interface{ SetImageFormat(string, int) }); ok {
+			renderer.SetImageFormat(format, quality)
+		}
+	}
+}
+
+// Processed returns how many documents were processed (created or updated)
+// since the processor was created
+func (p *Processor) Processed() int {
+	return int(p.processed.Load())
+}
+
+// SetThumbnailSize sets the longer side, in pixels, of the thumbnails stored
+// with each image. 0 disables thumbnails.
+func (p *Processor) SetThumbnailSize(size int) {
+	p.thumbnailSize = max(size, 0)
+}
+
+// SetImageConcurrency sets how many images are captioned and embedded at
+// once. Each runs its own CLIP process, so this trades CPU and memory for
+// speed. Values below 1 are treated as 1.
+func (p *Processor) SetImageConcurrency(n int) {
+	p.imageConcurrency = max(n, 1)
+}
+
+// SetQuickScan sets whether processed files whose size and modification
+// time match those recorded are skipped without being read and hashed.
+// Changes that keep both, which are rare, then go unnoticed.
+func (p *Processor) SetQuickScan(enabled bool) {
+	p.quickScan = enabled
+}
+
+// SetNormalization sets the clean-up rules applied to extracted text
+// before chunking
+func (p *Processor) SetNormalization(opts NormalizeOptions) {
+	p.normalization = opts
+}
+
+// SetMinChunkChars sets the length below which a trailing chunk is merged
+// into the previous chunk; a document's only chunk is always kept. 0, the
+// default, keeps all chunks.
+func (p *Processor) SetMinChunkChars(n int) {
+	p.minChunkChars = max(n, 0)
+}
+
+// ProcessOutcome is what processing did with a document
+type ProcessOutcome int
+
+const (
+	ProcessFailed  ProcessOutcome = iota // Processing returned an error
+	ProcessCreated                       // A new document was stored
+	ProcessUpdated                       // A known document was re-parsed in place
+	ProcessSkipped                       // The file's content is already stored
+)
+
+// String returns the outcome as a lowercase word
+func (o ProcessOutcome) String() string {
+	switch o {
+	case ProcessCreated:
+		return "created"
+	case ProcessUpdated:
+		return "updated"
+	case ProcessSkipped:
+		return "skipped"
+	}
+	return "failed"
+}
+
+// ProcessResult describes one processing run. Chunks and Images are zero
+// for skipped documents and may be partial for failed ones.
+type ProcessResult struct {
+	Outcome  ProcessOutcome
+	Chunks   int      // Chunks the document has after processing
+	Images   int      // Images stored by this run
+	Warnings []string // Non-fatal problems, such as unreadable pages or images
+	Duration time.Duration
+}
+
+// RefuseModelChange makes ingestion fail with ErrEmbeddingModelChanged when
+// the text embedder cannot use indexModel, the model the existing chunks were
+// embedded with, so incompatible vectors are never mixed in one index
+func (p *Processor) RefuseModelChange(indexModel string) {
+	refused := ""
+	if indexModel != "" && !p.textEmb.HasModel(indexModel) {
+		refused = indexModel
+	}
+	p.indexModel.Store(refused)
+}
+
+// refusedModel returns the model set by RefuseModelChange, or "" if
+// ingestion is allowed
+func (p *Processor) refusedModel() string {
+	model, _ := p.indexModel.Load().(string)
+	return model
+}
+
+// beginIngest returns a func that ends the ingestion it starts, or
+// ErrRebuildInProgress while the embeddings are being rebuilt
+func (p *Processor) beginIngest() (func(), error) {
+	if !p.ingest.TryRLock() {
+		return nil, ErrRebuildInProgress
+	}
+	return p.ingest.RUnlock, nil
+}
+
+// checkModel returns ErrEmbeddingModelChanged if ingestion is refused
+func (p *Processor) checkModel() error {
+	indexModel := p.refusedModel()
+	if indexModel == "" {
+		return nil
+	}
+	return fmt.Errorf("%w: the index was built with %s but embeddings.text_model is %s; rebuild the index or start with -force",
+		ErrEmbeddingModelChanged, indexModel, p.textEmb.Model())
+}
+
+// ProcessDocument processes a document if it's new or changed and reports
+// what it did. The result is never nil, and its outcome is ProcessFailed
+// whenever err is non-nil.
+func (p *Processor) ProcessDocument(ctx context.Context, filePath string) (*ProcessResult, error) {
+	result := &ProcessResult{Outcome: ProcessFailed}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	end, err := p.beginIngest()
+	if err != nil {
+		return result, err
+	}
+	defer end()
+	if err := p.checkModel(); err != nil {
+		return result, err
+	}
+	info, err := checkFile(filePath)
+	if errors.Is(err, ErrEmptyFile) {
+		p.recordEmptyFile(ctx, filePath, err)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	pathDoc, err := p.db.GetDocumentByPath(ctx, filePath)
+	if err != nil {
+		return result, fmt.Errorf("failed to check existing document: %w", err)
+	}
+	// With quick scans a processed file of the recorded size and time is
+	// taken to be unchanged without reading it
+	if p.quickScan && pathDoc != nil && pathDoc.ProcessedAt != nil && FileUnchanged(pathDoc, info) {
+		result.Outcome = ProcessSkipped
+		return result, nil
+	}
+
 	// Compute file hash
-	hash, err := computeFileHash(filePath)
+	hash, err := ComputeFileHash(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to compute hash: %w", err)
+		return result, fmt.Errorf("failed to compute hash: %w", err)
 	}
 
 	// Check if document already processed
 	existingDoc, err := p.db.GetDocumentByHash(ctx, hash)
 	if err != nil {
-		return fmt.Errorf("failed to check existing document: %w", err)
+		return result, fmt.Errorf("failed to check existing document: %w", err)
 	}
 
 	if existingDoc != nil {
-		// Document already processed, skip silently
-		return nil
+		// Document already processed; record its size and time if this is
+		// its file, so the next quick scan need not hash it
+		if existingDoc.FilePath == filePath {
+			p.recordFileStat(ctx, existingDoc, info)
+		}
+		result.Outcome = ProcessSkipped
+		return result, nil
 	}
 
 	// Determine file type
-	fileType := strings.ToLower(filepath.Ext(filePath))
-	if fileType == ".pdf" {
-		fileType = "pdf"
-	} else if fileType == ".epub" {
-		fileType = "epub"
-	} else {
-		return fmt.Errorf("unsupported file type: %s", fileType)
+	fileType, err := DetectType(filePath)
+	if err != nil {
+		return result, err
+	}
+
+	// A changed file at a known path is updated in place
+	if pathDoc != nil {
+		p.recordFileStat(ctx, pathDoc, info)
+		if err := p.updateDocument(ctx, pathDoc, hash, result); err != nil {
+			return result, err
+		}
+		result.Outcome = ProcessUpdated
+		return result, nil
 	}
 
 	// Create document record
 	doc, err := p.db.CreateDocument(ctx, filePath, hash, fileType)
 	if err != nil {
-		return fmt.Errorf("failed to create document record: %w", err)
+		return result, fmt.Errorf("failed to create document record: %w", err)
 	}
+	p.recordFileStat(ctx, doc, info)
 
-	// Parse document
-	var parsed *ParsedDocument
-	if fileType == "pdf" {
-		parsed, err = p.pdfParser.Parse(filePath)
-	} else {
-		parsed, err = p.epubParser.Parse(filePath)
+	plog := newProcessingLog(ctx, "processing new document", filePath)
+	err = p.processNewDocument(ctx, doc, plog)
+	p.db.UpdateDocumentLog(ctx, doc.ID, plog.finish(err))
+	plog.fill(result)
+	if err != nil {
+		return result, err
 	}
+	p.processed.Add(1)
+	result.Outcome = ProcessCreated
+	return result, nil
+}
+
+// processNewDocument parses a newly created document and stores its chunks
+// and images, recording what happened in plog
+func (p *Processor) processNewDocument(ctx context.Context, doc *db.Document, plog *processingLog) error {
+	// Parse document
+	parsed, err := p.parse(doc.FileType, doc.FilePath)
 	if err != nil {
-		errorMsg := fmt.Sprintf("failed to parse document: %v", err)
-		p.db.UpdateDocumentError(ctx, doc.ID, errorMsg)
-		return fmt.Errorf("failed to parse document: %w", err)
+		err = describeParseError(doc.FilePath, err)
+		p.db.UpdateDocumentError(ctx, doc.ID, err.Error())
+		return err
 	}
+	p.db.UpdateDocumentTitle(ctx, doc.ID, parsed.Title)
+	logParsed(plog, parsed)
+	p.normalize(parsed, plog)
 
 	// Process text chunks
-	if err := p.processTextChunks(ctx, doc.ID, parsed.Text); err != nil {
+	if err := p.processTextChunks(ctx, doc.ID, parsed, plog); err != nil {
 		errorMsg := fmt.Sprintf("failed to process text chunks: %v", err)
 		p.db.UpdateDocumentError(ctx, doc.ID, errorMsg)
 		return fmt.Errorf("failed to process text chunks: %w", err)
 	}
+	chunkSize, chunkOverlap := p.chunking()
+	p.db.UpdateDocumentChunking(ctx, doc.ID, chunkSize, chunkOverlap, p.textEmb.Model())
+	p.summarize(ctx, doc.ID, parsed.Title, doc.FilePath, plog)
 
 	// Process images (non-blocking - continue even if image processing fails)
-	if err := p.processImages(ctx, doc.ID, parsed.Images); err != nil {
-		// Silently continue - image processing is optional
-		// Error is not critical for document processing
+	if err := p.processImages(ctx, doc.ID, parsed.Images, plog); err != nil {
+		// Image processing is optional; the failure is kept in the log
+		plog.warnf("failed to store images: %v", err)
 	}
 
 	// Mark document as processed
@@ -113,17 +355,176 @@ func (p *Processor) ProcessDocument(ctx context.Context, filePath string) error
 	return nil
 }
 
+// normalize applies the normalization rules to parsed text and records how
+// much it changed
+func (p *Processor) normalize(parsed *ParsedDocument, plog *processingLog) {
+	if !p.normalization.enabled() {
+		return
+	}
+	before := len(parsed.Text)
+	normalizeParsed(parsed, p.normalization)
+	plog.printf("normalized text: %d → %d characters", before, len(parsed.Text))
+}
+
+// ExtractText parses a document and returns its text after normalization,
+// exactly as it is split into chunks
+func (p *Processor) ExtractText(fileType, filePath string) (string, error) {
+	parsed, err := p.parse(fileType, filePath)
+	if err != nil {
+		return "", describeParseError(filePath, err)
+	}
+	if p.normalization.enabled() {
+		normalizeParsed(parsed, p.normalization)
+	}
+	return parsed.Text, nil
+}
+
+// logParsed records what parsing produced, including any page warnings
+func logParsed(plog *processingLog, parsed *ParsedDocument) {
+	plog.printf("parsed %d pages: %d characters of text, %d images, %d outline sections",
+		parsed.Pages, len(parsed.Text), len(parsed.Images), len(parsed.Sections))
+	if parsed.Title != "" {
+		plog.printf("title: %s", parsed.Title)
+	}
+	for _, warning := range parsed.Warnings {
+		plog.warnf("%s", warning)
+	}
+}
+
+// ReprocessDocument reprocesses a known document in place, ignoring the hash
+// check. Chunks whose content is unchanged keep their existing embeddings.
+// An unknown document is processed as new. The result is never nil.
+func (p *Processor) ReprocessDocument(ctx context.Context, filePath string) (*ProcessResult, error) {
+	result := &ProcessResult{Outcome: ProcessFailed}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	if err := p.reprocess(ctx, filePath, result); err != nil {
+		result.Outcome = ProcessFailed
+		return result, err
+	}
+	return result, nil
+}
+
+// reprocess does the work of ReprocessDocument, filling in result
+func (p *Processor) reprocess(ctx context.Context, filePath string, result *ProcessResult) error {
+	end, err := p.beginIngest()
+	if err != nil {
+		return err
+	}
+	defer end()
+	// Unchanged chunks keep their old vectors, so a reprocess would mix models
+	if err := p.checkModel(); err != nil {
+		return err
+	}
+	doc, err := p.db.GetDocumentByPath(ctx, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to check existing document: %w", err)
+	}
+	if doc == nil {
+		created, err := p.ProcessDocument(ctx, filePath)
+		*result = *created
+		return err
+	}
+
+	info, err := checkFile(filePath)
+	if err != nil {
+		p.db.UpdateDocumentError(ctx, doc.ID, err.Error())
+		return err
+	}
+
+	hash, err := ComputeFileHash(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to compute hash: %w", err)
+	}
+	p.recordFileStat(ctx, doc, info)
+	if err := p.updateDocument(ctx, doc, hash, result); err != nil {
+		return err
+	}
+	result.Outcome = ProcessUpdated
+	return nil
+}
+
+// updateDocument re-parses an existing document and updates its chunks and
+// images in place, recording counts and warnings in result
+func (p *Processor) updateDocument(ctx context.Context, doc *db.Document, hash string, result *ProcessResult) error {
+	plog := newProcessingLog(ctx, "updating document", doc.FilePath)
+	err := p.updateDocumentLogged(ctx, doc, hash, plog)
+	p.db.UpdateDocumentLog(ctx, doc.ID, plog.finish(err))
+	plog.fill(result)
+	if err == nil {
+		p.processed.Add(1)
+	}
+	return err
+}
+
+// updateDocumentLogged does the work of updateDocument, recording what
+// happened in plog
+func (p *Processor) updateDocumentLogged(ctx context.Context, doc *db.Document, hash string, plog *processingLog) error {
+	if err := p.db.UpdateDocumentHash(ctx, doc.ID, hash); err != nil {
+		return fmt.Errorf("failed to update document hash: %w", err)
+	}
+
+	parsed, err := p.parse(doc.FileType, doc.FilePath)
+	if err != nil {
+		err = describeParseError(doc.FilePath, err)
+		p.db.UpdateDocumentError(ctx, doc.ID, err.Error())
+		return err
+	}
+	p.db.UpdateDocumentTitle(ctx, doc.ID, parsed.Title)
+	logParsed(plog, parsed)
+	p.normalize(parsed, plog)
+
+	if err := p.updateTextChunks(ctx, doc, parsed, plog); err != nil {
+		errorMsg := fmt.Sprintf("failed to update text chunks: %v", err)
+		p.db.UpdateDocumentError(ctx, doc.ID, errorMsg)
+		return fmt.Errorf("failed to update text chunks: %w", err)
+	}
+	chunkSize, chunkOverlap := p.chunking()
+	p.db.UpdateDocumentChunking(ctx, doc.ID, chunkSize, chunkOverlap, p.textEmb.Model())
+	p.summarize(ctx, doc.ID, parsed.Title, doc.FilePath, plog)
+
+	// Page renders are regenerated on every parse, so images are replaced
+	if err := p.db.DeleteImagesByDocument(ctx, doc.ID); err != nil {
+		return fmt.Errorf("failed to delete old images: %w", err)
+	}
+	if err := p.processImages(ctx, doc.ID, parsed.Images, plog); err != nil {
+		// Image processing is optional, same as for new documents
+		plog.warnf("failed to store images: %v", err)
+	}
+
+	if err := p.db.UpdateDocumentProcessed(ctx, doc.ID); err != nil {
+		return fmt.Errorf("failed to update processed timestamp: %w", err)
+	}
+
+	return nil
+}
+
+// parse runs the parser for the given file type
+func (p *Processor) parse(fileType, filePath string) (*ParsedDocument, error) {
+	parser, ok := p.parsers[fileType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, fileType)
+	}
+	return parser.Parse(filePath)
+}
+
 // processTextChunks splits text into chunks and generates embeddings
-func (p *Processor) processTextChunks(ctx context.Context, docID uuid.UUID, text string) error {
-	chunks := p.splitText(text)
+func (p *Processor) processTextChunks(ctx context.Context, docID uuid.UUID, parsed *ParsedDocument, plog *processingLog) error {
+	chunkSize, chunkOverlap := p.chunking()
+	chunks := p.splitText(parsed.Text, chunkSize, chunkOverlap)
+	plog.printf("split text into %d chunks (size %d, overlap %d%%)", len(chunks), chunkSize, chunkOverlap)
 	if len(chunks) == 0 {
 		return nil
 	}
+	sections := sectionTitles(parsed.Text, parsed.Sections, chunks)
 
 	// Generate embeddings for all chunks
 	chunkData := make([]*db.Chunk, 0, len(chunks))
-	for i, chunkText := range chunks {
-		embedding, err := p.textEmb.Embed(ctx, chunkText)
+	for i, chunk := range chunks {
+		embedding, err := p.textEmb.Embed(ctx, chunk.text)
 		if err != nil {
 			return fmt.Errorf("failed to generate embedding for chunk %d: %w", i, err)
 		}
@@ -132,65 +533,229 @@ func (p *Processor) processTextChunks(ctx context.Context, docID uuid.UUID, text
 			ID:         uuid.New(),
 			DocumentID: docID,
 			ChunkIndex: i,
-			Content:    chunkText,
+			Content:    chunk.text,
+			Section:    sections[i],
 			Embedding:  embedding,
 		})
 	}
 
 	// Insert chunks in batch
-	return p.db.InsertChunksBatch(ctx, chunkData)
+	if err := p.db.InsertChunksBatch(ctx, chunkData); err != nil {
+		return err
+	}
+	plog.chunks = len(chunkData)
+	plog.printf("embedded and stored %d chunks", len(chunkData))
+	return nil
+}
+
+// updateTextChunks re-chunks text for an existing document, keeping chunks
+// whose content hash is unchanged and embedding only new or changed ones.
+// Kept chunks are re-embedded too when the document was embedded with
+// another model, or an unrecorded one, so that it can be recorded as
+// embedded with the current one.
+func (p *Processor) updateTextChunks(ctx context.Context, doc *db.Document, parsed *ParsedDocument, plog *processingLog) error {
+	docID := doc.ID
+	existing, err := p.db.GetChunksByDocument(ctx, docID)
+	if err != nil {
+		return err
+	}
+
+	// Index existing chunks by content hash; duplicates are kept in order
+	byHash := make(map[string][]*db.Chunk, len(existing))
+	for _, chunk := range existing {
+		h := contentHash(chunk.Content)
+		byHash[h] = append(byHash[h], chunk)
+	}
+
+	chunkSize, chunkOverlap := p.chunking()
+	chunks := p.splitText(parsed.Text, chunkSize, chunkOverlap)
+	sections := sectionTitles(parsed.Text, parsed.Sections, chunks)
+
+	var newChunks []*db.Chunk
+	reembedded := 0
+	for i, chunk := range chunks {
+		h := contentHash(chunk.text)
+		if kept := byHash[h]; len(kept) > 0 {
+			byHash[h] = kept[1:]
+			if kept[0].ChunkIndex != i || kept[0].Section != sections[i] {
+				if err := p.db.UpdateChunkPosition(ctx, kept[0].ID, i, sections[i]); err != nil {
+					return fmt.Errorf("failed to reindex chunk %d: %w", i, err)
+				}
+			}
+			if doc.EmbeddingModel != p.textEmb.Model() {
+				embedding, err := p.textEmb.Embed(ctx, chunk.text)
+				if err != nil {
+					return fmt.Errorf("failed to generate embedding for chunk %d: %w", i, err)
+				}
+				if err := p.db.UpdateChunkEmbedding(ctx, kept[0].ID, embedding); err != nil {
+					return fmt.Errorf("failed to store embedding of chunk %d: %w", i, err)
+				}
+				reembedded++
+			}
+			continue
+		}
+
+		embedding, err := p.textEmb.Embed(ctx, chunk.text)
+		if err != nil {
+			return fmt.Errorf("failed to generate embedding for chunk %d: %w", i, err)
+		}
+		newChunks = append(newChunks, &db.Chunk{
+			ID:         uuid.New(),
+			DocumentID: docID,
+			ChunkIndex: i,
+			Content:    chunk.text,
+			Section:    sections[i],
+			Embedding:  embedding,
+		})
+	}
+
+	// Whatever was not matched no longer appears in the document
+	var stale []uuid.UUID
+	for _, chunks := range byHash {
+		for _, chunk := range chunks {
+			stale = append(stale, chunk.ID)
+		}
+	}
+	if err := p.db.DeleteChunks(ctx, stale); err != nil {
+		return fmt.Errorf("failed to delete stale chunks: %w", err)
+	}
+
+	if len(newChunks) > 0 {
+		if err := p.db.InsertChunksBatch(ctx, newChunks); err != nil {
+			return err
+		}
+	}
+	plog.chunks = len(chunks)
+	plog.printf("split text into %d chunks (size %d, overlap %d%%): kept %d, embedded %d new, removed %d",
+		len(chunks), chunkSize, chunkOverlap, len(chunks)-len(newChunks), len(newChunks), len(stale))
+	if reembedded > 0 {
+		plog.printf("re-embedded %d kept chunks with %s, as they were embedded with %s",
+			reembedded, p.textEmb.Model(), describeModel(doc.EmbeddingModel))
+	}
+	return nil
+}
+
+// describeModel names an embedding model for a log, or says it is unknown
+func describeModel(model string) string {
+	if model == "" {
+		return "an unrecorded model"
+	}
+	return model
 }
 
 // processImages processes images with CLIP2 captioning and embeddings
-func (p *Processor) processImages(ctx context.Context, docID uuid.UUID, images []ImageData) error {
+func (p *Processor) processImages(ctx context.Context, docID uuid.UUID, images []ImageData, plog *processingLog) error {
 	if len(images) == 0 {
 		return nil
 	}
 
-	imageData := make([]*db.Image, 0, len(images))
-	for _, img := range images {
+	// Workers fill in their own slots, so images and warnings keep the
+	// document's order however the work interleaves
+	type imageResult struct {
+		image        *db.Image
+		err          error
+		thumbnailErr error
+	}
+	results := make([]imageResult, len(images))
+	p.forEachImage(ctx, len(images), func(i int) {
+		img := images[i]
+		var page *int
+		if img.Page > 0 {
+			page = &img.Page
+		}
+		results[i].image = &db.Image{
+			ID:             uuid.New(),
+			DocumentID:     docID,
+			ImageIndex:     img.Index,
+			FilePath:       img.FilePath,
+			SourcePage:     page,
+			SourceLocation: img.Location,
+			Status:         db.ImageDone,
+		}
+
 		// Generate caption and embedding
 		caption, embedding, err := p.imageEmb.ProcessImage(ctx, img.FilePath)
+		var captionEmbedding *pgvector.Vector
+		if err == nil {
+			captionEmbedding, err = p.embedCaption(ctx, caption)
+		}
 		if err != nil {
+			// Stored as failed, to be retried from Actions
+			results[i].err = err
+			results[i].image.Status = db.ImageFailed
+			results[i].image.Error = err.Error()
+			return
+		}
+		results[i].image.Caption = caption
+		results[i].image.Embedding = embedding
+		results[i].image.CaptionEmbedding = captionEmbedding
+
+		// A missing thumbnail only costs the preview, so keep the image
+		if p.thumbnailSize > 0 {
+			results[i].image.ThumbnailPath, results[i].thumbnailErr = writeThumbnail(img.FilePath, p.thumbnailSize)
+		}
+	})
+
+	imageData := make([]*db.Image, 0, len(images))
+	done, thumbnails := 0, 0
+	for i, result := range results {
+		img := images[i]
+		if result.image == nil {
+			continue // Not started before ctx was done
+		}
+		imageData = append(imageData, result.image)
+		if result.err != nil {
 			// Log error but continue with other images
-			// Silently skip failed images - continue processing others
+			plog.warnf("image %d (%s): %v", img.Index, filepath.Base(img.FilePath), result.err)
 			continue
 		}
-
-		imageData = append(imageData, &db.Image{
-			ID:         uuid.New(),
-			DocumentID: docID,
-			ImageIndex: img.Index,
-			FilePath:   img.FilePath,
-			Caption:    caption,
-			Embedding:  embedding,
-		})
+		done++
+		if result.thumbnailErr != nil {
+			plog.warnf("thumbnail for image %d (%s): %v", img.Index, filepath.Base(img.FilePath), result.thumbnailErr)
+		} else if result.image.ThumbnailPath != "" {
+			thumbnails++
+		}
 	}
 
+	plog.printf("captioned and embedded %d of %d images", done, len(images))
+	if p.thumbnailSize > 0 {
+		plog.printf("made %d thumbnails of up to %dpx", thumbnails, p.thumbnailSize)
+	}
 	if len(imageData) > 0 {
-		return p.db.InsertImagesBatch(ctx, imageData)
+		if err := p.db.InsertImagesBatch(ctx, imageData); err != nil {
+			return err
+		}
 	}
+	plog.images = done
 	return nil
 }
 
-// splitText splits text into chunks with overlap
-func (p *Processor) splitText(text string) []string {
+// textChunk is a chunk of document text and the index of its first word
+type textChunk struct {
+	text  string
+	start int
+}
+
+// splitText splits text into chunks of about chunkSize characters, each
+// starting with the last overlap percent of the previous chunk's words
+func (p *Processor) splitText(text string, chunkSize, overlap int) []textChunk {
 	words := strings.Fields(text)
 	if len(words) == 0 {
 		return nil
 	}
 
-	var chunks []string
+	var chunks []textChunk
 	currentChunk := []string{}
+	currentStart := 0
 	currentSize := 0
 
-	for _, word := range words {
+	for i, word := range words {
 		wordSize := len(word) + 1 // +1 for space
-		if currentSize+wordSize > p.chunkSize && len(currentChunk) > 0 {
-			chunks = append(chunks, strings.Join(currentChunk, " "))
+		if currentSize+wordSize > chunkSize && len(currentChunk) > 0 {
+			chunks = append(chunks, textChunk{text: strings.Join(currentChunk, " "), start: currentStart})
 			
 			// Keep overlap words for next chunk
-			overlapWords := len(currentChunk) * p.chunkOverlap / 100
+			overlapWords := len(currentChunk) * overlap / 100
 			if overlapWords > 0 && overlapWords < len(currentChunk) {
 				currentChunk = currentChunk[len(currentChunk)-overlapWords:]
 				currentSize = len(strings.Join(currentChunk, " "))
@@ -198,20 +763,140 @@ func (p *Processor) splitText(text string) []string {
 				currentChunk = []string{}
 				currentSize = 0
 			}
+			currentStart = i - len(currentChunk)
 		}
 		currentChunk = append(currentChunk, word)
 		currentSize += wordSize
 	}
 
 	if len(currentChunk) > 0 {
-		chunks = append(chunks, strings.Join(currentChunk, " "))
+		chunks = append(chunks, textChunk{text: strings.Join(currentChunk, " "), start: currentStart})
+	}
+
+	// Every chunk but the last is close to chunkSize; a near-empty tail adds
+	// little meaning, so fold it into the previous chunk. A document's only
+	// chunk is kept however short, so that no document ends up without text.
+	if n := len(chunks); n > 1 && len(chunks[n-1].text) < p.minChunkChars {
+		chunks[n-2].text = strings.Join(words[chunks[n-2].start:], " ")
+		chunks = chunks[:n-1]
 	}
 
 	return chunks
 }
 
-// computeFileHash computes SHA256 hash of a file
-func computeFileHash(filePath string) (string, error) {
+// sectionTitles returns the title of the section each chunk starts in, or ""
+// for chunks before the first section or when there is no outline
+func sectionTitles(text string, sections []Section, chunks []textChunk) []string {
+	titles := make([]string, len(chunks))
+	if len(sections) == 0 {
+		return titles
+	}
+
+	// Convert section byte offsets to word indexes; sections are in text
+	// order and start on page boundaries, so words can be counted piecewise
+	starts := make([]int, len(sections))
+	prevOffset, words := 0, 0
+	for i, section := range sections {
+		offset := min(max(section.Offset, prevOffset), len(text))
+		words += len(strings.Fields(text[prevOffset:offset]))
+		prevOffset = offset
+		starts[i] = words
+	}
+
+	current := -1
+	for i, chunk := range chunks {
+		for current+1 < len(sections) && starts[current+1] <= chunk.start {
+			current++
+		}
+		if current >= 0 {
+			titles[i] = sections[current].Title
+		}
+	}
+	return titles
+}
+
+// checkFile verifies a file exists and is non-empty before processing
+func checkFile(filePath string) (os.FileInfo, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("%w: %s is zero bytes", ErrEmptyFile, filepath.Base(filePath))
+	}
+	return info, nil
+}
+
+// recordEmptyFile stores the error for an empty document file, creating its
+// record if it has none, so the Documents view lists it as an empty file.
+// Once the file has content it is processed as a changed file.
+func (p *Processor) recordEmptyFile(ctx context.Context, filePath string, fileErr error) {
+	doc, err := p.db.GetDocumentByPath(ctx, filePath)
+	if err != nil {
+		log.Printf("warning: failed to record empty file %s: %v", filePath, err)
+		return
+	}
+	if doc == nil {
+		fileType, err := DetectType(filePath)
+		if err != nil {
+			return // Not a document; nothing to list
+		}
+		hash, err := ComputeFileHash(filePath)
+		if err != nil {
+			log.Printf("warning: failed to record empty file %s: %v", filePath, err)
+			return
+		}
+		if doc, err = p.db.CreateDocument(ctx, filePath, hash, fileType); err != nil {
+			log.Printf("warning: failed to record empty file %s: %v", filePath, err)
+			return
+		}
+	}
+	p.db.UpdateDocumentError(ctx, doc.ID, fileErr.Error())
+}
+
+// ResumeUnchanged reports whether filePath, which an interrupted import
+// finished with, still has the size and modification time recorded then,
+// so that the resumed import can skip it without rehashing. A file changed
+// since, or without a processed document, has to be processed again.
+func (p *Processor) ResumeUnchanged(ctx context.Context, filePath string) bool {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	doc, err := p.db.GetDocumentByPath(ctx, filePath)
+	if err != nil || doc == nil || doc.ProcessedAt == nil {
+		return false
+	}
+	return FileUnchanged(doc, info)
+}
+
+// FileUnchanged reports whether a file's size and modification time are
+// those recorded for doc when it was last hashed. It is false for documents
+// with none recorded.
+func FileUnchanged(doc *db.Document, info os.FileInfo) bool {
+	if doc.FileSize == nil || doc.FileModTime == nil {
+		return false
+	}
+	// Postgres keeps timestamps to the microsecond
+	return *doc.FileSize == info.Size() && doc.FileModTime.Equal(info.ModTime().Truncate(time.Microsecond))
+}
+
+// recordFileStat records the size and modification time of doc's file, as
+// stat'ed before hashing it. A failure only costs the next quick scan a hash.
+func (p *Processor) recordFileStat(ctx context.Context, doc *db.Document, info os.FileInfo) {
+	modTime := info.ModTime().Truncate(time.Microsecond)
+	if err := p.db.UpdateDocumentFileStat(ctx, doc.ID, info.Size(), modTime); err != nil {
+		log.Printf("warning: failed to record file size and time of %s: %v", doc.FilePath, err)
+	}
+}
+
+// contentHash computes SHA256 hash of chunk content
+func contentHash(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
+
+// ComputeFileHash computes SHA256 hash of a file
+func ComputeFileHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", err
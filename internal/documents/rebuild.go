@@ -0,0 +1,125 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dream-ai/cli/internal/db"
+)
+
+// RebuildEmbeddings re-embeds every stored chunk with the current text
+// model at dim dimensions, so that an index built with another model can be
+// searched again without reparsing documents. Document summaries are
+// re-embedded too, and caption embeddings in caption-only mode. The model is
+// probed before anything changes, and the new vectors are staged beside the
+// old ones and swapped in together at the end, so a rebuild that fails or is
+// cancelled leaves the index as it was. It does not start while documents
+// are being ingested, and ingestion is refused with ErrRebuildInProgress
+// until it finishes. progress is called before each document. It returns
+// how many chunks were embedded.
+func (p *Processor) RebuildEmbeddings(ctx context.Context, dim int, progress func(done, total int, doc *db.Document)) (embedded int, err error) {
+	if !p.ingest.TryLock() {
+		return 0, fmt.Errorf("documents are being processed; rebuild the embeddings when they finish")
+	}
+	defer p.ingest.Unlock()
+
+	probe, err := p.textEmb.Embed(ctx, "embedding dimension probe")
+	if err != nil {
+		return 0, fmt.Errorf("embedding model %s is not usable: %w", p.textEmb.Model(), err)
+	}
+	if got := len(probe.Slice()); got != dim {
+		return 0, fmt.Errorf("embedding model %s produces %d-dim embeddings but %d are configured", p.textEmb.Model(), got, dim)
+	}
+
+	docs, err := p.db.GetAllDocuments(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.db.BeginEmbeddingRebuild(ctx, dim, p.captionOnly); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			// A fresh context, so a cancelled rebuild is still cleaned up
+			p.db.AbortEmbeddingRebuild(context.Background())
+		}
+	}()
+
+	for i, doc := range docs {
+		if progress != nil {
+			progress(i, len(docs), doc)
+		}
+		chunks, err := p.db.GetChunksByDocument(ctx, doc.ID)
+		if err != nil {
+			return embedded, err
+		}
+		for _, chunk := range chunks {
+			embedding, err := p.textEmb.Embed(ctx, chunk.Content)
+			if err != nil {
+				return embedded, fmt.Errorf("failed to generate embedding for chunk %d of %s: %w", chunk.ChunkIndex, doc.FilePath, err)
+			}
+			if err := p.db.StageChunkEmbedding(ctx, chunk.ID, embedding); err != nil {
+				return embedded, fmt.Errorf("failed to store embedding: %w", err)
+			}
+			embedded++
+		}
+
+		if p.captionOnly {
+			if err := p.rebuildCaptions(ctx, doc); err != nil {
+				return embedded, err
+			}
+		}
+	}
+
+	// Summaries pick the documents to search by the same model's vectors
+	summaries, err := p.db.GetDocumentSummaries(ctx)
+	if err != nil {
+		return embedded, err
+	}
+	for docID, summary := range summaries {
+		embedding, err := p.textEmb.Embed(ctx, summary)
+		if err != nil {
+			return embedded, fmt.Errorf("failed to generate embedding for a document summary: %w", err)
+		}
+		if err := p.db.StageSummaryEmbedding(ctx, docID, embedding); err != nil {
+			return embedded, fmt.Errorf("failed to store summary embedding: %w", err)
+		}
+	}
+
+	if err := p.db.FinishEmbeddingRebuild(ctx, dim, p.captionOnly); err != nil {
+		return embedded, err
+	}
+	for _, doc := range docs {
+		p.db.UpdateDocumentChunking(ctx, doc.ID, doc.ChunkSize, doc.ChunkOverlap, p.textEmb.Model())
+	}
+
+	// The index now matches the text model, so ingestion may go ahead
+	p.indexModel.Store("")
+	return embedded, nil
+}
+
+// rebuildCaptions stages new embeddings of the captions of a document's
+// images
+func (p *Processor) rebuildCaptions(ctx context.Context, doc *db.Document) error {
+	images, err := p.db.GetImagesByDocument(ctx, doc.ID)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if img.Status != db.ImageDone {
+			continue // Never captioned; Retry Failed Images or Process Images Only will embed it
+		}
+		captionEmbedding, err := p.embedCaption(ctx, img.Caption)
+		if err != nil {
+			return fmt.Errorf("image %d of %s: %w", img.ImageIndex, doc.FilePath, err)
+		}
+		if captionEmbedding == nil {
+			continue
+		}
+		if err := p.db.StageCaptionEmbedding(ctx, img.ID, captionEmbedding); err != nil {
+			return fmt.Errorf("failed to store caption embedding: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sniffLen is how many leading bytes of a file DetectType reads
+const sniffLen = 64
+
+// FileType describes a document format a parser handles
+type FileType struct {
+	Name       string   // Stored as the document's file type, e.g. "pdf"
+	Extensions []string // Lower-case, with the dot, e.g. ".pdf"
+	// Sniff reports whether a file's leading bytes belong to the format, to
+	// tell which format a file really is; nil if it has no signature
+	Sniff func(header []byte) bool
+}
+
+// registeredType is a file type and the constructor of its parser
+type registeredType struct {
+	FileType
+	newParser func(imageDir string) Parser
+}
+
+// ParserRegistry maps file types to the parsers that read them. Scanning,
+// watching and processing all go through it, and the documents table takes
+// any type name, so a registered format is picked up everywhere.
+type ParserRegistry struct {
+	types []registeredType // In registration order, which is scan order
+}
+
+// NewParserRegistry creates an empty registry
+func NewParserRegistry() *ParserRegistry {
+	return &ParserRegistry{}
+}
+
+// Register adds a file type and the constructor of its parser, which is
+// given the directory extracted images are written to. Registering a name
+// again replaces it.
+func (r *ParserRegistry) Register(t FileType, newParser func(imageDir string) Parser) {
+	entry := registeredType{FileType: t, newParser: newParser}
+	for i := range r.types {
+		if r.types[i].Name == t.Name {
+			r.types[i] = entry
+			return
+		}
+	}
+	r.types = append(r.types, entry)
+}
+
+// Types returns the registered file type names in registration order
+func (r *ParserRegistry) Types() []string {
+	names := make([]string, len(r.types))
+	for i, t := range r.types {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Extensions returns the extensions of all registered file types
+func (r *ParserRegistry) Extensions() []string {
+	var exts []string
+	for _, t := range r.types {
+		exts = append(exts, t.Extensions...)
+	}
+	return exts
+}
+
+// NewParsers creates one parser for each registered file type, keyed by name
+func (r *ParserRegistry) NewParsers(imageDir string) map[string]Parser {
+	parsers := make(map[string]Parser, len(r.types))
+	for _, t := range r.types {
+		parsers[t.Name] = t.newParser(imageDir)
+	}
+	return parsers
+}
+
+// DetectType returns the name of the file type of filePath. Only a
+// registered extension makes a file a document, so partial downloads such as
+// book.pdf.part are never picked up. The file's leading bytes then decide:
+// the first type with the extension whose signature matches, or else any
+// registered type whose signature matches, so that a PDF saved as .epub is
+// read as a PDF. A file matching no signature, such as an empty or corrupt
+// one, keeps the type of its extension and is left to that parser to
+// report. ErrUnsupportedType is returned for any other extension.
+func (r *ParserRegistry) DetectType(filePath string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	var matches []registeredType
+	for _, t := range r.types {
+		if slices.Contains(t.Extensions, ext) {
+			matches = append(matches, t)
+		}
+	}
+	if len(matches) == 0 {
+		if ext == "" {
+			ext = filepath.Base(filePath)
+		}
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedType, ext)
+	}
+
+	if header := readHeader(filePath); len(header) > 0 {
+		for _, t := range append(matches, r.types...) {
+			if t.Sniff != nil && t.Sniff(header) {
+				return t.Name, nil
+			}
+		}
+	}
+	return matches[0].Name, nil
+}
+
+// Supports reports whether filePath has the extension of a registered file
+// type. It does not open the file.
+func (r *ParserRegistry) Supports(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	for _, t := range r.types {
+		if slices.Contains(t.Extensions, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// readHeader returns up to sniffLen leading bytes of the file, or nil if it
+// cannot be read
+func readHeader(filePath string) []byte {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil
+	}
+	return header[:n]
+}
+
+// Parsers is the registry of the formats dream-ai reads
+var Parsers = NewParserRegistry()
+
+func init() {
+	Parsers.Register(FileType{Name: "pdf", Extensions: []string{".pdf"}, Sniff: isPDF},
+		func(imageDir string) Parser { return NewPDFParser(imageDir) })
+	// Zip-based parser for EPUB 3.0 support
+	Parsers.Register(FileType{Name: "epub", Extensions: []string{".epub"}, Sniff: isEPUB},
+		func(imageDir string) Parser { return NewEPUBParserV2(imageDir) })
+}
+
+// DetectType returns the file type of filePath from the default registry
+func DetectType(filePath string) (string, error) {
+	return Parsers.DetectType(filePath)
+}
+
+// isSupportedFile reports whether filePath has the extension of a format a
+// registered parser reads
+func isSupportedFile(filePath string) bool {
+	return Parsers.Supports(filePath)
+}
+
+// isPDF reports whether header starts with the PDF signature
+func isPDF(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("%PDF-"))
+}
+
+// isEPUB reports whether header is a zip archive whose first entry is the
+// stored mimetype file an EPUB must start with
+func isEPUB(header []byte) bool {
+	const mimetypeOffset = 30 // Length of a zip local file header
+	return bytes.HasPrefix(header, []byte("PK\x03\x04")) &&
+		bytes.HasPrefix(header[min(mimetypeOffset, len(header)):], []byte("mimetypeapplication/epub+zip"))
+}
@@ -0,0 +1,94 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SetRetry configures how many times the batch paths attempt a document
+// before recording its error, and the backoff before the first retry. The
+// backoff doubles after each further attempt.
+func (p *Processor) SetRetry(attempts int, backoff time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	p.retryAttempts = attempts
+	p.retryBackoff = backoff
+}
+
+// ProcessDocumentWithRetry is ProcessDocument, retrying transient failures.
+// A document that succeeds on a retry is reported as ProcessUpdated, since
+// the retry updates the record the failed attempt left behind. The result's
+// duration covers every attempt.
+func (p *Processor) ProcessDocumentWithRetry(ctx context.Context, filePath string) (*ProcessResult, error) {
+	return p.retry(ctx, filePath, p.ProcessDocument)
+}
+
+// ReprocessDocumentWithRetry is ReprocessDocument, retrying transient failures
+func (p *Processor) ReprocessDocumentWithRetry(ctx context.Context, filePath string) (*ProcessResult, error) {
+	return p.retry(ctx, filePath, p.ReprocessDocument)
+}
+
+// retry makes a first attempt with process, then retries while the failure
+// is transient and returns the last result. A failed attempt may leave a
+// document record behind (with its hash), so retries go through
+// ReprocessDocument, which updates it in place.
+func (p *Processor) retry(ctx context.Context, filePath string, process func(context.Context, string) (*ProcessResult, error)) (*ProcessResult, error) {
+	start := time.Now()
+	result, err := process(ctx, filePath)
+	backoff := p.retryBackoff
+	for attempt := 1; attempt < p.retryAttempts && IsTransient(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			result.Duration = time.Since(start)
+			return result, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		result, err = p.ReprocessDocument(ctx, filePath)
+	}
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// IsTransient reports whether err looks like a passing infrastructure
+// failure (network, timeout, dropped connection) that is worth retrying.
+// Problems with the file itself, such as an unsupported or corrupt format,
+// are permanent.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch {
+	case errors.Is(err, ErrEmptyFile),
+		errors.Is(err, ErrCorruptFile),
+		errors.Is(err, ErrPasswordProtected),
+		errors.Is(err, ErrUnsupportedType),
+		errors.Is(err, ErrEmbeddingModelChanged),
+		errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection exceptions, 57P0x is server shutdown
+		return pgErr.Code[:2] == "08" || pgErr.Code == "57P01" || pgErr.Code == "57P03"
+	}
+	return pgconn.SafeToRetry(err) || pgconn.Timeout(err)
+}
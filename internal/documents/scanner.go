@@ -0,0 +1,124 @@
+package documents
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Exclusions are the files directory scans skip
+type Exclusions struct {
+	// Patterns are glob patterns matched against a file's name and full path
+	Patterns []string
+	// Dirs are directories whose contents are never ingested, such as the
+	// image output directory
+	Dirs []string
+}
+
+// Excludes reports whether filePath matches an exclude pattern or lies under
+// an excluded directory
+func (e Exclusions) Excludes(filePath string) bool {
+	filePath = filepath.Clean(filePath)
+	for _, pattern := range e.Patterns {
+		pattern = ExpandHome(pattern)
+		if ok, _ := filepath.Match(pattern, filepath.Base(filePath)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filePath); ok {
+			return true
+		}
+	}
+	for _, dir := range e.Dirs {
+		rel, err := filepath.Rel(filepath.Clean(ExpandHome(dir)), filePath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanDirectories returns the document files a registered parser reads
+// (PDF and EPUB) found directly inside the given directories, minus excluded
+// files. A configured path that is a supported file rather than a directory
+// is taken as is.
+// Directories that are missing, unreadable or hold no documents are skipped
+// and reported in warnings, as is any other file.
+func ScanDirectories(dirs []string, exclude Exclusions) (files []string, warnings []string) {
+	for _, dir := range dirs {
+		dir = ExpandHome(dir)
+
+		// Check if directory exists
+		info, err := os.Stat(dir)
+		if err != nil {
+			warnings = append(warnings, describeDirError(dir, err))
+			continue
+		}
+		if !info.IsDir() {
+			if isSupportedFile(dir) {
+				files = append(files, dir)
+			} else {
+				warnings = append(warnings, fmt.Sprintf("configured path %s is a file, not a directory", dir))
+			}
+			continue
+		}
+
+		// Glob hides read errors, so list the directory to report them
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			warnings = append(warnings, describeDirError(dir, err))
+			continue
+		}
+
+		// Grouped by file type in registration order (PDFs, then EPUBs),
+		// each in name order
+		byType := make(map[string][]string)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			filePath := filepath.Join(dir, entry.Name())
+			if fileType, err := DetectType(filePath); err == nil {
+				byType[fileType] = append(byType[fileType], filePath)
+			}
+		}
+		if len(byType) == 0 {
+			warnings = append(warnings, fmt.Sprintf("documents directory %s is empty (no %s files)", dir, strings.Join(Parsers.Extensions(), " or ")))
+			continue
+		}
+		for _, fileType := range Parsers.Types() {
+			files = append(files, byType[fileType]...)
+		}
+	}
+
+	kept := files[:0]
+	for _, file := range files {
+		if !exclude.Excludes(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept, warnings
+}
+
+// describeDirError says why a documents directory could not be scanned,
+// telling a missing directory from one without read permission
+func describeDirError(dir string, err error) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Sprintf("documents directory %s not found", dir)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Sprintf("documents directory %s is not readable: permission denied (check its permissions)", dir)
+	}
+	return fmt.Sprintf("cannot read documents directory %s: %v", dir, err)
+}
+
+// ExpandHome expands a leading ~ in path to the user's home directory
+func ExpandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		homeDir := os.Getenv("HOME")
+		return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+	}
+	return path
+}
@@ -0,0 +1,56 @@
+package documents
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/google/uuid"
+)
+
+// Summarizer writes a short summary of a whole document from its chunks,
+// given in document order
+type Summarizer interface {
+	Summarize(ctx context.Context, title string, chunks []*db.Chunk) (string, error)
+}
+
+// SetSummarizer makes processing also summarize each document and store
+// the summary's embedding for document-level retrieval. nil disables it.
+func (p *Processor) SetSummarizer(summarizer Summarizer) {
+	p.summarizer = summarizer
+}
+
+// summarize stores an embedded summary of a processed document. A summary
+// only helps retrieval, so failures are warnings in plog.
+func (p *Processor) summarize(ctx context.Context, docID uuid.UUID, title, filePath string, plog *processingLog) {
+	if p.summarizer == nil {
+		return
+	}
+	chunks, err := p.db.GetChunksByDocument(ctx, docID)
+	if err != nil {
+		plog.warnf("failed to summarize document: %v", err)
+		return
+	}
+	if len(chunks) == 0 {
+		return
+	}
+	if title == "" {
+		title = filepath.Base(filePath)
+	}
+
+	summary, err := p.summarizer.Summarize(ctx, title, chunks)
+	if err != nil {
+		plog.warnf("failed to summarize document: %v", err)
+		return
+	}
+	embedding, err := p.textEmb.Embed(ctx, summary)
+	if err != nil {
+		plog.warnf("failed to embed document summary: %v", err)
+		return
+	}
+	if err := p.db.UpsertDocumentSummary(ctx, docID, summary, p.textEmb.Model(), embedding); err != nil {
+		plog.warnf("%v", err)
+		return
+	}
+	plog.printf("stored a %d-character document summary", len(summary))
+}
@@ -0,0 +1,90 @@
+package documents
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// thumbnailSuffix replaces an image's extension to name its thumbnail
+const thumbnailSuffix = "_thumb.jpg"
+
+// thumbnailQuality is the JPEG quality of thumbnails; they are only previews
+const thumbnailQuality = 80
+
+// writeThumbnail writes a JPEG thumbnail of the image at imagePath next to
+// it, scaled so that its longer side is at most size pixels, and returns the
+// thumbnail's path
+func writeThumbnail(imagePath string, size int) (string, error) {
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open image: %w", err)
+	}
+	src, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	thumbPath := strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + thumbnailSuffix
+	out, err := os.Create(thumbPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+	defer out.Close()
+
+	if err := jpeg.Encode(out, scaleDown(src, size), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return thumbPath, nil
+}
+
+// scaleDown shrinks src to fit within size x size, keeping its aspect ratio,
+// by averaging the source pixels behind each thumbnail pixel. Images that
+// already fit are returned as is.
+func scaleDown(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+
+	tw, th := size, size
+	if w >= h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := bounds.Min.Y + y*h/th
+		y1 := bounds.Min.Y + (y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0 := bounds.Min.X + x*w/tw
+			x1 := bounds.Min.X + (x+1)*w/tw
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,62 @@
+package documents
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// trailingMarker matches words dropped from the end of a cleaned file name:
+// years, and edition or version markers such as 2nd, ed, edition, v2
+var trailingMarker = regexp.MustCompile(`^(?i:(1[5-9]|20)\d\d|\d+(st|nd|rd|th)|ed|edn|edition|rev|revised|v\d+)$`)
+
+// minorWords stay lowercase in a title unless they come first
+var minorWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "by": true,
+	"for": true, "in": true, "of": true, "on": true, "or": true, "the": true,
+	"to": true, "with": true,
+}
+
+// DisplayName returns the name to show for a document: its metadata title
+// when it has one, otherwise its file name, cleaned up when clean is set
+func DisplayName(title, filePath string, clean bool) string {
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	if clean {
+		if name := CleanFileName(filePath); name != "" {
+			return name
+		}
+	}
+	return filepath.Base(filePath)
+}
+
+// CleanFileName derives a readable title from a file name, e.g.
+// the_interpretation_of_dreams_2nd_ed.pdf becomes The Interpretation of Dreams.
+// Underscores, hyphens and dots become spaces, words are title-cased, and
+// trailing years and edition markers are dropped.
+func CleanFileName(filePath string) string {
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	})
+
+	for len(words) > 1 && trailingMarker.MatchString(strings.Trim(words[len(words)-1], "()[]")) {
+		words = words[:len(words)-1]
+	}
+
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		switch {
+		case i > 0 && minorWords[lower]:
+			words[i] = lower
+		case word == strings.ToLower(word):
+			// Leave mixed- and upper-case words (acronyms, names) as written
+			r, size := utf8.DecodeRuneInString(word)
+			words[i] = string(unicode.ToUpper(r)) + word[size:]
+		}
+	}
+	return strings.Join(words, " ")
+}
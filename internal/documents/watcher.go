@@ -0,0 +1,136 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long a file must go without writes before it is
+// considered fully copied and ready to process
+const watchDebounce = 2 * time.Second
+
+// Watcher watches document directories and processes newly added files
+type Watcher struct {
+	dirs        []string
+	process     func(ctx context.Context, filePath string) (*ProcessResult, error)
+	onProcessed func(filePath string, result *ProcessResult, err error)
+	exclude     Exclusions
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// NewWatcher creates a directory watcher. process is called for each new
+// supported file once writes to it have settled, and onProcessed is called
+// with the outcome.
+func NewWatcher(
+	dirs []string,
+	process func(ctx context.Context, filePath string) (*ProcessResult, error),
+	onProcessed func(filePath string, result *ProcessResult, err error),
+) *Watcher {
+	return &Watcher{
+		dirs:        dirs,
+		process:     process,
+		onProcessed: onProcessed,
+		timers:      make(map[string]*time.Timer),
+	}
+}
+
+// SetExclusions sets the files the watcher ignores
+func (w *Watcher) SetExclusions(exclude Exclusions) {
+	w.exclude = exclude
+}
+
+// Run watches the directories until ctx is cancelled. Directories that do
+// not exist are skipped; it is an error if none can be watched.
+func (w *Watcher) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	watched := 0
+	for _, dir := range w.dirs {
+		if err := fsw.Add(ExpandHome(dir)); err == nil {
+			watched++
+		}
+	}
+	if watched == 0 {
+		return fmt.Errorf("no documents directories could be watched")
+	}
+
+	// Files are processed one at a time, in the order they settle
+	ready := make(chan string, 16)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case filePath := <-ready:
+				result, err := w.process(ctx, filePath)
+				if w.onProcessed != nil {
+					w.onProcessed(filePath, result, err)
+				}
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.stopTimers()
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if !isSupportedFile(event.Name) || w.exclude.Excludes(event.Name) {
+				continue
+			}
+			w.debounce(ctx, event.Name, ready)
+		case _, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+		}
+	}
+}
+
+// debounce (re)starts the settle timer for a file; each write pushes it back
+func (w *Watcher) debounce(ctx context.Context, filePath string, ready chan<- string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if timer, ok := w.timers[filePath]; ok {
+		timer.Reset(watchDebounce)
+		return
+	}
+	w.timers[filePath] = time.AfterFunc(watchDebounce, func() {
+		w.mu.Lock()
+		delete(w.timers, filePath)
+		w.mu.Unlock()
+
+		select {
+		case ready <- filePath:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// stopTimers cancels all pending settle timers
+func (w *Watcher) stopTimers() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for filePath, timer := range w.timers {
+		timer.Stop()
+		delete(w.timers, filePath)
+	}
+}
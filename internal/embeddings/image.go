@@ -1,20 +1,34 @@
 package embeddings
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/pgvector/pgvector-go"
 )
 
+// ErrNoCaptioner is returned by ProcessImage in caption-only mode when the
+// CLIP2 script, which captions the images, is not found
+var ErrNoCaptioner = errors.New("caption-only mode needs the CLIP2 script to caption images")
+
 // ImageEmbedder processes images with CLIP2 for captioning and embeddings
 type ImageEmbedder struct {
 	pythonPath string
 	scriptPath string
+
+	// Captions are wanted in captionLanguage; ones the script returns in
+	// another language are passed through translate, if set
+	captionLanguage string
+	translate       func(ctx context.Context, caption, language string) (string, error)
+
+	captionOnly bool // Skip CLIP image vectors; ProcessImage returns a nil embedding
 }
 
 // NewImageEmbedder creates a new image embedder
@@ -27,7 +41,24 @@ func NewImageEmbedder(pythonPath string) *ImageEmbedder {
 	}
 }
 
-// ProcessImage generates a caption and embedding for an image using CLIP2
+// SetCaptionLanguage asks for captions in language (a name or code such as
+// "German" or "de"; empty keeps the captioner's own). The script is asked
+// for it, and a caption it returns in another language is translated with
+// translate, if given, or else kept as is.
+func (e *ImageEmbedder) SetCaptionLanguage(language string, translate func(ctx context.Context, caption, language string) (string, error)) {
+	e.captionLanguage = strings.TrimSpace(language)
+	e.translate = translate
+}
+
+// SetCaptionOnly makes ProcessImage caption images without computing their
+// CLIP embedding, which is then nil
+func (e *ImageEmbedder) SetCaptionOnly(captionOnly bool) {
+	e.captionOnly = captionOnly
+}
+
+// ProcessImage generates a caption and embedding for an image using CLIP2.
+// Without the script it falls back to ProcessImageSimple; if the script
+// fails, the error includes the end of what it printed to standard error.
 func (e *ImageEmbedder) ProcessImage(ctx context.Context, imagePath string) (string, *pgvector.Vector, error) {
 	// Try to use the Python script if available
 	scriptPath := e.scriptPath
@@ -40,16 +71,22 @@ func (e *ImageEmbedder) ProcessImage(ctx context.Context, imagePath string) (str
 		}
 	}
 
-	cmd := exec.CommandContext(ctx, e.pythonPath, scriptPath, imagePath)
-	output, err := cmd.Output()
+	args := []string{scriptPath}
+	if e.captionOnly {
+		args = append(args, "--caption-only")
+	}
+	if e.captionLanguage != "" {
+		args = append(args, "--language", e.captionLanguage)
+	}
+	output, err := e.runScript(ctx, append(args, imagePath)...)
 	if err != nil {
-		// Fallback to simple processing
-		return e.ProcessImageSimple(ctx, imagePath)
+		return "", nil, fmt.Errorf("failed to run CLIP2 script: %w", err)
 	}
 
 	// Parse output: JSON with caption and embedding
 	var result struct {
 		Caption   string    `json:"caption"`
+		Language  string    `json:"language,omitempty"` // Of the caption, if the script says
 		Embedding []float32 `json:"embedding"`
 		Error     string    `json:"error,omitempty"`
 	}
@@ -57,12 +94,84 @@ func (e *ImageEmbedder) ProcessImage(ctx context.Context, imagePath string) (str
 		return e.ProcessImageSimple(ctx, imagePath)
 	}
 
-	if result.Error != "" || len(result.Embedding) == 0 {
+	// A placeholder caption would only embed the file name, which is no use
+	// when the caption is all that is searched
+	if e.captionOnly && result.Error != "" {
+		return "", nil, fmt.Errorf("failed to caption image: %s", result.Error)
+	}
+	if result.Error != "" || (len(result.Embedding) == 0 && !e.captionOnly) {
 		return e.ProcessImageSimple(ctx, imagePath)
 	}
 
+	caption := result.Caption
+	if e.captionLanguage != "" && e.translate != nil && languageCode(result.Language) != languageCode(e.captionLanguage) {
+		// An untranslated caption is still better than none
+		if translated, err := e.translate(ctx, caption, e.captionLanguage); err == nil {
+			caption = translated
+		}
+	}
+
+	if e.captionOnly {
+		return caption, nil, nil
+	}
 	vec := pgvector.NewVector(result.Embedding)
-	return result.Caption, &vec, nil
+	return caption, &vec, nil
+}
+
+// languageCodes maps the names of common languages, in English and in the
+// language itself, to their ISO 639-1 codes
+var languageCodes = map[string]string{
+	"arabic": "ar", "chinese": "zh", "czech": "cs", "danish": "da",
+	"dutch": "nl", "nederlands": "nl", "english": "en", "finnish": "fi",
+	"french": "fr", "français": "fr", "german": "de", "deutsch": "de",
+	"greek": "el", "hebrew": "he", "hindi": "hi", "italian": "it",
+	"italiano": "it", "japanese": "ja", "korean": "ko", "norwegian": "no",
+	"polish": "pl", "polski": "pl", "portuguese": "pt", "português": "pt",
+	"russian": "ru", "spanish": "es", "español": "es", "swedish": "sv",
+	"svenska": "sv", "turkish": "tr", "türkçe": "tr", "ukrainian": "uk",
+}
+
+// languageCode returns the ISO 639-1 code for a language given by name or
+// code, such as "English", "en" or "en-US", so that ways of naming the same
+// language compare equal. Names it does not know are returned lowercased.
+func languageCode(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageCodes[language]; ok {
+		return code
+	}
+	// A region or script subtag does not change the language
+	if code, _, found := strings.Cut(strings.ReplaceAll(language, "_", "-"), "-"); found {
+		return code
+	}
+	return language
+}
+
+// stderrLines is how many of its last lines of standard error a failed
+// script run reports; the last line of a Python traceback is the exception
+const stderrLines = 3
+
+// runScript runs Python with args and returns its standard output. Standard
+// error, where torch and transformers print warnings, is captured rather
+// than inherited so that it cannot draw over the TUI: it is discarded on
+// success, and its last lines are included in the error on failure.
+func (e *ImageEmbedder) runScript(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.pythonPath, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var lines []string
+		for _, line := range strings.Split(stderr.String(), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", err, strings.Join(lines[max(len(lines)-stderrLines, 0):], "; "))
+	}
+	return stdout.Bytes(), nil
 }
 
 // getCLIP2Script returns the Python script for CLIP2 processing
@@ -107,6 +216,9 @@ print(json.dumps(result))
 func (e *ImageEmbedder) ProcessImageSimple(ctx context.Context, imagePath string) (string, *pgvector.Vector, error) {
 	// Fallback: use image file path as caption and generate a simple hash-based embedding
 	// This is a placeholder - in production, you'd want actual CLIP2
+	if e.captionOnly {
+		return "", nil, ErrNoCaptioner
+	}
 	caption := fmt.Sprintf("Image: %s", filepath.Base(imagePath))
 	
 	// Generate a simple embedding based on file hash (not ideal, but works as fallback)
@@ -120,6 +232,41 @@ func (e *ImageEmbedder) ProcessImageSimple(ctx context.Context, imagePath string
 	return caption, &vec, nil
 }
 
+// EmbedText embeds text with CLIP's text encoder, into the same space as
+// image embeddings, so that a query can search images. Unlike
+// ProcessImage, it has no placeholder fallback: it fails without CLIP2.
+func (e *ImageEmbedder) EmbedText(ctx context.Context, text string) (*pgvector.Vector, error) {
+	scriptPath := e.scriptPath
+	if scriptPath == "" {
+		scriptPath = "scripts/clip2_process.py"
+	}
+	if _, err := os.Stat(scriptPath); err != nil {
+		return nil, fmt.Errorf("CLIP2 script not found: %w", err)
+	}
+
+	output, err := e.runScript(ctx, scriptPath, "--text", text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run CLIP2 script: %w", err)
+	}
+
+	var result struct {
+		Embedding []float32 `json:"embedding"`
+		Error     string    `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse CLIP2 output: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("CLIP2: %s", result.Error)
+	}
+	if len(result.Embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding returned")
+	}
+
+	vec := pgvector.NewVector(result.Embedding)
+	return &vec, nil
+}
+
 // SetScriptPath sets the path to a custom Python script
 func (e *ImageEmbedder) SetScriptPath(path string) {
 	e.scriptPath = path
@@ -3,40 +3,147 @@ package embeddings
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/dream-ai/cli/internal/ollama"
 	"github.com/pgvector/pgvector-go"
 )
 
+// defaultEmbedTimeout bounds a single embedding request unless SetTimeout
+// changes it
+const defaultEmbedTimeout = 30 * time.Second
+
+// ErrModelNotFound is returned when Ollama does not have an embedding model
+var ErrModelNotFound = errors.New("embedding model not found")
+
 // TextEmbedder generates text embeddings using Ollama
 type TextEmbedder struct {
 	baseURL    string
-	model      string
+	configured []string // The fallbacks in configured order
+	candidates []string // Models to try, in order, until one is available
+	dimension  int      // Length a candidate's embeddings must have; 0 for any
 	httpClient *http.Client
+
+	mu    sync.Mutex
+	model string // The first available candidate; empty until the first embedding
 }
 
-// NewTextEmbedder creates a new text embedder
+// NewTextEmbedder creates a new text embedder. model may be a
+// comma-separated list of fallbacks: the first one Ollama has is used for
+// every embedding. baseURL is normalized as ollama.NormalizeBaseURL does.
 func NewTextEmbedder(baseURL, model string) *TextEmbedder {
-	if baseURL == "" {
-		baseURL = "http://localhost:11434"
+	if normalized, err := ollama.NormalizeBaseURL(baseURL); err == nil {
+		baseURL = normalized
+	}
+	var candidates []string
+	for _, name := range strings.Split(model, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			candidates = append(candidates, name)
+		}
 	}
-	if model == "" {
-		model = "nomic-embed-text" // Default embedding model
+	if len(candidates) == 0 {
+		candidates = []string{"nomic-embed-text"} // Default embedding model
 	}
 	return &TextEmbedder{
-		baseURL: baseURL,
-		model:   model,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second, // 30 second timeout for embedding requests
+		baseURL:    baseURL,
+		configured: candidates,
+		candidates: append([]string(nil), candidates...),
+		httpClient: newHTTPClient(defaultEmbedTimeout),
+	}
+}
+
+// newHTTPClient returns a client whose requests give up after timeout. The
+// transport also bounds connecting and waiting for response headers, and
+// probes idle connections, so a dead Ollama connection fails instead of
+// blocking a request until the overall timeout.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 15 * time.Second,
+			}).DialContext,
+			ResponseHeaderTimeout: timeout,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConnsPerHost:   4,
 		},
 	}
 }
 
-// Embed generates an embedding for the given text
+// SetTimeout sets how long one embedding request may take before it fails.
+// Values below 1 are ignored.
+func (e *TextEmbedder) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		e.httpClient = newHTTPClient(timeout)
+	}
+}
+
+// SetDimension makes the embedder pass over fallback models whose
+// embeddings do not have dim dimensions, so that an index is never filled
+// with vectors of mixed sizes. Values below 1 accept any model.
+func (e *TextEmbedder) SetDimension(dim int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dimension = max(dim, 0)
+}
+
+// PreferModel moves model to the front of the fallbacks, if it is one, so
+// that queries use the model an existing index was built with; "" restores
+// the configured order. Unless the model in use is already the first
+// fallback, the next embedding selects the model again, so switching to a
+// corpus built with another model takes effect at once.
+func (e *TextEmbedder) PreferModel(model string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.candidates = append(e.candidates[:0], e.configured...)
+	for i, name := range e.candidates {
+		if name == model {
+			copy(e.candidates[1:i+1], e.candidates[:i])
+			e.candidates[0] = model
+			break
+		}
+	}
+	if e.model != e.candidates[0] {
+		e.model = ""
+	}
+}
+
+// HasModel reports whether model is one of the configured fallbacks
+func (e *TextEmbedder) HasModel(model string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, name := range e.candidates {
+		if name == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Model returns the name of the embedding model in use, or of the first
+// fallback before any embedding has been made
+func (e *TextEmbedder) Model() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.model != "" {
+		return e.model
+	}
+	return e.candidates[0]
+}
+
+// Embed generates an embedding for the given text. The first call selects
+// the embedding model, trying each fallback that Ollama reports missing or
+// that makes embeddings of another dimension than SetDimension's.
 func (e *TextEmbedder) Embed(ctx context.Context, text string) (*pgvector.Vector, error) {
 	// Clean and prepare text
 	text = strings.TrimSpace(text)
@@ -44,10 +151,56 @@ func (e *TextEmbedder) Embed(ctx context.Context, text string) (*pgvector.Vector
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
+	// The lock is not held across requests, so a hung request cannot block
+	// other callers past their own context; concurrent first calls may each
+	// try the fallbacks
+	e.mu.Lock()
+	model := e.model
+	candidates := append([]string(nil), e.candidates...)
+	dimension := e.dimension
+	e.mu.Unlock()
+	if model != "" {
+		return e.embed(ctx, model, text)
+	}
+
+	var mismatched []string
+	for _, model := range candidates {
+		vec, err := e.embed(ctx, model, text)
+		if errors.Is(err, ErrModelNotFound) {
+			log.Printf("warning: embedding model %s is not installed in Ollama", model)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n := len(vec.Slice()); dimension > 0 && n != dimension {
+			log.Printf("warning: embedding model %s makes %d-dimension embeddings, not the configured %d", model, n, dimension)
+			mismatched = append(mismatched, model)
+			continue
+		}
+		e.mu.Lock()
+		if e.model == "" {
+			e.model = model
+			if len(candidates) > 1 {
+				log.Printf("using embedding model %s", model)
+			}
+		}
+		e.mu.Unlock()
+		return vec, nil
+	}
+	if len(mismatched) > 0 {
+		return nil, fmt.Errorf("no installed embedding model makes %d-dimension embeddings (%s do not); set embeddings.dimension to match the model and rebuild the embeddings",
+			dimension, strings.Join(mismatched, ", "))
+	}
+	return nil, fmt.Errorf("none of %s: %w", strings.Join(candidates, ", "), ErrModelNotFound)
+}
+
+// embed generates an embedding for text with the given model
+func (e *TextEmbedder) embed(ctx context.Context, model, text string) (*pgvector.Vector, error) {
 	// Prepare request
 	url := fmt.Sprintf("%s/api/embeddings", e.baseURL)
 	payload := map[string]interface{}{
-		"model": e.model,
+		"model": model,
 		"prompt": text,
 	}
 
@@ -71,6 +224,9 @@ func (e *TextEmbedder) Embed(ctx context.Context, text string) (*pgvector.Vector
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrModelNotFound, model)
+		}
 		return nil, fmt.Errorf("ollama API error: %d - %s", resp.StatusCode, string(body))
 	}
 
@@ -0,0 +1,47 @@
+package ollama
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is where Ollama listens unless configured otherwise
+const DefaultBaseURL = "http://localhost:11434"
+
+// NormalizeBaseURL turns a configured Ollama address into a base URL that
+// API paths can be appended to: "http://" is added when there is no scheme
+// and trailing slashes are dropped, so "localhost:11434/" becomes
+// "http://localhost:11434". An empty address is DefaultBaseURL. It is an
+// error if the result is not an http(s) URL with a host.
+func NormalizeBaseURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return DefaultBaseURL, nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	raw = strings.TrimRight(raw, "/")
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid Ollama base URL %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid Ollama base URL %q: scheme must be http or https", raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid Ollama base URL %q: no host", raw)
+	}
+	return raw, nil
+}
+
+// baseURLOrRaw normalizes raw, keeping it unchanged if it is invalid so the
+// request error names the configured value; NormalizeBaseURL reports why
+func baseURLOrRaw(raw string) string {
+	if normalized, err := NormalizeBaseURL(raw); err == nil {
+		return normalized
+	}
+	return raw
+}
@@ -1,32 +1,131 @@
 package ollama
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/dream-ai/cli/internal/trace"
 )
 
+// ErrTruncatedResponse is returned, along with the text generated so far,
+// when a response stream ends before Ollama marks it done
+var ErrTruncatedResponse = errors.New("response ended before generation finished")
+
+// ErrGenerationTimeout is returned by CheckTimeout when a request's
+// deadline, the configured chat.timeout, passed before it finished
+var ErrGenerationTimeout = errors.New("generation timed out")
+
+// ErrModelNotFound is returned by generation when Ollama no longer has the
+// requested model, for example because it was removed after being selected
+var ErrModelNotFound = errors.New("model not found")
+
+// defaultTimeout bounds generations whose context has no deadline, until
+// SetTimeout is called
+const defaultTimeout = 5 * time.Minute
+
+// metadataTimeout bounds requests for model lists and details, which
+// unlike generation should be quick
+const metadataTimeout = 30 * time.Second
+
+// CheckTimeout returns an ErrGenerationTimeout naming timeout if err is due
+// to ctx's deadline passing, and err unchanged otherwise
+func CheckTimeout(ctx context.Context, err error, timeout time.Duration) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s (raise chat.timeout for slow models)", ErrGenerationTimeout, timeout)
+	}
+	return err
+}
+
 // Client wraps Ollama API interactions
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+
+	mu             sync.Mutex
+	contextLengths map[string]int // Cached per model by ContextLength
+	usage          Usage
+
+	// timeout bounds each generation whose context has no deadline
+	timeout time.Duration
+
+	// generations holds a slot for each generation in flight; its capacity
+	// is the concurrency limit
+	generations chan struct{}
 }
 
-// NewClient creates a new Ollama client
+// NewClient creates a new Ollama client. baseURL is normalized with
+// NormalizeBaseURL; validate it with that first to report a bad address.
+// A generation is bounded by its context's deadline, or if it has none, as
+// for summaries and translations made during imports, by SetTimeout's.
 func NewClient(baseURL string) *Client {
-	if baseURL == "" {
-		baseURL = "http://localhost:11434"
-	}
 	return &Client{
-		baseURL: baseURL,
+		baseURL: baseURLOrRaw(baseURL),
 		httpClient: &http.Client{
-			Timeout: 5 * time.Minute, // 5 minute timeout for generation requests
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   10 * time.Second,
+					KeepAlive: 15 * time.Second,
+				}).DialContext,
+				IdleConnTimeout: 90 * time.Second,
+			},
 		},
+		contextLengths: make(map[string]int),
+		generations:    make(chan struct{}, 1),
+		timeout:        defaultTimeout,
+	}
+}
+
+// SetTimeout bounds generations whose context has no deadline, so that a
+// hung Ollama cannot stall an import or hold a generation slot for ever.
+// Must be called before the client is used.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		c.timeout = timeout
+	}
+}
+
+// withTimeout bounds ctx by the client's timeout unless it has a deadline
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// SetMaxConcurrentGenerations limits how many generation requests are sent
+// to Ollama at once; the rest wait for a slot. Ollama serves one at a time
+// on a single GPU, so more only queue there or time out. Values below 1
+// are treated as 1. Must be called before the client is used.
+func (c *Client) SetMaxConcurrentGenerations(n int) {
+	c.generations = make(chan struct{}, max(n, 1))
+}
+
+// acquireGeneration waits for a generation slot, returning a func that
+// releases it, or an error if ctx is done first
+func (c *Client) acquireGeneration(ctx context.Context) (func(), error) {
+	select {
+	case c.generations <- struct{}{}:
+		return func() { <-c.generations }, nil
+	default:
+		trace.Printf(ctx, "waiting for one of %d generation slots", cap(c.generations))
+	}
+	select {
+	case c.generations <- struct{}{}:
+		return func() { <-c.generations }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
@@ -34,6 +133,7 @@ func NewClient(baseURL string) *Client {
 type GenerateRequest struct {
 	Model    string            `json:"model"`
 	Prompt   string            `json:"prompt"`
+	System   string            `json:"system,omitempty"` // Overrides the model's own system prompt
 	Stream   bool              `json:"stream"`
 	Options  map[string]interface{} `json:"options,omitempty"`
 }
@@ -51,59 +151,99 @@ type GenerateResponse struct {
 	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
 	EvalCount          int    `json:"eval_count,omitempty"`
 	EvalDuration       int64  `json:"eval_duration,omitempty"`
+	Error              string `json:"error,omitempty"` // Set when generation fails mid-stream
 }
 
 // Generate generates text using Ollama
 func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (string, error) {
+	response, _, err := c.GenerateWithStats(ctx, req)
+	return response, err
+}
+
+// Translate asks model to translate text into language, returning only the
+// translation
+func (c *Client) Translate(ctx context.Context, model, text, language string) (string, error) {
+	response, err := c.Generate(ctx, &GenerateRequest{
+		Model:  model,
+		Prompt: fmt.Sprintf("Translate the following text into %s. Write only the translation.\n\n%s", language, text),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to translate: %w", err)
+	}
+	translated := strings.TrimSpace(response)
+	if translated == "" {
+		return "", fmt.Errorf("failed to translate: empty response")
+	}
+	return translated, nil
+}
+
+// GenerateWithStats generates text and also returns the final response
+// message, which carries the token counts and durations for the request
+func (c *Client) GenerateWithStats(ctx context.Context, req *GenerateRequest) (text string, final *GenerateResponse, err error) {
+	done := trace.Begin(ctx, "generation with "+req.Model)
+	defer func() { done(err) }()
+
+	release, err := c.acquireGeneration(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	defer release()
+	// Bounded from when the slot is held, not while waiting for it
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
 	url := fmt.Sprintf("%s/api/generate", c.baseURL)
 	
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute request: %w", err)
+		return "", nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama API error: %d - %s", resp.StatusCode, string(body))
+		return "", nil, generateError(resp.StatusCode, body, req.Model)
 	}
 
 	var result strings.Builder
-	decoder := json.NewDecoder(resp.Body)
-	
-	for {
-		var genResp GenerateResponse
-		if err := decoder.Decode(&genResp); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return "", fmt.Errorf("failed to decode response: %w", err)
-		}
-		
+	final, err = readStream(resp.Body, func(genResp *GenerateResponse) {
 		result.WriteString(genResp.Response)
-		
-		if genResp.Done {
-			break
-		}
+	})
+	if err != nil {
+		return result.String(), nil, err
 	}
+	c.recordUsage(final, time.Since(start))
 
-	return result.String(), nil
+	return result.String(), final, nil
 }
 
 // GenerateStream generates text with streaming support
-func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest, onChunk func(string)) error {
+func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest, onChunk func(string)) (err error) {
 	req.Stream = true
+	done := trace.Begin(ctx, "streamed generation with "+req.Model)
+	defer func() { done(err) }()
+
+	release, err := c.acquireGeneration(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
 	url := fmt.Sprintf("%s/api/generate", c.baseURL)
 	
 	jsonData, err := json.Marshal(req)
@@ -125,27 +265,58 @@ func (c *Client) GenerateStream(ctx context.Context, req *GenerateRequest, onChu
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("ollama API error: %d - %s", resp.StatusCode, string(body))
+		return generateError(resp.StatusCode, body, req.Model)
 	}
 
-	decoder := json.NewDecoder(resp.Body)
+	final, err := readStream(resp.Body, func(genResp *GenerateResponse) {
+		if genResp.Response != "" {
+			onChunk(genResp.Response)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	c.recordUsage(final, time.Since(start))
+	return nil
+}
+
+// generateError returns the error for a failed generate request, wrapping
+// ErrModelNotFound when Ollama reports that model is missing
+func generateError(status int, body []byte, model string) error {
+	if status == http.StatusNotFound && strings.Contains(string(body), "not found") {
+		return fmt.Errorf("%w: %s", ErrModelNotFound, model)
+	}
+	return fmt.Errorf("ollama API error: %d - %s", status, string(body))
+}
+
+// readStream reads newline-delimited generate responses, passing each to
+// onMessage, until one is marked done, and returns that final message.
+// Lines that are not valid JSON are skipped with a logged warning. If the
+// stream ends or breaks before the final message, the error wraps
+// ErrTruncatedResponse; everything read so far has been passed on already.
+func readStream(body io.Reader, onMessage func(*GenerateResponse)) (*GenerateResponse, error) {
+	reader := bufio.NewReader(body)
 	for {
-		var genResp GenerateResponse
-		if err := decoder.Decode(&genResp); err != nil {
-			if err == io.EOF {
-				break
+		line, readErr := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var genResp GenerateResponse
+			if err := json.Unmarshal(line, &genResp); err != nil {
+				log.Printf("warning: skipping unparseable Ollama stream line: %v", err)
+			} else if genResp.Error != "" {
+				return nil, fmt.Errorf("ollama error: %s", genResp.Error)
+			} else {
+				onMessage(&genResp)
+				if genResp.Done {
+					return &genResp, nil
+				}
 			}
-			return fmt.Errorf("failed to decode response: %w", err)
 		}
-		
-		if genResp.Response != "" {
-			onChunk(genResp.Response)
+
+		if readErr == io.EOF {
+			return nil, ErrTruncatedResponse
 		}
-		
-		if genResp.Done {
-			break
+		if readErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrTruncatedResponse, readErr)
 		}
 	}
-
-	return nil
 }
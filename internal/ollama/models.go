@@ -1,12 +1,14 @@
 package ollama
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -22,6 +24,12 @@ type ListModelsResponse struct {
 	Models []ModelInfo `json:"models"`
 }
 
+// ShowModelResponse represents the parts of /api/show used by the client
+type ShowModelResponse struct {
+	Parameters string                 `json:"parameters"`
+	ModelInfo  map[string]interface{} `json:"model_info"`
+}
+
 // ModelSelector handles model selection logic
 type ModelSelector struct {
 	client *Client
@@ -34,6 +42,8 @@ func NewModelSelector(client *Client) *ModelSelector {
 
 // ListModels lists all available Ollama models
 func (ms *ModelSelector) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
 	url := fmt.Sprintf("%s/api/tags", ms.client.baseURL)
 	
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -118,3 +128,104 @@ func (ms *ModelSelector) GetDefaultModel(ctx context.Context, defaultModel strin
 
 	return ms.SelectBestModel(ctx)
 }
+
+// ResolveModel returns the installed model called name, which may omit the
+// ":latest" tag, or an error naming the installed models if there is none
+func (ms *ModelSelector) ResolveModel(ctx context.Context, name string) (string, error) {
+	models, err := ms.ListModels(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(models))
+	for _, model := range models {
+		if model.Name == name || model.Name == name+":latest" {
+			return model.Name, nil
+		}
+		names = append(names, model.Name)
+	}
+	return "", fmt.Errorf("model %q is not installed (available: %s)", name, strings.Join(names, ", "))
+}
+
+// ShowModel fetches model details from /api/show
+func (c *Client) ShowModel(ctx context.Context, model string) (*ShowModelResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+	url := fmt.Sprintf("%s/api/show", c.baseURL)
+
+	jsonData, err := json.Marshal(map[string]string{"model": model})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama API error: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result ShowModelResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// ContextLength returns the model's context window in tokens: num_ctx from
+// its parameters if set, otherwise the architecture's context_length. Results
+// are cached per model. Returns 0 if neither is known.
+func (c *Client) ContextLength(ctx context.Context, model string) (int, error) {
+	c.mu.Lock()
+	if n, ok := c.contextLengths[model]; ok {
+		c.mu.Unlock()
+		return n, nil
+	}
+	c.mu.Unlock()
+
+	info, err := c.ShowModel(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+
+	n := parseNumCtx(info.Parameters)
+	if n == 0 {
+		for key, value := range info.ModelInfo {
+			if strings.HasSuffix(key, ".context_length") {
+				if f, ok := value.(float64); ok {
+					n = int(f)
+				}
+				break
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.contextLengths[model] = n
+	c.mu.Unlock()
+	return n, nil
+}
+
+// parseNumCtx extracts num_ctx from a Modelfile parameters block
+func parseNumCtx(parameters string) int {
+	for _, line := range strings.Split(parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			n, err := strconv.Atoi(fields[1])
+			if err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
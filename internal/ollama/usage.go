@@ -0,0 +1,48 @@
+package ollama
+
+import "time"
+
+// Usage aggregates the generation requests a client has made
+type Usage struct {
+	Requests        int           // Completed generation requests
+	PromptTokens    int           // Prompt tokens evaluated, as reported by Ollama
+	GeneratedTokens int           // Tokens generated, as reported by Ollama
+	ResponseTime    time.Duration // Total wall-clock time of the requests
+	GenerationTime  time.Duration // Total time Ollama spent generating tokens
+}
+
+// AverageResponseTime returns the mean wall-clock time per request
+func (u Usage) AverageResponseTime() time.Duration {
+	if u.Requests == 0 {
+		return 0
+	}
+	return u.ResponseTime / time.Duration(u.Requests)
+}
+
+// TokensPerSecond returns the generation throughput, or 0 if unknown
+func (u Usage) TokensPerSecond() float64 {
+	if u.GenerationTime <= 0 {
+		return 0
+	}
+	return float64(u.GeneratedTokens) / u.GenerationTime.Seconds()
+}
+
+// Usage returns the client's aggregated usage so far
+func (c *Client) Usage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// recordUsage adds a completed request to the usage totals
+func (c *Client) recordUsage(final *GenerateResponse, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage.Requests++
+	c.usage.ResponseTime += elapsed
+	if final != nil {
+		c.usage.PromptTokens += final.PromptEvalCount
+		c.usage.GeneratedTokens += final.EvalCount
+		c.usage.GenerationTime += time.Duration(final.EvalDuration)
+	}
+}
@@ -0,0 +1,37 @@
+package rag
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AnswerCacheKey returns the answer cache key for a query: a hash of the
+// normalized query, the model, the prompts builder lays the question out
+// with and the IDs of the retrieved chunks and images, so a cached answer is
+// only reused for the same context asked the same way. builder is the one
+// selected for the model with ForModel.
+func AnswerCacheKey(query, model string, builder *ContextBuilder, result *RetrievalResult) string {
+	ids := append(GetChunkIDs(result), GetImageIDs(result)...)
+	sort.Strings(ids)
+
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", normalized, model, builder.promptIdentity(), strings.Join(ids, ","))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// ChunkIDs returns the IDs of the retrieved chunks and their neighbors
+func ChunkIDs(result *RetrievalResult) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(result.Chunks)+len(result.Neighbors))
+	for _, chunk := range result.Chunks {
+		ids = append(ids, chunk.ID)
+	}
+	for _, chunk := range result.Neighbors {
+		ids = append(ids, chunk.ID)
+	}
+	return ids
+}
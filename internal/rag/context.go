@@ -2,12 +2,49 @@ package rag
 
 import (
 	"fmt"
+	"path"
 	"strings"
+	"sync"
+
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/google/uuid"
+)
+
+// Context orders control where the most relevant chunks are placed
+const (
+	ContextOrderRelevance   = "relevance"   // Most relevant first
+	ContextOrderReverse     = "reverse"     // Most relevant last, closest to the question
+	ContextOrderAlternating = "alternating" // Most relevant at both ends, least in the middle
+)
+
+// DefaultPersona opens the system prompt unless replaced with SetPersona
+const DefaultPersona = "You are an expert in dream interpretation and symbolic analysis.\n" +
+	"You have access to a knowledge base of symbols, dream meanings, and interpretations."
+
+// General knowledge markers wrap what an answer draws from the model's own
+// knowledge rather than the context, when SetMarkGeneralKnowledge is on
+const (
+	GeneralOpen  = "<general>"
+	GeneralClose = "</general>"
 )
 
 // ContextBuilder builds context for LLM from retrieval results
 type ContextBuilder struct {
-	maxTokens int
+	mu            *sync.Mutex // Guards maxTokens, which Settings changes while questions are answered
+	maxTokens     int
+	order         string
+	persona       string
+	includeImages bool
+	labelSources  bool
+	markGeneral   bool // Ask for general knowledge to be wrapped in GeneralOpen and GeneralClose
+	templates     []promptTemplate
+	template      string // Selected by ForModel; empty uses the built-in layout
+}
+
+// promptTemplate is a prompt layout for the models matching a name pattern
+type promptTemplate struct {
+	match    string
+	template string
 }
 
 // NewContextBuilder creates a new context builder
@@ -16,56 +53,248 @@ func NewContextBuilder(maxTokens int) *ContextBuilder {
 		maxTokens = 2000 // Default
 	}
 	return &ContextBuilder{
-		maxTokens: maxTokens,
+		mu:            new(sync.Mutex),
+		maxTokens:     maxTokens,
+		order:         ContextOrderRelevance,
+		persona:       DefaultPersona,
+		includeImages: true,
+		labelSources:  true,
+	}
+}
+
+// SetLabelSources sets whether each excerpt in the context names the
+// document it comes from, so the model can attribute and weigh sources
+func (cb *ContextBuilder) SetLabelSources(label bool) {
+	cb.labelSources = label
+}
+
+// SetMaxTokens sets the context's token budget; values below 1 are ignored
+func (cb *ContextBuilder) SetMaxTokens(maxTokens int) {
+	if maxTokens > 0 {
+		cb.mu.Lock()
+		cb.maxTokens = maxTokens
+		cb.mu.Unlock()
+	}
+}
+
+// tokenBudget returns the context's token budget
+func (cb *ContextBuilder) tokenBudget() int {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.maxTokens
+}
+
+// clone returns a copy of the builder with its own lock, for ForModel and
+// FitToWindow
+func (cb *ContextBuilder) clone() *ContextBuilder {
+	cb.mu.Lock()
+	c := *cb
+	cb.mu.Unlock()
+	c.mu = new(sync.Mutex)
+	return &c
+}
+
+// SetIncludeImages sets whether retrieved images are described in the
+// context. Their paths are never included; see ImageSources.
+func (cb *ContextBuilder) SetIncludeImages(include bool) {
+	cb.includeImages = include
+}
+
+// SetMarkGeneralKnowledge sets whether the system prompt asks the model to
+// wrap what it draws from general knowledge in GeneralOpen and GeneralClose,
+// instead of only to say so
+func (cb *ContextBuilder) SetMarkGeneralKnowledge(mark bool) {
+	cb.markGeneral = mark
+}
+
+// SetPersona sets who the model is told it is at the start of the system
+// prompt. An empty persona restores DefaultPersona.
+func (cb *ContextBuilder) SetPersona(persona string) {
+	persona = strings.TrimSpace(persona)
+	if persona == "" {
+		persona = DefaultPersona
+	}
+	cb.persona = persona
+}
+
+// AddPromptTemplate adds a prompt layout for models whose names match the
+// glob match, such as "llama3*". {context} and {question} in template are
+// replaced with the retrieved context and the user's question. Templates
+// added first take precedence.
+func (cb *ContextBuilder) AddPromptTemplate(match, template string) error {
+	if _, err := path.Match(match, ""); err != nil {
+		return fmt.Errorf("invalid prompt template pattern %q: %w", match, err)
+	}
+	cb.templates = append(cb.templates, promptTemplate{match: match, template: template})
+	return nil
+}
+
+// ForModel returns a copy of the builder whose BuildPrompt uses the first
+// prompt template matching model. A pattern without a tag also matches the
+// model's tagged names, so "mistral" matches "mistral:7b". Models matching
+// none get the built-in layout.
+func (cb *ContextBuilder) ForModel(model string) *ContextBuilder {
+	selected := cb.clone()
+	selected.template = ""
+	name, _, _ := strings.Cut(model, ":")
+	for _, t := range cb.templates {
+		if matched, _ := path.Match(t.match, model); matched {
+			selected.template = t.template
+			break
+		}
+		if matched, _ := path.Match(t.match, name); matched {
+			selected.template = t.template
+			break
+		}
+	}
+	return selected
+}
+
+// FitToWindow returns a copy of the builder whose token budget is capped to
+// fraction of a model's context window, leaving the rest for the prompt
+// framing and the answer. A zero window or fraction leaves the budget as is.
+func (cb *ContextBuilder) FitToWindow(numCtx int, fraction float64) *ContextBuilder {
+	fitted := cb.clone()
+	if numCtx > 0 && fraction > 0 {
+		if limit := int(float64(numCtx) * fraction); limit > 0 && limit < fitted.maxTokens {
+			fitted.maxTokens = limit
+		}
+	}
+	return fitted
+}
+
+// SetOrder sets how retrieved chunks are ordered in the context; "" is
+// relevance order
+func (cb *ContextBuilder) SetOrder(order string) error {
+	switch order {
+	case "", ContextOrderRelevance:
+		cb.order = ContextOrderRelevance
+	case ContextOrderReverse, ContextOrderAlternating:
+		cb.order = order
+	default:
+		return fmt.Errorf("unknown rag.context_order %q: use %q, %q or %q",
+			order, ContextOrderRelevance, ContextOrderReverse, ContextOrderAlternating)
 	}
+	return nil
 }
 
-// BuildContext creates a formatted context string from retrieval results
-func (cb *ContextBuilder) BuildContext(result *RetrievalResult) string {
+// BuildContext creates a formatted context string from retrieval results,
+// and reports whether it had to cut the context to the token budget. The
+// result is left as it is, chunks in their retrieved order.
+func (cb *ContextBuilder) BuildContext(result *RetrievalResult) (string, bool) {
 	var parts []string
 
 	// Add text chunks
 	if len(result.Chunks) > 0 {
 		parts = append(parts, "## Relevant Text Excerpts:")
-		for i, chunk := range result.Chunks {
-			parts = append(parts, fmt.Sprintf("\n### Excerpt %d:", i+1))
+		for i, chunk := range cb.orderChunks(result.Chunks) {
+			heading := fmt.Sprintf("Excerpt %d", i+1)
+			if name, ok := result.SourceNames[chunk.DocumentID]; ok && cb.labelSources {
+				heading += fmt.Sprintf(" from %q", name)
+			}
+			if chunk.Section != "" {
+				heading += fmt.Sprintf(" (section: %s)", chunk.Section)
+			}
+			parts = append(parts, "\n### "+heading+":")
 			parts = append(parts, chunk.Content)
 			parts = append(parts, "")
 		}
 	}
 
-	// Add image information
-	if len(result.Images) > 0 {
+	// Add images as numbered captions the model can refer to; paths mean
+	// nothing to it
+	if cb.includeImages && len(result.Images) > 0 {
 		parts = append(parts, "## Relevant Images:")
 		for i, img := range result.Images {
-			parts = append(parts, fmt.Sprintf("\n### Image %d:", i+1))
-			if img.Caption != "" {
-				parts = append(parts, fmt.Sprintf("Caption: %s", img.Caption))
+			caption := img.Caption
+			if caption == "" {
+				caption = "(no caption)"
+			}
+			if origin := ImageOrigin(img, result.SourceNames); cb.labelSources && origin != "" {
+				parts = append(parts, fmt.Sprintf("Image %d (%s): %s", i+1, origin, caption))
+			} else {
+				parts = append(parts, fmt.Sprintf("Image %d: %s", i+1, caption))
 			}
-			parts = append(parts, fmt.Sprintf("Source: %s", img.FilePath))
-			parts = append(parts, "")
 		}
+		parts = append(parts, "")
 	}
 
 	context := strings.Join(parts, "\n")
 	
 	// Truncate if too long (simple token estimation: ~4 chars per token)
-	maxChars := cb.maxTokens * 4
-	if len(context) > maxChars {
+	maxChars := cb.tokenBudget() * 4
+	truncated := len(context) > maxChars
+	if truncated {
 		context = context[:maxChars] + "\n\n[Context truncated...]"
 	}
 
-	return context
+	return context, truncated
 }
 
-// BuildPrompt creates a complete prompt with context and user query
-func (cb *ContextBuilder) BuildPrompt(context, userQuery string) string {
+// orderChunks arranges chunks (given most similar first) by the configured order
+func (cb *ContextBuilder) orderChunks(chunks []*db.Chunk) []*db.Chunk {
+	ordered := make([]*db.Chunk, 0, len(chunks))
+	switch cb.order {
+	case ContextOrderReverse:
+		for i := len(chunks) - 1; i >= 0; i-- {
+			ordered = append(ordered, chunks[i])
+		}
+	case ContextOrderAlternating:
+		// Ranks 1, 3, 5, ... from the front, then ..., 6, 4, 2 toward the end
+		for i := 0; i < len(chunks); i += 2 {
+			ordered = append(ordered, chunks[i])
+		}
+		start := len(chunks) - 1
+		if start%2 == 0 {
+			start--
+		}
+		for i := start; i > 0; i -= 2 {
+			ordered = append(ordered, chunks[i])
+		}
+	default:
+		ordered = append(ordered, chunks...)
+	}
+	return ordered
+}
+
+// BuildSystemPrompt creates the system prompt for answering questions: the
+// persona and how to use the context that BuildPrompt supplies
+func (cb *ContextBuilder) BuildSystemPrompt() string {
 	var parts []string
 
-	parts = append(parts, "You are an expert in dream interpretation and symbolic analysis.")
-	parts = append(parts, "You have access to a knowledge base of symbols, dream meanings, and interpretations.")
+	parts = append(parts, cb.persona)
 	parts = append(parts, "")
-	
+	parts = append(parts, "Please provide a thoughtful, detailed response based on the knowledge base context provided with each question.")
+	parts = append(parts, "If the context doesn't contain relevant information, you can draw from your general knowledge,")
+	if cb.markGeneral {
+		parts = append(parts, "but wrap everything that comes from general knowledge rather than the context in "+GeneralOpen+GeneralClose+" tags.")
+	} else {
+		parts = append(parts, "but please indicate when you're doing so.")
+	}
+	if cb.labelSources {
+		parts = append(parts, "Each excerpt names the source it comes from; attribute interpretations to their sources and note where they differ.")
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// promptIdentity returns what decides the prompts besides the question and
+// the retrieved context: the system prompt, the selected template and how
+// the context is laid out
+func (cb *ContextBuilder) promptIdentity() string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%t", cb.BuildSystemPrompt(), cb.template, cb.order, cb.includeImages)
+}
+
+// BuildPrompt creates the user prompt from the context and user query, laid
+// out by the template ForModel selected if any. The instructions are sent
+// separately, from BuildSystemPrompt.
+func (cb *ContextBuilder) BuildPrompt(context, userQuery string) string {
+	if cb.template != "" {
+		return strings.NewReplacer("{context}", context, "{question}", userQuery).Replace(cb.template)
+	}
+
+	var parts []string
+
 	if context != "" {
 		parts = append(parts, "## Knowledge Base Context:")
 		parts = append(parts, context)
@@ -74,20 +303,91 @@ func (cb *ContextBuilder) BuildPrompt(context, userQuery string) string {
 
 	parts = append(parts, "## User Question:")
 	parts = append(parts, userQuery)
-	parts = append(parts, "")
-	parts = append(parts, "Please provide a thoughtful, detailed response based on the context provided above.")
-	parts = append(parts, "If the context doesn't contain relevant information, you can draw from your general knowledge,")
-	parts = append(parts, "but please indicate when you're doing so.")
 
 	return strings.Join(parts, "\n")
 }
 
-// GetChunkIDs extracts chunk IDs from retrieval result
+// AnswerPart is a stretch of an answer, either resting on the context or
+// marked by the model as general knowledge
+type AnswerPart struct {
+	Text    string
+	General bool
+}
+
+// SplitGeneralKnowledge splits an answer at its general knowledge markers.
+// An unclosed marker (e.g. a truncated answer) makes the rest general, and
+// stray or nested markers are dropped. Empty parts are left out.
+func SplitGeneralKnowledge(answer string) []AnswerPart {
+	var parts []AnswerPart
+	stray := strings.NewReplacer(GeneralOpen, "", GeneralClose, "")
+	add := func(text string, general bool) {
+		text = stray.Replace(text)
+		if strings.TrimSpace(text) != "" {
+			parts = append(parts, AnswerPart{Text: text, General: general})
+		}
+	}
+	for {
+		start := strings.Index(answer, GeneralOpen)
+		if start < 0 {
+			add(answer, false)
+			return parts
+		}
+		add(answer[:start], false)
+		answer = answer[start+len(GeneralOpen):]
+		end := strings.Index(answer, GeneralClose)
+		if end < 0 {
+			add(answer, true)
+			return parts
+		}
+		add(answer[:end], true)
+		answer = answer[end+len(GeneralClose):]
+	}
+}
+
+// ImageSources returns where each retrieved image came from and its file,
+// numbered as in the context, for listing alongside an answer's sources
+func ImageSources(result *RetrievalResult) []string {
+	sources := make([]string, 0, len(result.Images))
+	for i, img := range result.Images {
+		if origin := ImageOrigin(img, result.SourceNames); origin != "" {
+			sources = append(sources, fmt.Sprintf("Image %d: %s (%s)", i+1, origin, img.FilePath))
+		} else {
+			sources = append(sources, fmt.Sprintf("Image %d: %s", i+1, img.FilePath))
+		}
+	}
+	return sources
+}
+
+// ImageOrigin describes where in its document an image came from, such as
+// "page 63 of The Dream Book", using names for document names. It is empty
+// for images stored before their source was recorded.
+func ImageOrigin(img *db.Image, names map[uuid.UUID]string) string {
+	name := names[img.DocumentID]
+	var origin string
+	switch {
+	case img.SourcePage != nil:
+		origin = fmt.Sprintf("page %d", *img.SourcePage)
+	case img.SourceLocation != "":
+		origin = img.SourceLocation
+	default:
+		return name
+	}
+	if name != "" {
+		origin += " of " + name
+	}
+	return origin
+}
+
+// GetChunkIDs extracts chunk IDs from retrieval result, including the
+// neighbors joined into its passages
 func GetChunkIDs(result *RetrievalResult) []string {
-	ids := make([]string, 0, len(result.Chunks))
+	ids := make([]string, 0, len(result.Chunks)+len(result.Neighbors))
 	for _, chunk := range result.Chunks {
 		ids = append(ids, chunk.ID.String())
 	}
+	for _, chunk := range result.Neighbors {
+		ids = append(ids, chunk.ID.String())
+	}
 	return ids
 }
 
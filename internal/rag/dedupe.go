@@ -0,0 +1,60 @@
+package rag
+
+import (
+	"math"
+	"strings"
+
+	"github.com/dream-ai/cli/internal/db"
+)
+
+// dedupeSimilarity is the cosine similarity at or above which two chunks'
+// embeddings count as the same passage
+const dedupeSimilarity = 0.98
+
+// dedupeChunks drops each chunk whose content, ignoring case and whitespace,
+// or embedding matches an earlier one's, keeping the order of the rest
+func dedupeChunks(chunks []*db.Chunk) []*db.Chunk {
+	seen := make(map[string]bool, len(chunks))
+	kept := make([]*db.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		key := strings.Join(strings.Fields(strings.ToLower(chunk.Content)), " ")
+		if seen[key] || nearDuplicate(chunk, kept) {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, chunk)
+	}
+	return kept
+}
+
+// nearDuplicate reports whether chunk's embedding is within
+// dedupeSimilarity of any kept chunk's
+func nearDuplicate(chunk *db.Chunk, kept []*db.Chunk) bool {
+	if chunk.Embedding == nil {
+		return false
+	}
+	for _, other := range kept {
+		if other.Embedding != nil && cosineSimilarity(chunk.Embedding.Slice(), other.Embedding.Slice()) >= dedupeSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 if
+// they differ in length or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,99 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/pgvector/pgvector-go"
+)
+
+// Ways of embedding a query longer than the maximum, selectable with
+// rag.long_query
+const (
+	LongQueryAverage   = "average"   // Embed it in pieces and average them
+	LongQuerySummarize = "summarize" // Embed a summary written by the model
+)
+
+// Condenser shortens a long query into a search query that keeps its
+// meaning
+type Condenser interface {
+	Condense(ctx context.Context, query string) (string, error)
+}
+
+// LLMCondenser asks an Ollama model to summarize long queries
+type LLMCondenser struct {
+	client *ollama.Client
+	model  func() string // The model to ask, read on each call
+}
+
+// NewLLMCondenser creates a condenser asking the model returned by model
+func NewLLMCondenser(client *ollama.Client, model func() string) *LLMCondenser {
+	return &LLMCondenser{client: client, model: model}
+}
+
+// Condense implements Condenser
+func (lc *LLMCondenser) Condense(ctx context.Context, query string) (string, error) {
+	model := lc.model()
+	if model == "" {
+		return "", fmt.Errorf("no model to summarize with")
+	}
+	response, err := lc.client.Generate(ctx, &ollama.GenerateRequest{
+		Model: model,
+		Prompt: "Summarize the following text in two or three sentences for use as a search query, " +
+			"keeping its key images, people, places and feelings. Write only the summary.\n\n" + query,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize query: %w", err)
+	}
+	summary := strings.TrimSpace(response)
+	if summary == "" {
+		return "", fmt.Errorf("failed to summarize query: empty response")
+	}
+	return summary, nil
+}
+
+// splitQuery splits text at word boundaries into pieces of at most maxChars
+// (a single longer word is a piece of its own)
+func splitQuery(text string, maxChars int) []string {
+	var pieces []string
+	var b strings.Builder
+	for _, word := range strings.Fields(text) {
+		if b.Len() > 0 && b.Len()+1+len(word) > maxChars {
+			pieces = append(pieces, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+	}
+	if b.Len() > 0 {
+		pieces = append(pieces, b.String())
+	}
+	return pieces
+}
+
+// averageEmbeddings returns the mean of the vectors, each weighted by the
+// length of the text it embeds. Cosine distance ignores the result's length,
+// so it is not normalized.
+func averageEmbeddings(vectors []*pgvector.Vector, weights []int) *pgvector.Vector {
+	var sum []float32
+	total := 0
+	for i, v := range vectors {
+		values := v.Slice()
+		if sum == nil {
+			sum = make([]float32, len(values))
+		}
+		for j := range values {
+			sum[j] += values[j] * float32(weights[i])
+		}
+		total += weights[i]
+	}
+	for j := range sum {
+		sum[j] /= float32(total)
+	}
+	vec := pgvector.NewVector(sum)
+	return &vec
+}
@@ -0,0 +1,68 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dream-ai/cli/internal/trace"
+)
+
+// Retrieval modes, selectable with rag.mode
+const (
+	ModeVector = "vector" // Vector similarity only
+	ModeHybrid = "hybrid" // Vector similarity filtered by the query's keywords
+	ModeAuto   = "auto"   // Hybrid for queries naming rare terms, else vector
+)
+
+// SetMode sets how Retrieve searches: ModeVector (or ""), ModeHybrid, or
+// ModeAuto, which uses hybrid search only when the query names rare terms
+// that embeddings tend to blur, such as proper nouns
+func (r *Retriever) SetMode(mode string) error {
+	switch mode {
+	case "", ModeVector:
+		r.mode = ModeVector
+	case ModeHybrid, ModeAuto:
+		r.mode = mode
+	default:
+		return fmt.Errorf("unknown rag.mode %q: use %q, %q or %q", mode, ModeVector, ModeHybrid, ModeAuto)
+	}
+	return nil
+}
+
+// useHybrid reports whether the retrieval mode calls for hybrid search of
+// query, tracing why in auto mode
+func (r *Retriever) useHybrid(ctx context.Context, query string) bool {
+	switch r.mode {
+	case ModeHybrid:
+		return true
+	case ModeAuto:
+		terms := rareTerms(query)
+		if len(terms) == 0 {
+			return false
+		}
+		trace.Printf(ctx, "hybrid search for rare terms: %s", strings.Join(terms, ", "))
+		return true
+	}
+	return false
+}
+
+// rareTerms returns the query's keywords that look rare: capitalized words
+// that do not start a sentence (names such as Artemidorus or Jung) and
+// words containing digits
+func rareTerms(query string) []string {
+	var terms []string
+	sentenceStart := true
+	for _, field := range strings.Fields(query) {
+		word := strings.Trim(field, `.,!?;:"'()`)
+		if len(extractKeywords(word)) > 0 {
+			first := []rune(word)[0]
+			if (unicode.IsUpper(first) && !sentenceStart) || strings.ContainsFunc(word, unicode.IsDigit) {
+				terms = append(terms, word)
+			}
+		}
+		sentenceStart = strings.ContainsAny(field[len(field)-1:], ".!?")
+	}
+	return terms
+}
@@ -0,0 +1,96 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/google/uuid"
+)
+
+// rrfK damps reciprocal rank fusion so that a chunk ranked first by one
+// search does not outweigh one ranked well by several
+const rrfK = 60
+
+// Paraphraser generates alternative phrasings of a query, so that retrieval
+// does not hinge on one embedding of it
+type Paraphraser interface {
+	Paraphrase(ctx context.Context, query string, n int) ([]string, error)
+}
+
+// LLMParaphraser asks an Ollama model for paraphrases
+type LLMParaphraser struct {
+	client *ollama.Client
+	model  func() string // The model to ask, read on each call
+}
+
+// NewLLMParaphraser creates a paraphraser asking the model returned by model
+func NewLLMParaphraser(client *ollama.Client, model func() string) *LLMParaphraser {
+	return &LLMParaphraser{client: client, model: model}
+}
+
+// Paraphrase implements Paraphraser
+func (lp *LLMParaphraser) Paraphrase(ctx context.Context, query string, n int) ([]string, error) {
+	model := lp.model()
+	if model == "" {
+		return nil, fmt.Errorf("no model to paraphrase with")
+	}
+	response, err := lp.client.Generate(ctx, &ollama.GenerateRequest{
+		Model: model,
+		Prompt: fmt.Sprintf("Rewrite the following question in %d different ways that keep its meaning but vary the wording, "+
+			"so that a search could find passages phrased differently. Write one question per line, with no numbering or other text.\n\n"+
+			"Question: %s", n, query),
+		Options: map[string]interface{}{"temperature": 0.7},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate paraphrases: %w", err)
+	}
+	return parseParaphrases(response, query, n), nil
+}
+
+// parseParaphrases returns up to n distinct lines of response, stripped of
+// list markers, that differ from query
+func parseParaphrases(response, query string, n int) []string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	var paraphrases []string
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•0123456789.)"))
+		key := strings.ToLower(line)
+		if line == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		paraphrases = append(paraphrases, line)
+		if len(paraphrases) == n {
+			break
+		}
+	}
+	return paraphrases
+}
+
+// fuseRankings merges ranked chunk lists by reciprocal rank fusion: each
+// chunk scores 1/(rrfK+rank) in every list it appears in, and the result is
+// ordered by total score. A chunk keeps its smallest distance to any query.
+func fuseRankings(rankings [][]*db.Chunk) []*db.Chunk {
+	scores := make(map[uuid.UUID]float64)
+	best := make(map[uuid.UUID]*db.Chunk)
+	var fused []*db.Chunk
+	for _, ranking := range rankings {
+		for rank, chunk := range ranking {
+			scores[chunk.ID] += 1 / float64(rrfK+rank+1)
+			if prev, ok := best[chunk.ID]; !ok {
+				best[chunk.ID] = chunk
+				fused = append(fused, chunk)
+			} else if chunk.Distance < prev.Distance {
+				prev.Distance = chunk.Distance
+			}
+		}
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return scores[fused[i].ID] > scores[fused[j].ID]
+	})
+	return fused
+}
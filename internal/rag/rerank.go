@@ -0,0 +1,67 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dream-ai/cli/internal/db"
+)
+
+// Reranker reorders retrieval candidates (given most similar first) by
+// relevance to the query. The retriever keeps the first topK it returns.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, chunks []*db.Chunk) []*db.Chunk
+}
+
+// Rerankers selectable with rag.reranker
+const (
+	RerankerNone    = ""
+	RerankerKeyword = "keyword"
+)
+
+// NewReranker returns the named reranker, or nil for none. An unknown name
+// is an error rather than no reranking, so a typo in rag.reranker is noticed.
+func NewReranker(name string) (Reranker, error) {
+	switch name {
+	case RerankerNone:
+		return nil, nil
+	case RerankerKeyword:
+		return KeywordReranker{Weight: 0.1}, nil
+	}
+	return nil, fmt.Errorf("unknown rag.reranker %q: use %q or leave it empty", name, RerankerKeyword)
+}
+
+// KeywordReranker boosts chunks that contain the query's keywords. Each
+// chunk's cosine distance is reduced by Weight times the share of keywords
+// it contains, so lexical matches can overtake slightly closer vectors.
+type KeywordReranker struct {
+	Weight float64
+}
+
+// Rerank implements Reranker
+func (kr KeywordReranker) Rerank(ctx context.Context, query string, chunks []*db.Chunk) []*db.Chunk {
+	keywords := extractKeywords(query)
+	if len(keywords) == 0 {
+		return chunks
+	}
+
+	scores := make(map[*db.Chunk]float64, len(chunks))
+	for _, chunk := range chunks {
+		content := strings.ToLower(chunk.Content)
+		matches := 0
+		for _, keyword := range keywords {
+			if strings.Contains(content, keyword) {
+				matches++
+			}
+		}
+		scores[chunk] = chunk.Distance - kr.Weight*float64(matches)/float64(len(keywords))
+	}
+
+	reranked := append([]*db.Chunk(nil), chunks...)
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return scores[reranked[i]] < scores[reranked[j]]
+	})
+	return reranked
+}
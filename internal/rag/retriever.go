@@ -2,18 +2,83 @@ package rag
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/dream-ai/cli/internal/db"
 	"github.com/dream-ai/cli/internal/embeddings"
+	"github.com/dream-ai/cli/internal/trace"
+	"github.com/google/uuid"
+	"github.com/pgvector/pgvector-go"
 )
 
+// queryCacheSize bounds how many queries' embeddings a Retriever keeps
+const queryCacheSize = 32
+
 // Retriever handles RAG retrieval using vector similarity search
 type Retriever struct {
-	db      *db.DB
-	textEmb *embeddings.TextEmbedder
-	topK    int
+	db       *db.DB
+	textEmb  *embeddings.TextEmbedder
+	imageEmb *embeddings.ImageEmbedder // Embeds queries for image search; nil skips it
+
+	// limitsMu guards topK, tokenBudget and maxResults, which Settings
+	// changes while questions are answered
+	limitsMu sync.Mutex
+	topK     int
+
+	// Find images by their captions' text embeddings rather than CLIP
+	captionSearch bool
+
+	// Leave out images farther than this cosine distance from the query;
+	// 0 keeps every image found
+	imageMaxDistance float64
+
+	// Embeddings of recent queries, so a query retrieved again (by hybrid
+	// search or a reranker, say) is not re-embedded
+	mu         sync.Mutex
+	queryCache map[string]*QueryEmbeddings
+
+	// Similarity search fetches topK * candidateMultiplier chunks, which the
+	// reranker (if any) narrows back to topK
+	candidateMultiplier int
+	reranker            Reranker
+
+	// Chunks on either side of each retrieved chunk included with it
+	neighborWindow int
+
+	// Instead of topK, return as many chunks (up to maxResults) as fit an
+	// estimated tokenBudget; 0 returns topK
+	tokenBudget int
+	maxResults  int
+
+	// Drop near-duplicate chunks from search results, refilling topK
+	dedupe bool
+
+	// Also search queryVariants paraphrases of each query from the
+	// paraphraser, fusing the results
+	paraphraser   Paraphraser
+	queryVariants int
+
+	// Retrieved chunks are traced as previews of at most previewChars
+	previewChars int
+
+	// Queries longer than maxQueryChars are embedded as longQuery says,
+	// summarizing with condenser; zero embeds every query whole
+	maxQueryChars int
+	longQuery     string
+	condenser     Condenser
+
+	// Search chunks only in the summaryDocs documents whose summaries best
+	// match the query (and in documents without a summary); 0 searches all
+	summaryDocs int
+
+	// How Retrieve searches: ModeVector, ModeHybrid or ModeAuto
+	mode string
 }
 
 // NewRetriever creates a new RAG retriever
@@ -22,51 +87,592 @@ func NewRetriever(db *db.DB, textEmb *embeddings.TextEmbedder, topK int) *Retrie
 		topK = 5 // Default
 	}
 	return &Retriever{
-		db:      db,
-		textEmb: textEmb,
-		topK:    topK,
+		db:                  db,
+		textEmb:             textEmb,
+		topK:                topK,
+		candidateMultiplier: 1,
+		previewChars:        200,
+		queryCache:          make(map[string]*QueryEmbeddings),
+		mode:                ModeVector,
+	}
+}
+
+// SetTopK sets how many chunks Retrieve returns; values below 1 are ignored
+func (r *Retriever) SetTopK(topK int) {
+	if topK > 0 {
+		r.limitsMu.Lock()
+		r.topK = topK
+		r.limitsMu.Unlock()
+	}
+}
+
+// SetTokenBudget makes Retrieve return as many chunks as fit tokens, by
+// the same estimate of four characters a token the context is cut to, but
+// at least one and at most maxResults, in place of topK. Small chunks then
+// fill the context and large ones stop short of overflowing it. A budget
+// of 0 returns topK chunks again.
+func (r *Retriever) SetTokenBudget(tokens, maxResults int) {
+	r.limitsMu.Lock()
+	defer r.limitsMu.Unlock()
+	r.tokenBudget = max(tokens, 0)
+	r.maxResults = max(maxResults, 1)
+}
+
+// limits returns how many chunks Retrieve fetches at most, and the token
+// budget they are cut to (0 for none)
+func (r *Retriever) limits() (topK, budget int) {
+	r.limitsMu.Lock()
+	defer r.limitsMu.Unlock()
+	if r.tokenBudget > 0 {
+		return r.maxResults, r.tokenBudget
 	}
+	return r.topK, 0
+}
+
+// imageLimit returns how many images Retrieve fetches when chunks are cut
+// to a token budget, which images are not part of
+func (r *Retriever) imageLimit() int {
+	r.limitsMu.Lock()
+	defer r.limitsMu.Unlock()
+	return r.topK
+}
+
+// SetPreviewChars sets the length chunks are cut to when traced; zero
+// traces them whole
+func (r *Retriever) SetPreviewChars(n int) {
+	r.previewChars = max(n, 0)
+}
+
+// SetImageEmbedder enables image search, embedding each query with CLIP's
+// text encoder to match the image embeddings
+func (r *Retriever) SetImageEmbedder(imageEmb *embeddings.ImageEmbedder) {
+	r.imageEmb = imageEmb
+}
+
+// SetCaptionSearch enables finding images by their captions, embedded with
+// the text model (see clip2.caption_only), using the query's text embedding
+func (r *Retriever) SetCaptionSearch(enabled bool) {
+	r.captionSearch = enabled
+}
+
+// SetImageMaxDistance sets the cosine distance from the query beyond which
+// found images are left out of results; 0 or less keeps them all
+func (r *Retriever) SetImageMaxDistance(d float64) {
+	r.imageMaxDistance = max(d, 0)
+}
+
+// QueryEmbeddings holds a query embedded for each kind of search
+type QueryEmbeddings struct {
+	Text  *pgvector.Vector // From the text model, for chunk search
+	Image *pgvector.Vector // From CLIP, for image search; nil if unavailable
+}
+
+// EmbedQuery returns the embeddings of query for chunk and image search,
+// computing them once per query and reusing them after. A failure to embed
+// for image search is not an error; Image is left nil.
+func (r *Retriever) EmbedQuery(ctx context.Context, query string) (*QueryEmbeddings, error) {
+	r.mu.Lock()
+	cached, ok := r.queryCache[query]
+	r.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	text, searchText, err := r.embedText(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+	embedded := &QueryEmbeddings{Text: text}
+	if r.imageEmb != nil {
+		if embedded.Image, err = r.imageEmb.EmbedText(ctx, searchText); err != nil {
+			trace.Printf(ctx, "image search skipped: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	if len(r.queryCache) >= queryCacheSize {
+		clear(r.queryCache)
+	}
+	r.queryCache[query] = embedded
+	r.mu.Unlock()
+	return embedded, nil
+}
+
+// ClearQueryCache forgets the embeddings of recent queries, for when the
+// text model that embeds them changes
+func (r *Retriever) ClearQueryCache() {
+	r.mu.Lock()
+	clear(r.queryCache)
+	r.mu.Unlock()
+}
+
+// SetLongQuery sets how queries longer than maxChars, such as a pasted
+// dream transcript, are embedded: LongQueryAverage embeds them in pieces of
+// up to maxChars and averages the vectors; LongQuerySummarize embeds a
+// summary from condenser instead, averaging if that fails. The whole query
+// is still what the model answers. maxChars below 1 embeds queries whole.
+func (r *Retriever) SetLongQuery(mode string, maxChars int, condenser Condenser) {
+	r.longQuery = mode
+	r.maxQueryChars = max(maxChars, 0)
+	r.condenser = condenser
+}
+
+// embedText embeds query with the text model, shortening it first if it is
+// too long, and returns the text that stands for it in searches
+func (r *Retriever) embedText(ctx context.Context, query string) (*pgvector.Vector, string, error) {
+	if r.maxQueryChars == 0 || len(query) <= r.maxQueryChars {
+		vec, err := r.textEmb.Embed(ctx, query)
+		return vec, query, err
+	}
+
+	if r.longQuery == LongQuerySummarize && r.condenser != nil {
+		summary, err := r.condenser.Condense(ctx, query)
+		if err == nil {
+			trace.Printf(ctx, "long query summarized: %s", summary)
+			vec, err := r.textEmb.Embed(ctx, summary)
+			return vec, summary, err
+		}
+		trace.Printf(ctx, "long query averaged instead: %v", err)
+	}
+
+	pieces := splitQuery(query, r.maxQueryChars)
+	vectors := make([]*pgvector.Vector, 0, len(pieces))
+	weights := make([]int, 0, len(pieces))
+	for _, piece := range pieces {
+		vec, err := r.textEmb.Embed(ctx, piece)
+		if err != nil {
+			return nil, "", err
+		}
+		vectors = append(vectors, vec)
+		weights = append(weights, len(piece))
+	}
+	trace.Printf(ctx, "long query embedded in %d pieces", len(pieces))
+	return averageEmbeddings(vectors, weights), pieces[0], nil
+}
+
+// SetReranker sets the reranker applied to search candidates, and how many
+// times topK candidates to fetch for it. A larger multiplier lets the
+// reranker rescue relevant chunks that fall just outside topK by vector
+// distance, at the cost of fetching and scoring more rows.
+func (r *Retriever) SetReranker(reranker Reranker, candidateMultiplier int) {
+	r.reranker = reranker
+	r.candidateMultiplier = max(candidateMultiplier, 1)
+}
+
+// SetNeighborWindow sets how many chunks before and after each retrieved
+// chunk in its document are included with it, giving the model a coherent
+// passage rather than an isolated fragment. Zero includes none.
+func (r *Retriever) SetNeighborWindow(n int) {
+	r.neighborWindow = max(n, 0)
+}
+
+// SetDedupe keeps only the first of near-identical chunks (same text, or
+// nearly the same embedding) among the results, refilling topK with the
+// next distinct ones, so repeated boilerplate does not crowd out the context
+func (r *Retriever) SetDedupe(enabled bool) {
+	r.dedupe = enabled
+}
+
+// SetDocumentSummaries makes retrieval two-level: the docs documents whose
+// stored summaries are most similar to the query are found first, and only
+// their chunks (and those of documents without a summary) are searched.
+// 0 searches every chunk.
+func (r *Retriever) SetDocumentSummaries(docs int) {
+	r.summaryDocs = max(docs, 0)
+}
+
+// SetMultiQuery also searches n paraphrases of each query, generated by
+// paraphraser, and fuses the results by reciprocal rank, which finds
+// passages a single phrasing of the question misses. A nil paraphraser or
+// n below 1 searches the query alone.
+func (r *Retriever) SetMultiQuery(paraphraser Paraphraser, n int) {
+	r.paraphraser = paraphraser
+	r.queryVariants = max(n, 0)
 }
 
 // RetrievalResult contains retrieved chunks and images
 type RetrievalResult struct {
 	Chunks []*db.Chunk
 	Images []*db.Image
+	// Neighbors are the chunks joined into Chunks as surrounding context
+	// when a neighbor window is set
+	Neighbors []*db.Chunk
+	// SourceNames is the title or file name of each chunk's document
+	SourceNames map[uuid.UUID]string
+	// ImageSearchErr is why images were left out, when image search failed
+	// without failing the retrieval: the stored image embeddings do not
+	// match the query's dimension (db.ErrDimensionMismatch)
+	ImageSearchErr error
+}
+
+// NoSourcesNote marks an answer given although retrieval found nothing
+const NoSourcesNote = "No relevant sources found — answering from general knowledge"
+
+// NoSourcesAnswer is given instead of an answer when retrieval found nothing
+// and rag.no_results is "refuse"
+const NoSourcesAnswer = "No relevant sources were found in your documents for this question."
+
+// Empty reports whether retrieval found neither chunks nor images, leaving
+// an answer nothing in the corpus to rest on
+func (r *RetrievalResult) Empty() bool {
+	return len(r.Chunks) == 0 && len(r.Images) == 0
 }
 
-// Retrieve finds relevant chunks and images for a query
+// Retrieve finds relevant chunks and images for a query, searching as the
+// retrieval mode says
 func (r *Retriever) Retrieve(ctx context.Context, query string) (*RetrievalResult, error) {
-	// Generate query embedding (for text chunks - 768 dimensions)
-	queryEmbedding, err := r.textEmb.Embed(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	topK, budget := r.limits()
+	return r.retrieve(ctx, query, topK, budget)
+}
+
+// RetrieveN is Retrieve with an explicit number of results, whatever the
+// token budget
+func (r *Retriever) RetrieveN(ctx context.Context, query string, topK int) (*RetrievalResult, error) {
+	return r.retrieve(ctx, query, topK, 0)
+}
+
+// retrieve does the work of Retrieve and RetrieveN
+func (r *Retriever) retrieve(ctx context.Context, query string, topK, budget int) (result *RetrievalResult, err error) {
+	done := trace.Begin(ctx, "retrieval")
+	defer func() { done(err) }()
+
+	if r.useHybrid(ctx, query) {
+		return r.retrieveHybrid(ctx, query, topK, budget)
 	}
+	return r.retrieveVector(ctx, query, topK, budget)
+}
 
-	// Search for similar chunks
-	chunks, err := r.db.SearchSimilarChunks(ctx, queryEmbedding, r.topK)
+// retrieveVector finds the topK chunks and images most similar to the query,
+// keeping only as many chunks as fit budget tokens if it is not 0
+func (r *Retriever) retrieveVector(ctx context.Context, query string, topK, budget int) (*RetrievalResult, error) {
+	embedded, err := r.EmbedQuery(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search chunks: %w", err)
+		return nil, err
 	}
 
-	// Search for similar images - skip if dimension mismatch (images use 512-dim embeddings)
-	// We can't use text embeddings (768-dim) to search images (512-dim)
-	images, err := r.db.SearchSimilarImages(ctx, queryEmbedding, r.topK)
+	// Search for similar chunks, over-fetching candidates for the reranker,
+	// and twice as many again to refill duplicates dropped by dedupe
+	limit := topK * r.candidateMultiplier
+	if r.dedupe {
+		limit *= 2
+	}
+	chunks, err := r.searchChunks(ctx, query, embedded, limit)
 	if err != nil {
-		// Dimension mismatch is expected - images use different embedding model
-		// Just return empty images list instead of failing
-		images = []*db.Image{}
+		return nil, err
+	}
+	if r.dedupe {
+		chunks = dedupeChunks(chunks)
+	}
+	if r.reranker != nil {
+		chunks = r.reranker.Rerank(ctx, query, chunks)
+	}
+	if len(chunks) > topK {
+		chunks = chunks[:topK]
+	}
+	imageK := topK
+	if budget > 0 {
+		chunks = r.withinBudget(ctx, chunks, budget)
+		imageK = r.imageLimit()
+	}
+	var neighbors []*db.Chunk
+	if r.neighborWindow > 0 {
+		chunks, neighbors, err = r.withNeighbors(ctx, chunks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Images are searched with the query's CLIP embedding, when there is
+	// one, or by caption with its text embedding. Images that no longer
+	// match are only left out, so text questions are still answered.
+	images := []*db.Image{}
+	var imageErr error
+	if embedded.Image != nil || r.captionSearch {
+		var found []*db.Image
+		if embedded.Image != nil {
+			found, err = r.db.SearchSimilarImages(ctx, embedded.Image, imageK)
+		} else {
+			found, err = r.db.SearchImagesByCaption(ctx, embedded.Text, imageK)
+		}
+		switch {
+		case errors.Is(err, db.ErrDimensionMismatch):
+			trace.Printf(ctx, "image search skipped: %v", err)
+			imageErr = err
+		case err != nil:
+			return nil, fmt.Errorf("failed to search images: %w", err)
+		default:
+			images = r.closeImages(ctx, found)
+		}
+	}
+
+	for i, chunk := range chunks {
+		trace.Printf(ctx, "chunk %d (distance %.4f): %s", i+1, chunk.Distance, Preview(chunk.Content, r.previewChars))
 	}
 
 	return &RetrievalResult{
-		Chunks: chunks,
-		Images: images,
+		Chunks:         chunks,
+		Images:         images,
+		Neighbors:      neighbors,
+		SourceNames:    r.sourceNames(ctx, chunks, images),
+		ImageSearchErr: imageErr,
 	}, nil
 }
 
-// RetrieveHybrid performs hybrid search (semantic + keyword)
+// withinBudget returns the leading chunks whose estimated tokens, counting
+// the neighbors that will be joined to each, fit budget; always at least one
+func (r *Retriever) withinBudget(ctx context.Context, chunks []*db.Chunk, budget int) []*db.Chunk {
+	used := 0
+	for i, chunk := range chunks {
+		tokens := len(chunk.Content) / 4 * (1 + 2*r.neighborWindow)
+		if i > 0 && used+tokens > budget {
+			trace.Printf(ctx, "token budget: kept %d chunks, ~%d of %d tokens", i, used, budget)
+			return chunks[:i]
+		}
+		used += tokens
+	}
+	trace.Printf(ctx, "token budget: kept all %d chunks, ~%d of %d tokens", len(chunks), used, budget)
+	return chunks
+}
+
+// closeImages returns the images within the maximum image distance of the
+// query, which may be none of them
+func (r *Retriever) closeImages(ctx context.Context, images []*db.Image) []*db.Image {
+	if r.imageMaxDistance <= 0 {
+		return images
+	}
+	kept := make([]*db.Image, 0, len(images))
+	for _, img := range images {
+		if img.Distance > r.imageMaxDistance {
+			trace.Printf(ctx, "image %s dropped (distance %.4f > %.4f)", filepath.Base(img.FilePath), img.Distance, r.imageMaxDistance)
+			continue
+		}
+		kept = append(kept, img)
+	}
+	return kept
+}
+
+// sourceNames returns the title, or else the file name, of each document
+// the chunks and images come from
+func (r *Retriever) sourceNames(ctx context.Context, chunks []*db.Chunk, images []*db.Image) map[uuid.UUID]string {
+	docIDs := make([]uuid.UUID, 0, len(chunks)+len(images))
+	for _, chunk := range chunks {
+		docIDs = append(docIDs, chunk.DocumentID)
+	}
+	for _, img := range images {
+		docIDs = append(docIDs, img.DocumentID)
+	}
+
+	names := make(map[uuid.UUID]string)
+	for _, docID := range docIDs {
+		if _, ok := names[docID]; ok {
+			continue
+		}
+		doc, err := r.db.GetDocumentByID(ctx, docID)
+		if err != nil || doc == nil {
+			continue
+		}
+		name := strings.TrimSpace(doc.Title)
+		if name == "" {
+			name = filepath.Base(doc.FilePath)
+		}
+		names[docID] = name
+	}
+	return names
+}
+
+// searchChunks finds the limit chunks most similar to the query or, with
+// multi-query enabled, fuses the searches for the query and its
+// paraphrases. A failure to paraphrase falls back to the query alone.
+func (r *Retriever) searchChunks(ctx context.Context, query string, embedded *QueryEmbeddings, limit int) ([]*db.Chunk, error) {
+	chunks, err := r.searchSimilar(ctx, embedded.Text, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chunks: %w", err)
+	}
+	if r.paraphraser == nil || r.queryVariants == 0 {
+		return chunks, nil
+	}
+
+	variants, err := r.paraphraser.Paraphrase(ctx, query, r.queryVariants)
+	if err != nil {
+		trace.Printf(ctx, "multi-query skipped: %v", err)
+		return chunks, nil
+	}
+	rankings := [][]*db.Chunk{chunks}
+	for _, variant := range variants {
+		trace.Printf(ctx, "query variant: %s", variant)
+		// Only chunks are searched by paraphrase, so CLIP is not needed
+		variantEmb, _, err := r.embedText(ctx, variant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+		}
+		found, err := r.searchSimilar(ctx, variantEmb, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search chunks: %w", err)
+		}
+		rankings = append(rankings, found)
+	}
+	return fuseRankings(rankings), nil
+}
+
+// searchSimilar finds the limit chunks most similar to embedding, first
+// narrowing the search to the best matching documents by summary if
+// enabled. Without any summaries, every chunk is searched.
+func (r *Retriever) searchSimilar(ctx context.Context, embedding *pgvector.Vector, limit int) ([]*db.Chunk, error) {
+	if r.summaryDocs > 0 {
+		docIDs, err := r.db.SearchDocumentSummaries(ctx, embedding, r.summaryDocs)
+		if err != nil {
+			trace.Printf(ctx, "document-level search skipped: %v", err)
+		} else if len(docIDs) > 0 {
+			trace.Printf(ctx, "searching the chunks of %d documents matched by summary", len(docIDs))
+			return r.db.SearchSimilarChunksInDocuments(ctx, embedding, docIDs, limit)
+		}
+	}
+	return r.db.SearchSimilarChunks(ctx, embedding, limit)
+}
+
+// withNeighbors replaces each chunk with a passage joining it to the chunks
+// within the neighbor window in its document, in document order. Chunks
+// already part of a more relevant passage are not repeated, so a retrieved
+// chunk adjacent to a better one is dropped. Returns the passages and the
+// neighbor chunks they include.
+func (r *Retriever) withNeighbors(ctx context.Context, chunks []*db.Chunk) ([]*db.Chunk, []*db.Chunk, error) {
+	type position struct {
+		doc   uuid.UUID
+		index int
+	}
+	included := make(map[position]bool)
+	var passages, neighbors []*db.Chunk
+
+	for _, chunk := range chunks {
+		if included[position{chunk.DocumentID, chunk.ChunkIndex}] {
+			continue
+		}
+		window, err := r.db.GetChunkRange(ctx, chunk.DocumentID,
+			chunk.ChunkIndex-r.neighborWindow, chunk.ChunkIndex+r.neighborWindow)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch neighboring chunks: %w", err)
+		}
+
+		// Consecutive chunks repeat the overlap between them, which is
+		// kept only once
+		var parts []string
+		prev := -1 // Index of the chunk that ends parts, or -1
+		for _, neighbor := range window {
+			pos := position{neighbor.DocumentID, neighbor.ChunkIndex}
+			if included[pos] {
+				prev = -1
+				continue
+			}
+			included[pos] = true
+			content := neighbor.Content
+			if neighbor.ChunkIndex == chunk.ChunkIndex {
+				content = chunk.Content
+			} else {
+				neighbors = append(neighbors, neighbor)
+			}
+			if prev >= 0 && neighbor.ChunkIndex == prev+1 {
+				parts[len(parts)-1] = stitchChunks(parts[len(parts)-1], content)
+			} else {
+				parts = append(parts, content)
+			}
+			prev = neighbor.ChunkIndex
+		}
+		if !included[position{chunk.DocumentID, chunk.ChunkIndex}] {
+			// Deleted since the search; keep the chunk as found
+			included[position{chunk.DocumentID, chunk.ChunkIndex}] = true
+			parts = append(parts, chunk.Content)
+		}
+
+		passage := *chunk
+		passage.Content = strings.Join(parts, "\n")
+		passages = append(passages, &passage)
+	}
+	return passages, neighbors, nil
+}
+
+// stitchChunks joins the chunk after prev to it, dropping the words next
+// repeats from the end of prev. The overlap is found as the longest run of
+// words ending prev that also starts next, so it works whatever overlap the
+// document was chunked with.
+func stitchChunks(prev, next string) string {
+	prevWords := strings.Fields(prev)
+	nextWords := strings.Fields(next)
+	for k := min(len(prevWords), len(nextWords)); k > 0; k-- {
+		if slices.Equal(prevWords[len(prevWords)-k:], nextWords[:k]) {
+			if k == len(nextWords) {
+				return prev
+			}
+			return prev + " " + strings.Join(nextWords[k:], " ")
+		}
+	}
+	return prev + "\n" + next
+}
+
+// Sources returns the unique source document file names for a retrieval
+// result, most relevant first: documents by the distance of their closest
+// chunk, then documents found only through images by their closest image
+// (CLIP distances are not comparable with text ones)
+func (r *Retriever) Sources(ctx context.Context, result *RetrievalResult) []string {
+	type rank struct {
+		id       uuid.UUID
+		image    bool // Found only through images
+		distance float64
+	}
+	var ranks []*rank
+	byID := make(map[uuid.UUID]*rank)
+	consider := func(id uuid.UUID, image bool, distance float64) {
+		rk, ok := byID[id]
+		switch {
+		case !ok:
+			rk = &rank{id: id, image: image, distance: distance}
+			byID[id] = rk
+			ranks = append(ranks, rk)
+		case rk.image == image && distance < rk.distance:
+			rk.distance = distance
+		}
+	}
+	for _, chunk := range result.Chunks {
+		consider(chunk.DocumentID, false, chunk.Distance)
+	}
+	for _, img := range result.Images {
+		consider(img.DocumentID, true, img.Distance)
+	}
+	// Stable, so equal distances keep the order of the results
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if ranks[i].image != ranks[j].image {
+			return !ranks[i].image
+		}
+		return ranks[i].distance < ranks[j].distance
+	})
+
+	// Fetch document file paths
+	sourceMap := make(map[string]bool)
+	var sources []string
+	for _, rk := range ranks {
+		doc, err := r.db.GetDocumentByID(ctx, rk.id)
+		if err == nil && doc != nil {
+			filePath := doc.FilePath
+			if !sourceMap[filePath] {
+				sourceMap[filePath] = true
+				sources = append(sources, filepath.Base(filePath))
+			}
+		}
+	}
+
+	return sources
+}
+
+// RetrieveHybrid performs hybrid search (semantic + keyword) whatever the
+// retrieval mode
 func (r *Retriever) RetrieveHybrid(ctx context.Context, query string) (*RetrievalResult, error) {
+	topK, budget := r.limits()
+	return r.retrieveHybrid(ctx, query, topK, budget)
+}
+
+// retrieveHybrid performs hybrid search (semantic + keyword)
+func (r *Retriever) retrieveHybrid(ctx context.Context, query string, topK, budget int) (*RetrievalResult, error) {
 	// First do semantic search
-	semanticResult, err := r.Retrieve(ctx, query)
+	semanticResult, err := r.retrieveVector(ctx, query, topK, budget)
 	if err != nil {
 		return nil, err
 	}
@@ -76,12 +682,8 @@ func (r *Retriever) RetrieveHybrid(ctx context.Context, query string) (*Retrieva
 	keywords := extractKeywords(query)
 	
 	// Filter chunks by keyword relevance
-	filteredChunks := filterByKeywords(semanticResult.Chunks, keywords)
-	
-	return &RetrievalResult{
-		Chunks: filteredChunks,
-		Images: semanticResult.Images,
-	}, nil
+	semanticResult.Chunks = filterByKeywords(semanticResult.Chunks, keywords)
+	return semanticResult, nil
 }
 
 // extractKeywords extracts important keywords from query
@@ -136,3 +738,18 @@ func filterByKeywords(chunks []*db.Chunk, keywords []string) []*db.Chunk {
 	}
 	return filtered
 }
+
+// Preview collapses the whitespace in text and cuts it to at most n
+// characters, ending in "..." if cut, for showing chunks in debug output.
+// Zero returns the whole text.
+func Preview(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if n <= 0 || len(runes) <= n {
+		return text
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,204 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/google/uuid"
+)
+
+// SourceGroup holds the retrieved chunks that came from one source document
+type SourceGroup struct {
+	Source string
+	Chunks []*db.Chunk
+}
+
+// GroupBySource groups chunks by their source document. Groups are ordered
+// by their most relevant chunk, and chunks keep their relevance order.
+func (r *Retriever) GroupBySource(ctx context.Context, chunks []*db.Chunk) []SourceGroup {
+	var groups []SourceGroup
+	index := make(map[uuid.UUID]int)
+	for _, chunk := range chunks {
+		i, ok := index[chunk.DocumentID]
+		if !ok {
+			source := chunk.DocumentID.String()
+			if doc, err := r.db.GetDocumentByID(ctx, chunk.DocumentID); err == nil && doc != nil {
+				source = filepath.Base(doc.FilePath)
+			}
+			i = len(groups)
+			index[chunk.DocumentID] = i
+			groups = append(groups, SourceGroup{Source: source})
+		}
+		groups[i].Chunks = append(groups[i].Chunks, chunk)
+	}
+	return groups
+}
+
+// BuildSummarySystemPrompt creates the system prompt for BuildSummaryPrompt:
+// the persona and how to write the overview
+func (cb *ContextBuilder) BuildSummarySystemPrompt() string {
+	var parts []string
+	parts = append(parts, cb.persona)
+	parts = append(parts, "")
+	parts = append(parts, "You will be given the most relevant passages on a topic from a library of sources, grouped by source.")
+	parts = append(parts, "Write a structured overview of the topic synthesized across those sources.")
+	parts = append(parts, "Organize it under short headings by theme, noting where sources agree or differ.")
+	parts = append(parts, "Cite every claim with the source number in brackets, e.g. [1] or [2][3].")
+	parts = append(parts, "Only use the passages given; do not draw on general knowledge.")
+
+	return strings.Join(parts, "\n")
+}
+
+// BuildSummaryPrompt creates the user prompt for a structured overview of a
+// topic: the given sources, numbered for citation as [1], [2], ..., and the topic
+func (cb *ContextBuilder) BuildSummaryPrompt(topic string, groups []SourceGroup) string {
+	var excerpts []string
+	for i, group := range groups {
+		excerpts = append(excerpts, fmt.Sprintf("### [%d] %s", i+1, group.Source))
+		for _, chunk := range group.Chunks {
+			if chunk.Section != "" {
+				excerpts = append(excerpts, fmt.Sprintf("(section: %s)", chunk.Section))
+			}
+			excerpts = append(excerpts, chunk.Content)
+			excerpts = append(excerpts, "")
+		}
+	}
+
+	context := strings.Join(excerpts, "\n")
+	maxChars := cb.tokenBudget() * 4
+	if len(context) > maxChars {
+		context = context[:maxChars] + "\n\n[Context truncated...]"
+	}
+
+	var parts []string
+	parts = append(parts, "## Sources:")
+	parts = append(parts, context)
+	parts = append(parts, "")
+	parts = append(parts, "## Topic:")
+	parts = append(parts, topic)
+
+	return strings.Join(parts, "\n")
+}
+
+// SampleChunks returns n chunks spaced evenly through a document's chunks
+// (given in document order), so that a summary sees the whole document
+// rather than just its opening. All chunks are returned if there are n or fewer.
+func SampleChunks(chunks []*db.Chunk, n int) []*db.Chunk {
+	if n <= 0 || len(chunks) <= n {
+		return chunks
+	}
+	sample := make([]*db.Chunk, 0, n)
+	for i := 0; i < n; i++ {
+		// Centre each pick in its stretch of the document
+		sample = append(sample, chunks[(2*i+1)*len(chunks)/(2*n)])
+	}
+	return sample
+}
+
+// BuildDocumentSummarySystemPrompt creates the system prompt for
+// BuildDocumentSummaryPrompt: the persona and how to write the summary
+func (cb *ContextBuilder) BuildDocumentSummarySystemPrompt() string {
+	var parts []string
+	parts = append(parts, cb.persona)
+	parts = append(parts, "")
+	parts = append(parts, "You will be given passages sampled evenly from start to end of a single document, in order.")
+	parts = append(parts, "Summarize the document as a whole: its purpose, main themes and how it is organized,")
+	parts = append(parts, "followed by the key ideas or symbols it covers as a short list.")
+	parts = append(parts, "Only use the passages given; say so if they are too fragmentary to judge something.")
+
+	return strings.Join(parts, "\n")
+}
+
+// BuildDocumentSummaryPrompt creates the user prompt for summarizing one
+// document from a sample of its chunks
+func (cb *ContextBuilder) BuildDocumentSummaryPrompt(title string, chunks []*db.Chunk) string {
+	headings := make([]string, len(chunks))
+	overhead := 0
+	for i, chunk := range chunks {
+		if chunk.Section != "" {
+			headings[i] = fmt.Sprintf("### Passage %d (section: %s):", i+1, chunk.Section)
+		} else {
+			headings[i] = fmt.Sprintf("### Passage %d:", i+1)
+		}
+		overhead += len(headings[i]) + 3 // With the newlines around the passage
+	}
+
+	// Each passage gets an equal share of the context, so that a long
+	// passage does not crowd out the end of the document
+	const elided = " [...]"
+	maxChars := cb.tokenBudget() * 4
+	share := 0
+	if len(chunks) > 0 {
+		share = max((maxChars-overhead)/len(chunks)-len(elided), 1)
+	}
+
+	var excerpts []string
+	for i, chunk := range chunks {
+		content := chunk.Content
+		if len(content) > share {
+			cut := share
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			content = content[:cut] + elided
+		}
+		excerpts = append(excerpts, headings[i])
+		excerpts = append(excerpts, content)
+		excerpts = append(excerpts, "")
+	}
+
+	context := strings.Join(excerpts, "\n")
+	if len(context) > maxChars {
+		context = context[:maxChars] + "\n\n[Context truncated...]"
+	}
+
+	var parts []string
+	parts = append(parts, "## Document:")
+	parts = append(parts, title)
+	parts = append(parts, "")
+	parts = append(parts, "## Passages:")
+	parts = append(parts, context)
+
+	return strings.Join(parts, "\n")
+}
+
+// LLMDocumentSummarizer summarizes documents at ingestion with an Ollama
+// model, sampling passages as the Documents view's summaries do
+type LLMDocumentSummarizer struct {
+	client   *ollama.Client
+	builder  *ContextBuilder
+	model    func() string // The model to ask, read on each call
+	passages int           // Chunks sampled from each document
+}
+
+// NewLLMDocumentSummarizer creates a summarizer asking the model returned by
+// model, with prompts from builder and passages chunks sampled per document
+func NewLLMDocumentSummarizer(client *ollama.Client, builder *ContextBuilder, model func() string, passages int) *LLMDocumentSummarizer {
+	return &LLMDocumentSummarizer{client: client, builder: builder, model: model, passages: passages}
+}
+
+// Summarize implements documents.Summarizer
+func (s *LLMDocumentSummarizer) Summarize(ctx context.Context, title string, chunks []*db.Chunk) (string, error) {
+	model := s.model()
+	if model == "" {
+		return "", fmt.Errorf("no model to summarize with")
+	}
+	response, err := s.client.Generate(ctx, &ollama.GenerateRequest{
+		Model:  model,
+		System: s.builder.BuildDocumentSummarySystemPrompt(),
+		Prompt: s.builder.BuildDocumentSummaryPrompt(title, SampleChunks(chunks, s.passages)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate summary: %w", err)
+	}
+	summary := strings.TrimSpace(response)
+	if summary == "" {
+		return "", fmt.Errorf("failed to generate summary: empty response")
+	}
+	return summary, nil
+}
@@ -0,0 +1,84 @@
+// Package trace tags processing and generation operations with request IDs,
+// so that the log lines and errors of one operation can be found together
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type idKey struct{}
+
+var enabled atomic.Bool
+
+// SetEnabled turns on logging of when each operation starts and finishes.
+// Request IDs are attached to contexts and errors either way.
+func SetEnabled(on bool) {
+	enabled.Store(on)
+}
+
+// Start returns ctx tagged with a new request ID, along with the ID. A
+// context that already has one keeps it, so nested operations share the ID
+// of the action that started them.
+func Start(ctx context.Context) (context.Context, string) {
+	if id := ID(ctx); id != "" {
+		return ctx, id
+	}
+	id := uuid.NewString()[:8]
+	return context.WithValue(ctx, idKey{}, id), id
+}
+
+// ID returns the request ID of ctx, or "" if it has none
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// Printf logs a line prefixed with the request ID of ctx, if tracing is
+// enabled
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	if !enabled.Load() {
+		return
+	}
+	log.Printf("[%s] %s", idOrDash(ctx), fmt.Sprintf(format, args...))
+}
+
+// Begin logs the start of an operation and returns a func that logs its end,
+// with the elapsed time and error if any. Failures are logged even when
+// tracing is disabled, so an ID shown in the UI can always be looked up.
+func Begin(ctx context.Context, operation string) func(err error) {
+	start := time.Now()
+	Printf(ctx, "%s started", operation)
+	return func(err error) {
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			log.Printf("[%s] %s failed after %s: %v", idOrDash(ctx), operation, elapsed, err)
+			return
+		}
+		Printf(ctx, "%s finished in %s", operation, elapsed)
+	}
+}
+
+// Error adds the request ID of ctx to err for showing to the user, who can
+// then find the operation's lines in the log. It returns err unchanged if
+// it is nil or ctx has no ID.
+func Error(ctx context.Context, err error) error {
+	id := ID(ctx)
+	if err == nil || id == "" {
+		return err
+	}
+	return fmt.Errorf("%w (request %s)", err, id)
+}
+
+// idOrDash returns the request ID of ctx, or "-" for an untagged context
+func idOrDash(ctx context.Context) string {
+	if id := ID(ctx); id != "" {
+		return id
+	}
+	return "-"
+}
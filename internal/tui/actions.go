@@ -3,8 +3,15 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/documents"
+	"github.com/dream-ai/cli/internal/trace"
 	"github.com/google/uuid"
 	"github.com/rivo/tview"
 )
@@ -36,7 +43,7 @@ func NewActionsView(app *App) *ActionsView {
 	// Create info text view
 	av.info = tview.NewTextView().
 		SetDynamicColors(true).
-		SetWrap(true)
+		SetWrap(av.app.cfg.Display.Wrap)
 	av.info.SetBorder(true).SetTitle(" Status ")
 
 	// Create main flex layout
@@ -59,33 +66,55 @@ func (av *ActionsView) GetPrimitive() tview.Primitive {
 func (av *ActionsView) populateActions() {
 	av.list.Clear()
 	
-	av.list.AddItem("Reprocess All Documents", "Force reprocess all documents (ignores hash check)", 'r', nil)
-	av.list.AddItem("Process Images Only", "Process images from all documents with CLIP2", 'i', nil)
-	av.list.AddItem("Reprocess Selected Document", "Reprocess the selected document from Documents view", 's', nil)
-	av.list.AddItem("Clear All Chunks", "Delete all text chunks (keeps documents)", 'c', nil)
-	av.list.AddItem("Clear All Images", "Delete all image records (keeps documents)", 'x', nil)
-	av.list.AddItem("Rebuild Embeddings", "Regenerate embeddings for all chunks", 'e', nil)
+	mutating := av.app.mutating
+	av.list.AddItem(mutating("Reprocess All Documents"), "Reprocess all documents in place (only changed chunks are re-embedded)", 'r', nil)
+	av.list.AddItem(mutating("Process Images Only"), "Process images from all documents with CLIP2", 'i', nil)
+	av.list.AddItem(mutating("Retry Failed Images"), "Show why images failed with CLIP2 and process only those again", 'f', nil)
+	av.list.AddItem(mutating("Reprocess Selected Document"), "Reprocess the selected document from Documents view", 's', nil)
+	av.list.AddItem(mutating("Clear All Chunks"), "Delete all text chunks (keeps documents)", 'c', nil)
+	av.list.AddItem(mutating("Clear All Images"), "Delete all image records (keeps documents)", 'x', nil)
+	av.list.AddItem(mutating("Rebuild Embeddings"), "Regenerate embeddings for all chunks with the current text model", 'e', nil)
+	av.list.AddItem("Reconcile Index", "Compare configured directories with processed documents", 'h', nil)
+	av.list.AddItem("Check Embedding Dimensions", "Report chunks or images embedded with mixed dimensions", 'm', nil)
+	av.list.AddItem(mutating("Optimize Database"), "Reindex vector indexes and VACUUM ANALYZE chunks and images", 'v', nil)
 	
-	av.info.SetText("[white]Select an action to perform")
+	if av.app.cfg.ReadOnly {
+		av.info.SetText(colors.Text + "Select an action to perform\n" + colors.Muted + "Greyed-out actions are disabled in read-only mode")
+	} else {
+		av.info.SetText(colors.Text + "Select an action to perform")
+	}
 }
 
 // executeAction executes the selected action
 func (av *ActionsView) executeAction(index int) {
-	ctx := context.Background()
+	ctx, _ := trace.Start(context.Background())
+
+	// Only the reports are allowed in read-only mode
+	if index != 7 && index != 8 && av.app.refuseInReadOnly(av.info) {
+		return
+	}
 	
 	switch index {
 	case 0: // Reprocess All Documents
 		av.reprocessAllDocuments(ctx)
 	case 1: // Process Images Only
 		av.processImagesOnly(ctx)
-	case 2: // Reprocess Selected Document
-		av.info.SetText("[yellow]Go to Documents view, select a document, then come back here and select this action again")
-	case 3: // Clear All Chunks
+	case 2: // Retry Failed Images
+		av.retryFailedImages(ctx)
+	case 3: // Reprocess Selected Document
+		av.info.SetText(colors.Warning + "Go to Documents view, select a document, then come back here and select this action again")
+	case 4: // Clear All Chunks
 		av.clearAllChunks(ctx)
-	case 4: // Clear All Images
+	case 5: // Clear All Images
 		av.clearAllImages(ctx)
-	case 5: // Rebuild Embeddings
+	case 6: // Rebuild Embeddings
 		av.rebuildEmbeddings(ctx)
+	case 7: // Reconcile Index
+		av.reconcileIndex(ctx)
+	case 8: // Check Embedding Dimensions
+		av.checkEmbeddingDimensions(ctx)
+	case 9: // Optimize Database
+		av.optimizeDatabase(ctx)
 	}
 }
 
@@ -93,58 +122,64 @@ func (av *ActionsView) executeAction(index int) {
 func (av *ActionsView) reprocessAllDocuments(ctx context.Context) {
 	// Run in goroutine to avoid blocking UI
 	go func() {
-		av.app.app.QueueUpdateDraw(func() {
-			av.info.SetText("[yellow]Preparing to reprocess all documents...")
+		defer av.app.beginProcessing()()
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(colors.Warning + "Preparing to reprocess all documents...")
 		})
 
 		// Get all documents
 		docs, err := av.app.db.GetAllDocuments(ctx)
 		if err != nil {
-			av.app.app.QueueUpdateDraw(func() {
-				av.info.SetText(fmt.Sprintf("[red]Error: %v", err))
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)))
 			})
 			return
 		}
 
 		if len(docs) == 0 {
-			av.app.app.QueueUpdateDraw(func() {
-				av.info.SetText("[yellow]No documents found to reprocess")
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(colors.Warning + "No documents found to reprocess")
 			})
 			return
 		}
 
 		totalProcessed := 0
 		totalErrors := 0
+		totalChunks := 0
+		totalImages := 0
+		totalWarnings := 0
 
 		// Process each document
 		for i, doc := range docs {
 			progress := float64(i) / float64(len(docs))
 			progressBar := av.renderProgressBar(progress)
 			
-			av.app.app.QueueUpdateDraw(func() {
-				av.info.SetText(fmt.Sprintf("[yellow]Processing %d/%d: %s\n%s %.1f%%", 
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Processing %d/%d: %s\n%s %.1f%%", 
 					i+1, len(docs), filepath.Base(doc.FilePath), progressBar, progress*100))
 			})
 
-			// Delete existing chunks and images for this document first
-			// (This forces reprocessing)
-			if err := av.app.db.DeleteDocument(ctx, doc.ID); err == nil {
-				// Recreate document and process
-				if err := av.app.processor.ProcessDocument(ctx, doc.FilePath); err != nil {
-					totalErrors++
-				} else {
-					totalProcessed++
-				}
-			} else {
+			// Reprocess in place; unchanged chunks keep their embeddings
+			result, err := av.app.processor.ReprocessDocumentWithRetry(ctx, doc.FilePath)
+			if err != nil {
 				totalErrors++
+				continue
 			}
+			totalProcessed++
+			totalChunks += result.Chunks
+			totalImages += result.Images
+			totalWarnings += len(result.Warnings)
 		}
 
-		av.app.app.QueueUpdateDraw(func() {
+		av.app.queueUpdateDraw(func() {
+			counts := fmt.Sprintf("%d chunks, %d images", totalChunks, totalImages)
+			if totalWarnings > 0 {
+				counts += fmt.Sprintf(", %d warnings", totalWarnings)
+			}
 			if totalErrors > 0 {
-				av.info.SetText(fmt.Sprintf("[yellow]Processed %d documents, %d errors", totalProcessed, totalErrors))
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Processed %d documents (%s), %d errors (request %s)", totalProcessed, counts, totalErrors, trace.ID(ctx)))
 			} else {
-				av.info.SetText(fmt.Sprintf("[green]Successfully reprocessed %d documents!", totalProcessed))
+				av.info.SetText(fmt.Sprintf(colors.Success+"Successfully reprocessed %d documents (%s)!", totalProcessed, counts))
 			}
 		})
 	}()
@@ -154,94 +189,133 @@ func (av *ActionsView) reprocessAllDocuments(ctx context.Context) {
 func (av *ActionsView) processImagesOnly(ctx context.Context) {
 	// Run in goroutine to avoid blocking UI
 	go func() {
-		av.app.app.QueueUpdateDraw(func() {
-			av.info.SetText("[yellow]Scanning documents for images...")
+		defer av.app.beginProcessing()()
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(colors.Warning + "Scanning documents for images...")
 		})
 
 		// Get all documents
 		docs, err := av.app.db.GetAllDocuments(ctx)
 		if err != nil {
-			av.app.app.QueueUpdateDraw(func() {
-				av.info.SetText(fmt.Sprintf("[red]Error: %v", err))
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)))
 			})
 			return
 		}
 
-		// Count total images to process
-		totalImagesToProcess := 0
-		docImageCounts := make(map[uuid.UUID]int)
+		// Collect the images that still need a caption and embedding
+		var pending []*db.Image
+		docNames := make(map[uuid.UUID]string)
 		for _, doc := range docs {
 			images, err := av.app.db.GetImagesByDocument(ctx, doc.ID)
-			if err == nil {
-				count := 0
-				for _, img := range images {
-					if img.Embedding == nil {
-						count++
-					}
-				}
-				if count > 0 {
-					docImageCounts[doc.ID] = count
-					totalImagesToProcess += count
+			if err != nil {
+				continue
+			}
+			for _, img := range images {
+				if img.Embedding == nil && img.CaptionEmbedding == nil {
+					pending = append(pending, img)
+					docNames[doc.ID] = filepath.Base(doc.FilePath)
 				}
 			}
 		}
 
-		if totalImagesToProcess == 0 {
-			av.app.app.QueueUpdateDraw(func() {
-				av.info.SetText("[yellow]No images found that need processing")
+		if len(pending) == 0 {
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(colors.Warning + "No images found that need processing")
 			})
 			return
 		}
 
-		totalProcessed := 0
-		totalErrors := 0
-		currentImage := 0
+		// Images finish out of order when several are processed at once, so
+		// progress counts completions and names the latest one
+		done, totalErrors := 0, 0
+		totalProcessed := av.app.processor.EmbedImages(ctx, pending, func(img *db.Image, err error) {
+			done++
+			if err != nil {
+				totalErrors++
+			}
+			current, docName := done, docNames[img.DocumentID]
+			av.app.queueUpdateDraw(func() {
+				progress := float64(current) / float64(len(pending))
+				progressBar := av.renderProgressBar(progress)
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Processed %d/%d images\nDocument: %s\nImage: %s\n%s %.1f%%",
+					current, len(pending), docName, filepath.Base(img.FilePath), progressBar, progress*100))
+			})
+		})
 
-		for i, doc := range docs {
-			if _, ok := docImageCounts[doc.ID]; ok {
-				av.app.app.QueueUpdateDraw(func() {
-					av.info.SetText(fmt.Sprintf("[yellow]Processing %d/%d images\nDocument %d/%d: %s\nProgress: %d/%d images", 
-						currentImage+1, totalImagesToProcess, i+1, len(docs), filepath.Base(doc.FilePath), currentImage, totalImagesToProcess))
-				})
+		av.app.queueUpdateDraw(func() {
+			if totalErrors > 0 {
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Processed %d images, %d errors", totalProcessed, totalErrors))
+			} else {
+				av.info.SetText(fmt.Sprintf(colors.Success+"Successfully processed %d images!", totalProcessed))
+			}
+		})
+	}()
+}
 
-				// Get images for this document
-				images, err := av.app.db.GetImagesByDocument(ctx, doc.ID)
-				if err != nil {
-					continue
-				}
+// retryFailedImages lists the images that failed with CLIP2 and why, then
+// processes only those again, reporting the ones that still fail
+func (av *ActionsView) retryFailedImages(ctx context.Context) {
+	go func() {
+		defer av.app.beginProcessing()()
+		failed, err := av.app.db.GetFailedImages(ctx)
+		if err != nil {
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)))
+			})
+			return
+		}
+		if len(failed) == 0 {
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(colors.Success + "No failed images")
+			})
+			return
+		}
 
-				// Process each image that doesn't have an embedding
-				for _, img := range images {
-					if img.Embedding == nil {
-						currentImage++
-						av.app.app.QueueUpdateDraw(func() {
-							progress := float64(currentImage) / float64(totalImagesToProcess)
-							progressBar := av.renderProgressBar(progress)
-							av.info.SetText(fmt.Sprintf("[yellow]Processing %d/%d images\nDocument: %s\nImage: %s\n%s %.1f%%", 
-								currentImage, totalImagesToProcess, filepath.Base(doc.FilePath), filepath.Base(img.FilePath), progressBar, progress*100))
-						})
-
-						caption, embedding, err := av.app.imageEmb.ProcessImage(ctx, img.FilePath)
-						if err == nil {
-							// Update image with caption and embedding
-							if err := av.app.db.UpdateImage(ctx, img.ID, caption, embedding); err == nil {
-								totalProcessed++
-							} else {
-								totalErrors++
-							}
-						} else {
-							totalErrors++
-						}
-					}
-				}
+		docNames := make(map[uuid.UUID]string)
+		for _, img := range failed {
+			if _, ok := docNames[img.DocumentID]; ok {
+				continue
 			}
+			if doc, err := av.app.db.GetDocumentByID(ctx, img.DocumentID); err == nil && doc != nil {
+				docNames[img.DocumentID] = filepath.Base(doc.FilePath)
+			}
+		}
+		describe := func(img *db.Image, reason string) string {
+			return fmt.Sprintf("  "+colors.Muted+"- %s, image %d (%s): "+colors.Text+"%s\n",
+				docNames[img.DocumentID], img.ImageIndex, filepath.Base(img.FilePath), tview.Escape(reason))
+		}
+
+		var previous strings.Builder
+		previous.WriteString(fmt.Sprintf(colors.Text+"Retrying %d failed images:\n", len(failed)))
+		for _, img := range failed {
+			previous.WriteString(describe(img, img.Error))
 		}
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(previous.String())
+		})
+
+		var stillFailing strings.Builder
+		done, totalErrors := 0, 0
+		retried := av.app.processor.EmbedImages(ctx, failed, func(img *db.Image, err error) {
+			done++
+			if err != nil {
+				totalErrors++
+				stillFailing.WriteString(describe(img, err.Error()))
+			}
+			current := done
+			av.app.queueUpdateDraw(func() {
+				progress := float64(current) / float64(len(failed))
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Retried %d/%d images\n%s %.1f%%\n\n%s",
+					current, len(failed), av.renderProgressBar(progress), progress*100, previous.String()))
+			})
+		})
 
-		av.app.app.QueueUpdateDraw(func() {
+		av.app.queueUpdateDraw(func() {
 			if totalErrors > 0 {
-				av.info.SetText(fmt.Sprintf("[yellow]Processed %d images, %d errors", totalProcessed, totalErrors))
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Processed %d images, %d still failing:\n%s", retried, totalErrors, stillFailing.String()))
 			} else {
-				av.info.SetText(fmt.Sprintf("[green]Successfully processed %d images!", totalProcessed))
+				av.info.SetText(fmt.Sprintf(colors.Success+"Successfully processed %d images!", retried))
 			}
 		})
 	}()
@@ -264,27 +338,199 @@ func (av *ActionsView) renderProgressBar(progress float64) string {
 
 // clearAllChunks deletes all chunks
 func (av *ActionsView) clearAllChunks(ctx context.Context) {
-	av.info.SetText("[yellow]Clearing all chunks...")
+	av.info.SetText(colors.Warning + "Clearing all chunks...")
 	av.app.app.ForceDraw()
 
 	// This would require a new database method
-	av.info.SetText("[red]Not implemented yet - would require DELETE FROM chunks")
+	av.info.SetText(colors.Error + "Not implemented yet - would require DELETE FROM chunks")
 }
 
 // clearAllImages deletes all images
 func (av *ActionsView) clearAllImages(ctx context.Context) {
-	av.info.SetText("[yellow]Clearing all images...")
+	av.info.SetText(colors.Warning + "Clearing all images...")
 	av.app.app.ForceDraw()
 
 	// This would require a new database method
-	av.info.SetText("[red]Not implemented yet - would require DELETE FROM images")
+	av.info.SetText(colors.Error + "Not implemented yet - would require DELETE FROM images")
 }
 
 // rebuildEmbeddings regenerates embeddings for all chunks
 func (av *ActionsView) rebuildEmbeddings(ctx context.Context) {
-	av.info.SetText("[yellow]Rebuilding embeddings...")
-	av.app.app.ForceDraw()
+	// New chunks embedded mid-rebuild could end up in either dimension
+	if av.app.processing.Load() > 0 {
+		av.info.SetText(colors.Warning + "Wait for processing to finish before rebuilding embeddings")
+		return
+	}
+	go func() {
+		defer av.app.beginProcessing()()
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(colors.Warning + "Rebuilding embeddings...")
+		})
+
+		dim := av.app.cfg.Embeddings.Dimension
+		embedded, err := av.app.processor.RebuildEmbeddings(ctx, dim, func(done, total int, doc *db.Document) {
+			av.app.queueUpdateDraw(func() {
+				progress := float64(done) / float64(total)
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Rebuilding embeddings with %s (%d dimensions)\nDocument %d/%d: %s\n%s %.1f%%",
+					av.app.textEmb.Model(), dim, done+1, total, filepath.Base(doc.FilePath), av.renderProgressBar(progress), progress*100))
+			})
+		})
+		// Recent queries were embedded for the old index
+		av.app.retriever.ClearQueryCache()
 
-	// This would require fetching all chunks and regenerating embeddings
-	av.info.SetText("[red]Not implemented yet - would regenerate embeddings for all chunks")
+		av.app.queueUpdateDraw(func() {
+			if err != nil {
+				av.info.SetText(fmt.Sprintf(colors.Error+"Rebuild stopped after %d chunks: %v", embedded, trace.Error(ctx, err)))
+				return
+			}
+			av.info.SetText(fmt.Sprintf(colors.Success+"Rebuilt embeddings for %d chunks with %s", embedded, av.app.textEmb.Model()))
+			av.app.dashboardView.updateStats()
+		})
+	}()
+}
+
+// reconcileIndex reports files on disk that are not ingested, ingested
+// documents whose files are missing, and documents changed since ingestion
+func (av *ActionsView) reconcileIndex(ctx context.Context) {
+	go func() {
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(colors.Warning + "Scanning directories and documents...")
+		})
+
+		docs, err := av.app.db.GetAllDocuments(ctx)
+		if err != nil {
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)))
+			})
+			return
+		}
+
+		ingested := make(map[string]bool, len(docs))
+		for _, doc := range docs {
+			ingested[filepath.Clean(doc.FilePath)] = true
+		}
+
+		var notIngested, missing, changed []string
+		files, warnings := documents.ScanDirectories(av.app.cfg.Paths.DocumentsDirs, av.app.scanExclusions())
+		for _, file := range files {
+			if !ingested[filepath.Clean(file)] {
+				notIngested = append(notIngested, file)
+			}
+		}
+		for _, doc := range docs {
+			info, err := os.Stat(doc.FilePath)
+			if os.IsNotExist(err) {
+				missing = append(missing, doc.FilePath)
+				continue
+			}
+			if err == nil && av.app.cfg.Processing.QuickScan && documents.FileUnchanged(doc, info) {
+				continue
+			}
+			hash, err := documents.ComputeFileHash(doc.FilePath)
+			if err == nil && hash != doc.FileHash {
+				changed = append(changed, doc.FilePath)
+			}
+		}
+
+		var report strings.Builder
+		report.WriteString(fmt.Sprintf(colors.Text+"Checked %d documents against configured directories\n", len(docs)))
+		for _, warning := range warnings {
+			report.WriteString(fmt.Sprintf(colors.Error+"Warning: %s"+colors.Text+"\n", warning))
+		}
+		writeSection := func(title, color string, files []string) {
+			report.WriteString(fmt.Sprintf("\n%s%s (%d):"+colors.Text+"\n", color, title, len(files)))
+			for _, file := range files {
+				report.WriteString(fmt.Sprintf("  "+colors.Muted+"- %s"+colors.Text+"\n", file))
+			}
+		}
+		writeSection("On disk, not ingested", colors.Warning, notIngested)
+		writeSection("Ingested, missing on disk", colors.Error, missing)
+		writeSection("Changed since ingestion", colors.Accent, changed)
+
+		if len(notIngested)+len(missing)+len(changed) == 0 {
+			report.WriteString("\n" + colors.Success + "Index is in sync with the filesystem")
+		}
+
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(report.String())
+			av.info.ScrollToBeginning()
+		})
+	}()
+}
+
+// checkEmbeddingDimensions reports how many chunk and image embeddings there
+// are of each dimension. Mixed dimensions, left by re-embedding part of the
+// index with a different model, make searches fail unpredictably.
+func (av *ActionsView) checkEmbeddingDimensions(ctx context.Context) {
+	go func() {
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(colors.Warning + "Checking embedding dimensions...")
+		})
+
+		var report strings.Builder
+		consistent := true
+		for _, table := range []struct{ name, title string }{{"chunks", "Chunks"}, {"images", "Images"}} {
+			counts, err := av.app.db.EmbeddingDimensions(ctx, table.name)
+			if err != nil {
+				av.app.queueUpdateDraw(func() {
+					av.info.SetText(fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)))
+				})
+				return
+			}
+
+			dims := make([]int, 0, len(counts))
+			for dim := range counts {
+				dims = append(dims, dim)
+			}
+			sort.Ints(dims)
+
+			color := colors.Success
+			if len(dims) > 1 {
+				color = colors.Error
+				consistent = false
+			}
+			report.WriteString(fmt.Sprintf("%s%s:"+colors.Text+"\n", color, table.title))
+			if len(dims) == 0 {
+				report.WriteString("  " + colors.Muted + "no embeddings" + colors.Text + "\n")
+			}
+			for _, dim := range dims {
+				report.WriteString(fmt.Sprintf("  %s-dim: %d\n", emphasis(fmt.Sprint(dim)), counts[dim]))
+			}
+			if table.name == "chunks" && len(dims) == 1 && dims[0] != av.app.cfg.Embeddings.Dimension {
+				report.WriteString(fmt.Sprintf("  "+colors.Warning+"configured dimension is %d"+colors.Text+"\n", av.app.cfg.Embeddings.Dimension))
+			}
+			report.WriteString("\n")
+		}
+
+		if consistent {
+			report.WriteString(colors.Success + "Each embedding column holds a single dimension")
+		} else {
+			report.WriteString(colors.Error + "Mixed dimensions found: searches may fail. Rebuild the index with one embedding model (Clear All Chunks, then reprocess)")
+		}
+
+		av.app.queueUpdateDraw(func() {
+			av.info.SetText(report.String())
+			av.info.ScrollToBeginning()
+		})
+	}()
+}
+
+// optimizeDatabase rebuilds the vector indexes and refreshes statistics,
+// which keeps search fast after bulk imports and deletes
+func (av *ActionsView) optimizeDatabase(ctx context.Context) {
+	go func() {
+		start := time.Now()
+		err := av.app.db.Maintain(ctx, func(step string) {
+			av.app.queueUpdateDraw(func() {
+				av.info.SetText(fmt.Sprintf(colors.Warning+"Optimizing database...\n"+colors.Text+"%s", step))
+			})
+		})
+		av.app.queueUpdateDraw(func() {
+			if err != nil {
+				av.info.SetText(fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)))
+				return
+			}
+			av.info.SetText(fmt.Sprintf(colors.Success+"Database optimized in %s", time.Since(start).Round(time.Second)))
+		})
+	}()
 }
@@ -2,7 +2,14 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dream-ai/cli/config"
 	"github.com/dream-ai/cli/internal/db"
@@ -10,6 +17,7 @@ import (
 	"github.com/dream-ai/cli/internal/embeddings"
 	"github.com/dream-ai/cli/internal/ollama"
 	"github.com/dream-ai/cli/internal/rag"
+	"github.com/dream-ai/cli/internal/trace"
 	"github.com/rivo/tview"
 	"github.com/gdamore/tcell/v2"
 )
@@ -17,7 +25,9 @@ import (
 // App represents the main TUI application using tview
 type App struct {
 	app            *tview.Application
+	root           *tview.Flex
 	pages          *tview.Pages
+	pausedBar      *tview.TextView
 	db             *db.DB
 	processor      *documents.Processor
 	retriever      *rag.Retriever
@@ -27,7 +37,18 @@ type App struct {
 	textEmb        *embeddings.TextEmbedder
 	imageEmb       *embeddings.ImageEmbedder
 	cfg            *config.Config
+	stopWatcher    context.CancelFunc
+
+	// Open modal page name and the primitive to refocus when it closes
+	modal       string
+	modalReturn tview.Primitive
+
+	// screen is the terminal screen, captured on draw for clipboard access
+	screen tcell.Screen
 	
+	// Top-level pages in navigation order
+	pageList []page
+
 	// Views
 	dashboardView *DashboardView
 	chatView      *ChatView
@@ -35,18 +56,69 @@ type App struct {
 	modelsView    *ModelsView
 	settingsView  *SettingsView
 	actionsView   *ActionsView
+	historyView   *HistoryView
+
+	// Background refresh pause state. While paused, the stats loop skips its
+	// ticks and background UI updates are held until resume.
+	paused    atomic.Bool
+	pendingMu sync.Mutex
+	pending   []func()
+
+	// Imports and reprocessing runs in progress; the dashboard refreshes
+	// less often while there are any, leaving connections to ingestion
+	processing atomic.Int32
+
+	// Query timings for the metrics file
+	metrics sessionMetrics
+
+	// Whether the dimension mismatch notice was shown for chunks and for
+	// images; only used on the UI goroutine
+	chunkMismatchNoticed bool
+	imageMismatchNoticed bool
+}
+
+// page is a top-level view, registered with the pages under name and
+// switched to with key
+type page struct {
+	name        string
+	key         rune
+	title       string // Dashboard menu entry
+	description string
+	primitive   tview.Primitive
 }
 
 // NewApp creates a new TUI application
 func NewApp(cfg *config.Config) (*App, error) {
 	// Initialize database
-	database, err := db.New(cfg.Database.ConnectionString)
+	database, err := db.New(cfg.Database.ConnectionString, cfg.Database.Schema)
+	if errors.Is(err, db.ErrNotMigrated) {
+		return nil, fmt.Errorf("%w; run %s first", err, cfg.MigrateCommand())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	database.SetInsertBatchSize(cfg.Processing.InsertBatchSize)
+
+	// A profile's isolated index must match its embedding dimension
+	if cfg.Database.Schema != "" {
+		if err := database.EnsureEmbeddingDimension(context.Background(), cfg.Embeddings.Dimension); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("schema %s: %w", cfg.Database.Schema, err)
+		}
+	}
 
 	// Initialize embeddings
 	textEmb := embeddings.NewTextEmbedder(cfg.Ollama.BaseURL, cfg.Embeddings.TextModel)
+	textEmb.SetTimeout(cfg.Embeddings.Timeout)
+	textEmb.SetDimension(cfg.Embeddings.Dimension)
+	// Among fallback models, keep to the one the index was built with
+	indexModel, err := database.IndexEmbeddingModel(context.Background())
+	if err != nil {
+		indexModel = ""
+	}
+	if indexModel != "" {
+		textEmb.PreferModel(indexModel)
+	}
 	imageEmb := embeddings.NewImageEmbedder(cfg.CLIP2.PythonPath)
 	if cfg.CLIP2.ScriptPath != "" {
 		imageEmb.SetScriptPath(cfg.CLIP2.ScriptPath)
@@ -59,22 +131,83 @@ func NewApp(cfg *config.Config) (*App, error) {
 		imageEmb,
 		cfg.Paths.ImageDir,
 		cfg.Processing.ChunkSize,
-		cfg.Processing.ChunkOverlap,
+		cfg.Processing.ChunkOverlapPercent,
 	)
+	if indexModel != "" && !textEmb.HasModel(indexModel) {
+		if cfg.Embeddings.OnModelChange == "warn" {
+			log.Printf("warning: the index was built with %s but embeddings.text_model is %s; new chunks will not match existing ones until the index is rebuilt",
+				indexModel, cfg.Embeddings.TextModel)
+		} else {
+			processor.RefuseModelChange(indexModel)
+		}
+	}
+	processor.SetImageFormat(cfg.Processing.ImageFormat, cfg.Processing.JPEGQuality)
+	processor.SetRetry(cfg.Processing.RetryAttempts, cfg.Processing.RetryBackoff)
+	processor.SetMinChunkChars(cfg.Processing.MinChunkChars)
+	processor.SetThumbnailSize(cfg.Processing.ThumbnailSize)
+	processor.SetImageConcurrency(cfg.Processing.ImageConcurrency)
+	processor.SetQuickScan(cfg.Processing.QuickScan)
+	processor.SetCaptionOnly(cfg.CLIP2.CaptionOnly)
+	processor.SetNormalization(documents.NormalizeOptions{
+		Dehyphenate:        cfg.Processing.Normalize.Dehyphenate,
+		CollapseWhitespace: cfg.Processing.Normalize.CollapseWhitespace,
+		StripPageNumbers:   cfg.Processing.Normalize.StripPageNumbers,
+	})
 
 	// Initialize RAG components
-	retriever := rag.NewRetriever(database, textEmb, 5) // Default topK
-	contextBuilder := rag.NewContextBuilder(2000) // Default max context length
+	reranker, err := rag.NewReranker(cfg.RAG.Reranker)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	retriever := rag.NewRetriever(database, textEmb, cfg.Processing.TopK)
+	retriever.SetReranker(reranker, cfg.RAG.CandidateMultiplier)
+	retriever.SetNeighborWindow(cfg.RAG.NeighborWindow)
+	retriever.SetDedupe(cfg.RAG.DedupeResults)
+	if err := retriever.SetMode(cfg.RAG.Mode); err != nil {
+		database.Close()
+		return nil, err
+	}
+	retriever.SetPreviewChars(cfg.RAG.DebugPreviewChars)
+	retriever.SetImageMaxDistance(cfg.RAG.ImageMaxDistance)
+	if cfg.RAG.AdaptiveTopK {
+		retriever.SetTokenBudget(cfg.RAG.MaxContextLength, cfg.RAG.MaxTopK)
+	}
+	// Caption-only images have no CLIP vectors to match, so are found by
+	// caption instead
+	if cfg.CLIP2.CaptionOnly {
+		retriever.SetCaptionSearch(true)
+	} else if cfg.RAG.ImageSearch {
+		retriever.SetImageEmbedder(imageEmb)
+	}
+	contextBuilder := rag.NewContextBuilder(cfg.RAG.MaxContextLength)
+	if err := contextBuilder.SetOrder(cfg.RAG.ContextOrder); err != nil {
+		database.Close()
+		return nil, err
+	}
+	contextBuilder.SetPersona(cfg.RAG.Persona)
+	contextBuilder.SetIncludeImages(cfg.RAG.IncludeImages)
+	contextBuilder.SetLabelSources(cfg.RAG.LabelSources)
+	contextBuilder.SetMarkGeneralKnowledge(cfg.RAG.MarkGeneralKnowledge)
+	for _, t := range cfg.RAG.PromptTemplates {
+		if err := contextBuilder.AddPromptTemplate(t.Match, t.Template); err != nil {
+			database.Close()
+			return nil, err
+		}
+	}
 
 	// Initialize Ollama client
 	ollamaClient := ollama.NewClient(cfg.Ollama.BaseURL)
+	ollamaClient.SetMaxConcurrentGenerations(cfg.Ollama.MaxConcurrentGenerations)
+	ollamaClient.SetTimeout(cfg.Chat.Timeout)
 	modelSelector := ollama.NewModelSelector(ollamaClient)
 
 	// Select default model
 	ctx := context.Background()
 	defaultModel, err := modelSelector.SelectBestModel(ctx)
 	if err != nil {
-		defaultModel = "llama3.2" // Fallback
+		// No usable model; chat and dashboard report this until one is selected
+		defaultModel = ""
 	}
 
 	app := &App{
@@ -87,8 +220,12 @@ func NewApp(cfg *config.Config) (*App, error) {
 		textEmb:        textEmb,
 		imageEmb:       imageEmb,
 		cfg:            cfg,
+		metrics:        sessionMetrics{started: time.Now()},
 	}
 
+	// The theme must be in place before any view is created
+	applyTheme(resolveTheme(cfg.Display.Theme, cfg.Display.Colors))
+
 	// Initialize tview application
 	app.app = tview.NewApplication()
 	app.pages = tview.NewPages()
@@ -100,36 +237,154 @@ func NewApp(cfg *config.Config) (*App, error) {
 	app.modelsView = NewModelsView(app, defaultModel)
 	app.settingsView = NewSettingsView(app)
 	app.actionsView = NewActionsView(app)
+	app.historyView = NewHistoryView(app)
+
+	// Captions in another language are translated by the current chat model
+	imageEmb.SetCaptionLanguage(cfg.CLIP2.CaptionLanguage, func(ctx context.Context, caption, language string) (string, error) {
+		return ollamaClient.Translate(ctx, app.chatView.Model(), caption, language)
+	})
 
-	// Add pages
-	app.pages.AddPage("dashboard", app.dashboardView.GetPrimitive(), true, true)
-	app.pages.AddPage("chat", app.chatView.GetPrimitive(), true, false)
-	app.pages.AddPage("documents", app.documentsView.GetPrimitive(), true, false)
-	app.pages.AddPage("models", app.modelsView.GetPrimitive(), true, false)
-	app.pages.AddPage("settings", app.settingsView.GetPrimitive(), true, false)
-	app.pages.AddPage("actions", app.actionsView.GetPrimitive(), true, false)
-	app.pages.AddPage("actions", app.actionsView.GetPrimitive(), true, false)
+	// Summaries of long questions and paraphrases for multi-query retrieval
+	// come from the current chat model
+	retriever.SetLongQuery(cfg.RAG.LongQuery, cfg.RAG.MaxQueryChars, rag.NewLLMCondenser(ollamaClient, app.chatView.Model))
+	if cfg.RAG.MultiQuery {
+		retriever.SetMultiQuery(rag.NewLLMParaphraser(ollamaClient, app.chatView.Model), cfg.RAG.MultiQueryCount)
+	}
+
+	// Documents are summarized by the current chat model as they are ingested
+	if cfg.Processing.SummarizeDocuments {
+		processor.SetSummarizer(rag.NewLLMDocumentSummarizer(ollamaClient, contextBuilder, app.chatView.Model, cfg.RAG.SummaryTopK))
+		retriever.SetDocumentSummaries(cfg.RAG.SummaryDocuments)
+	}
+
+	// Add pages; the navigation keys and dashboard menu come from the same list
+	app.pageList = []page{
+		{"dashboard", '0', "Dashboard", "", app.dashboardView.GetPrimitive()},
+		{"chat", '1', "Chat Console", "Start chatting about dreams and symbols", app.chatView.GetPrimitive()},
+		{"documents", '2', "Documents Manager", "Manage and process documents", app.documentsView.GetPrimitive()},
+		{"models", '3', "Model Selection", "Select Ollama model", app.modelsView.GetPrimitive()},
+		{"settings", '4', "Settings", "View application settings", app.settingsView.GetPrimitive()},
+		{"actions", '5', "Actions", "Document processing actions", app.actionsView.GetPrimitive()},
+		{"history", '6', "Conversation History", "Browse past questions and answers", app.historyView.GetPrimitive()},
+	}
+	for i, p := range app.pageList {
+		app.pages.AddPage(p.name, p.primitive, true, i == 0)
+	}
+	app.dashboardView.setNavigation(app.pageList[1:])
+
+	// Paused indicator, collapsed to zero height until paused
+	app.pausedBar = tview.NewTextView().
+		SetDynamicColors(true).
+		SetText(colors.Banner + " PAUSED [-:-] Background refresh paused - press Ctrl+P to resume")
+	app.root = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(app.pages, 0, 1, true).
+		AddItem(app.pausedBar, 0, 0, false)
 
 	// Set root
-	app.app.SetRoot(app.pages, true).SetFocus(app.pages)
+	app.app.SetRoot(app.root, true).SetFocus(app.pages)
+	app.app.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
+		app.screen = screen
+		return false
+	})
 	
-	// Set focus to chat input when switching to chat page
+	// Set focus to chat input when switching to chat page, and list the
+	// latest conversations when switching to history
 	app.pages.SetChangedFunc(func() {
 		name, _ := app.pages.GetFrontPage()
-		if name == "chat" {
+		switch name {
+		case "chat":
 			app.app.SetFocus(app.chatView.input)
+		case "history":
+			app.historyView.reload()
 		}
 	})
 
 	// Set up global key handlers
 	app.setupGlobalKeys()
 
+	// Auto-ingest files dropped into the documents directories, unless the
+	// index is read-only
+	if cfg.Paths.Watch && !cfg.ReadOnly {
+		app.startWatcher()
+	}
+
 	return app, nil
 }
 
+// scanExclusions returns the files directory scans skip: the configured
+// exclude patterns and the image output directory
+func (a *App) scanExclusions() documents.Exclusions {
+	return documents.Exclusions{
+		Patterns: a.cfg.Paths.Exclude,
+		Dirs:     []string{a.cfg.Paths.ImageDir},
+	}
+}
+
+// startWatcher starts the background documents directory watcher
+func (a *App) startWatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopWatcher = cancel
+
+	watcher := documents.NewWatcher(
+		a.cfg.Paths.DocumentsDirs,
+		func(ctx context.Context, filePath string) (*documents.ProcessResult, error) {
+			ctx, _ = trace.Start(ctx)
+			defer a.beginProcessing()()
+			result, err := a.processor.ProcessDocumentWithRetry(ctx, filePath)
+			return result, trace.Error(ctx, err)
+		},
+		func(filePath string, result *documents.ProcessResult, err error) {
+			if result.Outcome == documents.ProcessSkipped {
+				// Touched but unchanged; nothing to report
+				return
+			}
+			a.queueUpdateDraw(func() {
+				a.documentsView.reloadDocuments()
+				if err != nil {
+					a.documentsView.info.SetText(fmt.Sprintf(colors.Error+"Auto-ingest failed for %s: %v", filepath.Base(filePath), err))
+				} else {
+					a.documentsView.info.SetText(fmt.Sprintf(colors.Success+"Auto-ingested %s: %s", filepath.Base(filePath), describeResult(result)))
+				}
+			})
+		},
+	)
+
+	watcher.SetExclusions(a.scanExclusions())
+
+	go func() {
+		if err := watcher.Run(ctx); err != nil {
+			a.queueUpdateDraw(func() {
+				a.documentsView.info.SetText(fmt.Sprintf(colors.Error+"Directory watcher stopped: %v", err))
+			})
+		}
+	}()
+}
+
 // setupGlobalKeys sets up global keyboard shortcuts
 func (a *App) setupGlobalKeys() {
 	a.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// Ctrl+P pauses/resumes background refresh from any view
+		if event.Key() == tcell.KeyCtrlP {
+			a.togglePause()
+			return nil
+		}
+
+		// Modals handle their own keys (including Esc to close)
+		if a.modal != "" {
+			if event.Key() == tcell.KeyCtrlC {
+				a.app.Stop()
+				return nil
+			}
+			return event
+		}
+
+		// Ctrl+O switches the active corpus from any view
+		if event.Key() == tcell.KeyCtrlO {
+			a.showCorpusSelector()
+			return nil
+		}
+
 		// Get the currently focused primitive
 		focused := a.app.GetFocus()
 		
@@ -174,32 +429,142 @@ func (a *App) setupGlobalKeys() {
 		}
 
 		// Number keys for navigation (only when not in input field)
-		switch event.Rune() {
-		case '0':
-			a.pages.SwitchToPage("dashboard")
-			return nil
-		case '1':
-			a.pages.SwitchToPage("chat")
-			return nil
-		case '2':
-			a.pages.SwitchToPage("documents")
-			return nil
-		case '3':
-			a.pages.SwitchToPage("models")
-			return nil
-		case '4':
-			a.pages.SwitchToPage("settings")
-			return nil
-		case '5':
-			a.pages.SwitchToPage("actions")
-			return nil
+		for _, p := range a.pageList {
+			if event.Rune() == p.key {
+				a.pages.SwitchToPage(p.name)
+				return nil
+			}
 		}
 
 		return event
 	})
 }
 
+// showModal shows p centered over the current page and focuses it. While a
+// modal is open, navigation keys are passed through to it.
+func (a *App) showModal(name string, p tview.Primitive, width, height int) {
+	a.modal = name
+	a.modalReturn = a.app.GetFocus()
+
+	centered := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(
+			tview.NewFlex().
+				SetDirection(tview.FlexRow).
+				AddItem(nil, 0, 1, false).
+				AddItem(p, height, 0, true).
+				AddItem(nil, 0, 1, false),
+			width, 0, true,
+		).
+		AddItem(nil, 0, 1, false)
+
+	a.pages.AddPage(name, centered, true, true)
+	a.app.SetFocus(p)
+}
+
+// copyToClipboard puts text on the system clipboard through the terminal
+// (OSC 52), which needs a terminal that supports it. Must be called from the
+// UI goroutine.
+func (a *App) copyToClipboard(text string) bool {
+	if a.screen == nil {
+		return false
+	}
+	a.screen.SetClipboard([]byte(text))
+	return true
+}
+
+// hideModal closes the open modal and restores the previous focus
+func (a *App) hideModal() {
+	if a.modal == "" {
+		return
+	}
+	a.pages.RemovePage(a.modal)
+	a.modal = ""
+	if a.modalReturn != nil {
+		a.app.SetFocus(a.modalReturn)
+		a.modalReturn = nil
+	}
+}
+
+// togglePause pauses or resumes background refreshes and progress updates.
+// Must be called from the UI goroutine.
+func (a *App) togglePause() {
+	a.pendingMu.Lock()
+	paused := !a.paused.Load()
+	a.paused.Store(paused)
+	var pending []func()
+	if !paused {
+		pending = a.pending
+		a.pending = nil
+	}
+	a.pendingMu.Unlock()
+
+	if paused {
+		a.root.ResizeItem(a.pausedBar, 1, 0)
+		return
+	}
+
+	a.root.ResizeItem(a.pausedBar, 0, 0)
+	// Apply updates held while paused, in the order they were queued
+	for _, f := range pending {
+		f()
+	}
+}
+
+// beginProcessing marks a document processing run as in progress until the
+// returned function is called
+func (a *App) beginProcessing() func() {
+	a.processing.Add(1)
+	return func() { a.processing.Add(-1) }
+}
+
+// queueUpdateDraw queues a UI update from a background goroutine. While
+// background refresh is paused the update is held and applied on resume.
+func (a *App) queueUpdateDraw(f func()) {
+	a.pendingMu.Lock()
+	if a.paused.Load() {
+		a.pending = append(a.pending, f)
+		a.pendingMu.Unlock()
+		return
+	}
+	a.pendingMu.Unlock()
+	a.app.QueueUpdateDraw(f)
+}
+
+// contextBuilderFor returns the context builder with the model's prompt
+// template and its budget fitted to the model's context window
+func (a *App) contextBuilderFor(ctx context.Context, model string) *rag.ContextBuilder {
+	builder := a.contextBuilder.ForModel(model)
+	numCtx, err := a.ollamaClient.ContextLength(ctx, model)
+	if err != nil {
+		return builder
+	}
+	return builder.FitToWindow(numCtx, a.cfg.RAG.ContextWindowFraction)
+}
+
 // Run starts the TUI application
 func (a *App) Run() error {
+	if a.stopWatcher != nil {
+		defer a.stopWatcher()
+	}
 	return a.app.Run()
 }
+
+// UsageSummary reports the session's activity for printing after the TUI exits.
+// Everything is counted locally.
+func (a *App) UsageSummary() string {
+	usage := a.ollamaClient.Usage()
+
+	var b strings.Builder
+	b.WriteString("Session summary\n")
+	fmt.Fprintf(&b, "  Documents processed: %d\n", a.processor.Processed())
+	fmt.Fprintf(&b, "  Chats sent:          %d\n", a.chatView.Sent())
+	fmt.Fprintf(&b, "  Tokens generated:    %d (%d prompt tokens evaluated)\n", usage.GeneratedTokens, usage.PromptTokens)
+	if usage.Requests > 0 {
+		fmt.Fprintf(&b, "  Avg response time:   %s over %d responses\n", usage.AverageResponseTime().Round(100*time.Millisecond), usage.Requests)
+	}
+	if tps := usage.TokensPerSecond(); tps > 0 {
+		fmt.Fprintf(&b, "  Throughput:          %.1f tokens/s\n", tps)
+	}
+	return b.String()
+}
@@ -2,13 +2,18 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
+	"github.com/dream-ai/cli/internal/db"
 	"github.com/dream-ai/cli/internal/ollama"
 	"github.com/dream-ai/cli/internal/rag"
+	"github.com/dream-ai/cli/internal/trace"
 	"github.com/gdamore/tcell/v2"
 	"github.com/google/uuid"
 	"github.com/rivo/tview"
@@ -18,12 +23,25 @@ import (
 type ChatView struct {
 	app      *App
 	flex     *tview.Flex
+	body     *tview.Flex
 	messages *tview.TextView
+	debug    *tview.TextView
 	input    *tview.TextArea
-	model    string
 
+	// mu guards model, messagesData, loading and sent, which are also reached from
+	// the Models view and from background generation goroutines
+	mu           sync.Mutex
+	model        string
 	messagesData []Message
 	loading      bool
+	sent         int // Questions sent this session
+
+	showThinking bool
+	showDebug    bool
+
+	// selected is the message highlighted while browsing the history, or
+	// -1 when the input has focus. Only used on the UI goroutine.
+	selected int
 }
 
 // Message represents a chat message
@@ -31,34 +49,110 @@ type Message struct {
 	Role    string
 	Content string
 	Sources []string // Document file paths used as sources
+	// Thinking holds reasoning blocks stripped from the answer, if any
+	Thinking string
+	// ContextTruncated is set when the retrieved context was cut to fit
+	// max_context_length, so the answer rests on part of it
+	ContextTruncated bool
+	// NoSources is set when retrieval found nothing, so the answer comes
+	// from the model's general knowledge rather than the documents
+	NoSources bool
+	// Model is set on replies to a question sent to a model named with
+	// @model, rather than the session's
+	Model string
+}
+
+// parseModelDirective splits a leading "@model" off a message, returning
+// the model named (empty if there is none) and the question that follows.
+// The name ends at the first whitespace; an "@" not followed by one is an
+// error.
+func parseModelDirective(msg string) (model, question string, err error) {
+	msg = strings.TrimSpace(msg)
+	rest, ok := strings.CutPrefix(msg, "@")
+	if !ok {
+		return "", msg, nil
+	}
+	end := strings.IndexFunc(rest, unicode.IsSpace)
+	if end < 0 {
+		end = len(rest)
+	}
+	model, question = rest[:end], strings.TrimSpace(rest[end:])
+	if model == "" {
+		return "", "", errors.New("name a model right after @, e.g. @llama3.2 followed by the question")
+	}
+	return model, question, nil
 }
 
+// Sent returns how many questions were sent this session
+func (cv *ChatView) Sent() int {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return cv.sent
+}
+
+// noModelsMessage is shown instead of generating when no model is selected
+const noModelsMessage = "No models available - pull one (e.g. ollama pull llama3.2) and select it in the Models view (press 3)"
+
 // NewChatView creates a new chat view
 func NewChatView(app *App, defaultModel string) *ChatView {
 	cv := &ChatView{
 		app:          app,
 		model:        defaultModel,
 		messagesData: []Message{},
+		selected:     -1,
 	}
 
 	// Create messages text view
 	cv.messages = tview.NewTextView().
 		SetDynamicColors(true).
-		SetWrap(true).
+		SetRegions(true).
+		SetWrap(cv.app.cfg.Display.Wrap).
 		SetScrollable(true)
 	cv.messages.SetBorder(true).SetTitle(" Chat ")
+	cv.messages.SetInputCapture(cv.handleHistoryKey)
+
+	// Create retrieval debug panel, hidden until toggled
+	cv.debug = tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(cv.app.cfg.Display.Wrap).
+		SetScrollable(true).
+		SetText(colors.Muted + "Send a message to see retrieval details")
+	cv.debug.SetBorder(true).SetTitle(" Retrieval Debug ")
+
+	cv.body = tview.NewFlex().
+		AddItem(cv.messages, 0, 1, false).
+		AddItem(cv.debug, 0, 0, false)
 
 	// Create input text area (supports multi-line and wrapping)
 	cv.input = tview.NewTextArea().
-		SetPlaceholder("Ask about dreams or symbols... (Ctrl+Enter to send)").
+		SetPlaceholder("Ask about dreams or symbols... (@model to ask another model, Ctrl+Enter to send, Shift+Tab browse history, Ctrl+T reasoning, Ctrl+G retrieval debug)").
 		SetWrap(true)
 
-	// Handle Ctrl+Enter to send message
+	// Handle Ctrl+Enter to send message, Shift+Tab to browse the history,
+	// Ctrl+T to toggle model reasoning, Ctrl+G to toggle the retrieval debug panel
 	cv.input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEnter && event.Modifiers()&tcell.ModCtrl != 0 {
 			cv.sendMessage()
 			return nil
 		}
+		if event.Key() == tcell.KeyBacktab {
+			cv.browseHistory()
+			return nil
+		}
+		if event.Key() == tcell.KeyCtrlT {
+			cv.showThinking = !cv.showThinking
+			cv.renderMessages()
+			return nil
+		}
+		if event.Key() == tcell.KeyCtrlG {
+			cv.showDebug = !cv.showDebug
+			if cv.showDebug {
+				cv.body.ResizeItem(cv.debug, 0, 1)
+			} else {
+				cv.body.ResizeItem(cv.debug, 0, 0)
+			}
+			return nil
+		}
 		return event
 	})
 
@@ -72,7 +166,7 @@ func NewChatView(app *App, defaultModel string) *ChatView {
 	// Create main flex layout
 	cv.flex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(cv.messages, 0, 1, false).
+		AddItem(cv.body, 0, 1, false).
 		AddItem(inputFlex, 3, 0, true)
 
 	return cv
@@ -83,109 +177,444 @@ func (cv *ChatView) GetPrimitive() tview.Primitive {
 	return cv.flex
 }
 
+// Model returns the model used for new messages
+func (cv *ChatView) Model() string {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return cv.model
+}
+
+// SetModel sets the model used for new messages. A generation already in
+// progress finishes with the model it started with.
+func (cv *ChatView) SetModel(model string) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.model = model
+}
+
 // sendMessage sends a message and gets a response
 func (cv *ChatView) sendMessage() {
 	userMsg := cv.input.GetText()
-	if strings.TrimSpace(userMsg) == "" || cv.loading {
+	if strings.TrimSpace(userMsg) == "" {
+		return
+	}
+
+	cv.mu.Lock()
+	if cv.loading {
+		cv.mu.Unlock()
 		return
 	}
 
-	// Clear input
+	// Clear input, and stop highlighting a message browsed earlier
 	cv.input.SetText("", false)
-	cv.loading = true
+	if cv.selected >= 0 {
+		cv.clearSelection()
+	}
 
-	// Add user message
-	cv.messagesData = append(cv.messagesData, Message{
-		Role:    "user",
-		Content: userMsg,
-	})
-	cv.renderMessages()
+	// "@model question" sends just this question to another model
+	override, query, err := parseModelDirective(userMsg)
+	if err == nil && override != "" && query == "" {
+		err = fmt.Errorf("add a question after @%s", override)
+	}
+	if err != nil {
+		cv.messagesData = append(cv.messagesData,
+			Message{Role: "user", Content: userMsg},
+			Message{Role: "assistant", Content: colors.Error + "Error: " + err.Error()},
+		)
+		cv.mu.Unlock()
+		cv.renderMessages()
+		return
+	}
 
-	// Add placeholder for assistant message
-	cv.messagesData = append(cv.messagesData, Message{
-		Role:    "assistant",
-		Content: "[yellow]Thinking...",
-	})
+	// Without a model, generation can only fail; say so up front
+	model := cv.model
+	if model == "" && override == "" {
+		cv.messagesData = append(cv.messagesData,
+			Message{Role: "user", Content: userMsg},
+			Message{Role: "assistant", Content: colors.Error + noModelsMessage},
+		)
+		cv.mu.Unlock()
+		cv.renderMessages()
+		return
+	}
+
+	cv.loading = true
+	cv.sent++
+
+	// Add user message and a placeholder for the assistant message
+	cv.messagesData = append(cv.messagesData,
+		Message{Role: "user", Content: userMsg},
+		Message{Role: "assistant", Content: colors.Warning + "Thinking..."},
+	)
+	reply := len(cv.messagesData) - 1
+	cv.mu.Unlock()
 	cv.renderMessages()
 
 	// Generate response asynchronously
-	go cv.generateResponse(userMsg)
+	go cv.generateResponse(query, model, override, reply)
+}
+
+// finishReply fills in the placeholder message at index reply and ends
+// loading. It must run on the UI goroutine.
+func (cv *ChatView) finishReply(reply int, msg Message) {
+	cv.mu.Lock()
+	if reply < len(cv.messagesData) {
+		cv.messagesData[reply] = msg
+	}
+	cv.loading = false
+	cv.mu.Unlock()
+	cv.renderMessages()
 }
 
-// generateResponse generates a response using RAG
-func (cv *ChatView) generateResponse(query string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+// generateResponse generates a response using RAG with the given model, or
+// the installed model named by override if set, and stores it in the
+// placeholder message at index reply
+func (cv *ChatView) generateResponse(query, model, override string, reply int) {
+	timeout := cv.app.cfg.Chat.Timeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	ctx, requestID := trace.Start(ctx)
+
+	var answeredBy string // Named on the answer when not the session's model
+	if override != "" {
+		resolved, err := cv.app.modelSelector.ResolveModel(ctx, override)
+		if err != nil {
+			cv.app.queueUpdateDraw(func() {
+				cv.finishReply(reply, Message{Role: "assistant", Content: fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err))})
+			})
+			return
+		}
+		model, answeredBy = resolved, resolved
+	}
 
 	// Retrieve relevant context
+	retrievalStart := time.Now()
 	result, err := cv.app.retriever.Retrieve(ctx, query)
+	retrievalTime := time.Since(retrievalStart)
 	if err != nil {
-		cv.app.app.QueueUpdateDraw(func() {
-			cv.messagesData[len(cv.messagesData)-1].Content = fmt.Sprintf("[red]Error: %v", err)
-			cv.loading = false
-			cv.renderMessages()
+		err = ollama.CheckTimeout(ctx, err, timeout)
+		cv.app.queueUpdateDraw(func() {
+			cv.finishReply(reply, Message{Role: "assistant", Content: fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)), Model: answeredBy})
+			if errors.Is(err, db.ErrDimensionMismatch) {
+				cv.app.noticeDimensionMismatch(false)
+			}
+		})
+		return
+	}
+
+	// With nothing retrieved the model can only answer from general
+	// knowledge; refuse if so configured, or else mark the answer
+	if result.Empty() && cv.app.cfg.RAG.NoResults == "refuse" {
+		cv.app.queueUpdateDraw(func() {
+			cv.finishReply(reply, Message{Role: "assistant", Content: colors.Warning + rag.NoSourcesAnswer, Model: answeredBy})
 		})
 		return
 	}
 
 	// Build context
-	context := cv.app.contextBuilder.BuildContext(result)
-	prompt := cv.app.contextBuilder.BuildPrompt(context, query)
+	builder := cv.app.contextBuilderFor(ctx, model)
+	context, truncated := builder.BuildContext(result)
+	system := builder.BuildSystemPrompt()
+	prompt := builder.BuildPrompt(context, query)
+
+	// Reuse a cached answer when the same question meets the same context
+	var cacheKey, response string
+	var stats *ollama.GenerateResponse
+	cached := false
+	if cv.app.cfg.RAG.CacheAnswers {
+		cacheKey = rag.AnswerCacheKey(query, model, builder, result)
+		response, cached, _ = cv.app.db.GetCachedAnswer(ctx, cacheKey)
+	}
 
 	// Generate response
-	response, err := cv.app.ollamaClient.Generate(ctx, &ollama.GenerateRequest{
-		Model:  cv.model,
-		Prompt: prompt,
-		Stream: false,
-	})
+	var generationTime time.Duration
+	if !cached {
+		generationStart := time.Now()
+		response, stats, err = cv.app.ollamaClient.GenerateWithStats(ctx, &ollama.GenerateRequest{
+			Model:  model,
+			System: system,
+			Prompt: prompt,
+			Stream: false,
+		})
+		generationTime = time.Since(generationStart)
+		// Keep what was generated before the stream broke off
+		if errors.Is(err, ollama.ErrTruncatedResponse) && response != "" {
+			response += "\n\n" + colors.Warning + "(response cut off: " + err.Error() + ")" + colors.Text
+			err = nil
+			cacheKey = ""
+		}
+		if err == nil && cacheKey != "" {
+			cv.app.db.PutCachedAnswer(ctx, cacheKey, query, model, response, rag.ChunkIDs(result))
+		}
+		err = ollama.CheckTimeout(ctx, err, timeout)
+	}
+	var thinking string
+	if err == nil {
+		if cv.app.cfg.Ollama.StripThinkTags {
+			response, thinking = stripThinking(response, cv.app.cfg.Ollama.ThinkTags)
+		}
+		cv.app.metrics.recordQuery(retrievalTime, generationTime, cached)
+		cv.saveConversation(ctx, query, model, response, result)
+	}
 
 	// Extract unique source documents from retrieval result
 	sources := cv.extractSources(ctx, result)
+	debugText := cv.formatDebug(ctx, result, system, prompt, stats)
+	if cached {
+		debugText = colors.Success + "Answer served from cache" + colors.Text + "\n\n" + debugText
+	}
+	debugText = colors.Muted + "Request " + requestID + colors.Text + "\n\n" + debugText
 
-	cv.app.app.QueueUpdateDraw(func() {
+	// Look for a replacement when the session's model has gone from Ollama
+	var replacement string
+	missing := errors.Is(err, ollama.ErrModelNotFound)
+	if missing && answeredBy == "" {
+		replacement, _ = cv.app.modelSelector.SelectBestModel(ctx)
+	}
+
+	cv.app.queueUpdateDraw(func() {
+		cv.debug.SetText(debugText).ScrollToBeginning()
+		if result.ImageSearchErr != nil {
+			cv.app.noticeDimensionMismatch(true)
+		}
+		if missing {
+			cv.finishReply(reply, Message{Role: "assistant", Content: colors.Error + missingModelMessage(model)})
+			if answeredBy == "" {
+				cv.app.showMissingModel(model, replacement)
+			}
+			return
+		}
 		if err != nil {
-			cv.messagesData[len(cv.messagesData)-1].Content = fmt.Sprintf("[red]Error: %v", err)
-			cv.messagesData[len(cv.messagesData)-1].Sources = nil
-		} else {
-			cv.messagesData[len(cv.messagesData)-1].Content = response
-			cv.messagesData[len(cv.messagesData)-1].Sources = sources
+			cv.finishReply(reply, Message{Role: "assistant", Content: fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)), Model: answeredBy})
+			return
 		}
-		cv.loading = false
-		cv.renderMessages()
+		cv.finishReply(reply, Message{
+			Role:             "assistant",
+			Content:          response,
+			Thinking:         thinking,
+			Sources:          sources,
+			ContextTruncated: truncated,
+			NoSources:        result.Empty(),
+			Model:            answeredBy,
+		})
 	})
 }
 
+// saveConversation records an answered question for the History view. A
+// read-only index is left unchanged, and a failure is only logged, as the
+// answer has been given anyway.
+func (cv *ChatView) saveConversation(ctx context.Context, query, model, response string, result *rag.RetrievalResult) {
+	if cv.app.cfg.ReadOnly {
+		return
+	}
+	imageIDs := make([]uuid.UUID, len(result.Images))
+	for i, img := range result.Images {
+		imageIDs[i] = img.ID
+	}
+	done := trace.Begin(ctx, "saving conversation")
+	done(cv.app.db.SaveConversation(ctx, &db.Conversation{
+		ID:               uuid.New(),
+		UserMessage:      query,
+		AssistantMessage: response,
+		ModelName:        model,
+		ContextChunkIDs:  rag.ChunkIDs(result),
+		ContextImageIDs:  imageIDs,
+	}))
+}
+
 // renderMessages updates the messages display
 func (cv *ChatView) renderMessages() {
+	cv.mu.Lock()
+	messages := append([]Message(nil), cv.messagesData...)
+	cv.mu.Unlock()
+
+	// Each message is a region so it can be highlighted while browsing
 	var lines []string
-	for _, msg := range cv.messagesData {
-		var prefix string
-		var color string
-		if msg.Role == "user" {
-			prefix = "You: "
-			color = "[cyan]"
-			lines = append(lines, fmt.Sprintf("%s%s%s[white]", color, prefix, msg.Content))
-		} else {
-			prefix = "AI: "
-			color = "[white]"
-			// Convert markdown to tview format and add content
-			formattedContent := cv.formatMarkdown(msg.Content)
-			lines = append(lines, fmt.Sprintf("%s%s%s[white]", color, prefix, formattedContent))
-
-			// Add sources section if available
-			if len(msg.Sources) > 0 {
-				lines = append(lines, "")
-				lines = append(lines, "[yellow]Sources Found:[white]")
-				for _, source := range msg.Sources {
-					lines = append(lines, fmt.Sprintf("  [gray]- %s[white]", source))
-				}
-			}
-		}
+	for i, msg := range messages {
+		lines = append(lines, fmt.Sprintf(`["%s"]%s[""]`, messageRegion(i), cv.formatMessage(msg, cv.showThinking)))
 	}
 	cv.messages.SetText(strings.Join(lines, "\n"))
+	if cv.selected >= 0 {
+		cv.messages.ScrollToHighlight()
+	} else {
+		cv.messages.ScrollToEnd()
+	}
+}
+
+// formatMessage renders one message for display, with the reasoning if
+// withThinking is set
+func (cv *ChatView) formatMessage(msg Message, withThinking bool) string {
+	if msg.Role == "user" {
+		return fmt.Sprintf(colors.Accent+"You: %s"+colors.Text, msg.Content)
+	}
+
+	var lines []string
+	if withThinking && msg.Thinking != "" {
+		lines = append(lines, fmt.Sprintf(colors.Muted+"Reasoning:\n%s"+colors.Text, msg.Thinking))
+	}
+	// Convert markdown to tview format and add content
+	speaker := "AI"
+	if msg.Model != "" {
+		speaker = fmt.Sprintf("AI (%s)", msg.Model)
+	}
+	if msg.NoSources {
+		lines = append(lines, colors.Warning+rag.NoSourcesNote+colors.Text)
+	}
+	lines = append(lines, fmt.Sprintf(colors.Text+"%s: %s"+colors.Text, speaker, cv.formatAnswer(msg.Content)))
+	if msg.ContextTruncated {
+		lines = append(lines, colors.Muted+"(context truncated — consider raising max_context_length or narrowing your question)"+colors.Text)
+	}
+
+	// Add sources section if available
+	if len(msg.Sources) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, colors.Emphasis+"Sources Found:"+colors.Text)
+		for _, source := range msg.Sources {
+			lines = append(lines, fmt.Sprintf("  "+colors.Muted+"- %s"+colors.Text, source))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// messageRegion returns the text region ID of the i-th message
+func messageRegion(i int) string {
+	return fmt.Sprintf("msg%d", i)
+}
+
+// messageCount returns the number of messages in the history
+func (cv *ChatView) messageCount() int {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return len(cv.messagesData)
+}
+
+// browseHistory moves focus from the input to the transcript, selecting the
+// latest message
+func (cv *ChatView) browseHistory() {
+	count := cv.messageCount()
+	if count == 0 {
+		return
+	}
+	cv.selectMessage(count - 1)
+	cv.app.app.SetFocus(cv.messages)
+}
+
+// leaveHistory clears the selection and returns focus to the input
+func (cv *ChatView) leaveHistory() {
+	cv.clearSelection()
+	cv.app.app.SetFocus(cv.input)
+}
+
+// clearSelection removes the history highlight and follows the latest
+// message again
+func (cv *ChatView) clearSelection() {
+	cv.selected = -1
+	cv.messages.Highlight()
+	cv.messages.SetTitle(" Chat ")
 	cv.messages.ScrollToEnd()
 }
 
+// selectMessage highlights message i, clamped to the history
+func (cv *ChatView) selectMessage(i int) {
+	count := cv.messageCount()
+	if count == 0 {
+		return
+	}
+	cv.selected = max(0, min(i, count-1))
+	cv.messages.Highlight(messageRegion(cv.selected))
+	cv.messages.ScrollToHighlight()
+	cv.messages.SetTitle(fmt.Sprintf(" Chat - message %d/%d (↑/↓ select, Enter expand, c copy, Tab back) ", cv.selected+1, count))
+}
+
+// selectedMessage returns the selected message, if any
+func (cv *ChatView) selectedMessage() (Message, bool) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	if cv.selected < 0 || cv.selected >= len(cv.messagesData) {
+		return Message{}, false
+	}
+	return cv.messagesData[cv.selected], true
+}
+
+// handleHistoryKey handles keys while the transcript has focus
+func (cv *ChatView) handleHistoryKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyUp:
+		cv.selectMessage(cv.selected - 1)
+		return nil
+	case tcell.KeyDown:
+		cv.selectMessage(cv.selected + 1)
+		return nil
+	case tcell.KeyHome:
+		cv.selectMessage(0)
+		return nil
+	case tcell.KeyEnd:
+		cv.selectMessage(cv.messageCount() - 1)
+		return nil
+	case tcell.KeyEnter:
+		cv.expandSelected()
+		return nil
+	case tcell.KeyTab, tcell.KeyBacktab:
+		cv.leaveHistory()
+		return nil
+	}
+	switch event.Rune() {
+	case 'k':
+		cv.selectMessage(cv.selected - 1)
+		return nil
+	case 'j':
+		cv.selectMessage(cv.selected + 1)
+		return nil
+	case 'c':
+		cv.copySelected()
+		return nil
+	}
+	return event
+}
+
+// expandSelected shows the selected message on its own, with its reasoning
+// and sources, in a scrollable modal
+func (cv *ChatView) expandSelected() {
+	msg, ok := cv.selectedMessage()
+	if !ok {
+		return
+	}
+	index := cv.selected
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(cv.app.cfg.Display.Wrap).
+		SetScrollable(true).
+		SetText(cv.formatMessage(msg, true))
+	view.SetBorder(true).SetTitle(fmt.Sprintf(" Message %d (c to copy, Esc to close) ", index+1))
+	view.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape || key == tcell.KeyEnter {
+			cv.app.hideModal()
+		}
+	})
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'c' {
+			cv.copySelected()
+			return nil
+		}
+		return event
+	})
+	cv.app.showModal("message", view, 100, 30)
+}
+
+// copySelected copies the selected message's text to the clipboard
+func (cv *ChatView) copySelected() {
+	msg, ok := cv.selectedMessage()
+	if !ok {
+		return
+	}
+	if cv.app.copyToClipboard(msg.Content) {
+		cv.messages.SetTitle(fmt.Sprintf(" Chat - copied message %d to the clipboard (Tab back) ", cv.selected+1))
+	} else {
+		cv.messages.SetTitle(" Chat - clipboard unavailable ")
+	}
+}
+
 // formatMarkdown converts markdown syntax to tview color codes
 func (cv *ChatView) formatMarkdown(text string) string {
 	// First, handle headers and lists (process line by line)
@@ -199,23 +628,23 @@ func (cv *ChatView) formatMarkdown(text string) string {
 		if strings.HasPrefix(trimmed, "### ") {
 			// Level 3 header
 			headerText := strings.TrimPrefix(trimmed, "### ")
-			formattedLines = append(formattedLines, fmt.Sprintf("[yellow]%s[white]", headerText))
+			formattedLines = append(formattedLines, fmt.Sprintf(colors.Emphasis+"%s"+colors.Text, headerText))
 			continue
 		} else if strings.HasPrefix(trimmed, "## ") {
 			// Level 2 header
 			headerText := strings.TrimPrefix(trimmed, "## ")
-			formattedLines = append(formattedLines, fmt.Sprintf("[yellow]%s[white]", headerText))
+			formattedLines = append(formattedLines, fmt.Sprintf(colors.Emphasis+"%s"+colors.Text, headerText))
 			continue
 		} else if strings.HasPrefix(trimmed, "# ") {
 			// Level 1 header
 			headerText := strings.TrimPrefix(trimmed, "# ")
-			formattedLines = append(formattedLines, fmt.Sprintf("[yellow]%s[white]", headerText))
+			formattedLines = append(formattedLines, fmt.Sprintf(colors.Emphasis+"%s"+colors.Text, headerText))
 			continue
 		} else if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") {
 			// Bullet points - process bold within bullets
 			bulletText := strings.TrimPrefix(strings.TrimPrefix(trimmed, "- "), "* ")
 			formattedBullet := cv.processBold(bulletText)
-			formattedLines = append(formattedLines, fmt.Sprintf("  [gray]•[white] %s", formattedBullet))
+			formattedLines = append(formattedLines, fmt.Sprintf("  "+colors.Muted+"•"+colors.Text+" %s", formattedBullet))
 			continue
 		}
 
@@ -227,7 +656,7 @@ func (cv *ChatView) formatMarkdown(text string) string {
 	return strings.Join(formattedLines, "\n")
 }
 
-// processBold converts **bold** markdown to [yellow]bold[white] tview format
+// processBold converts **bold** markdown to text in the emphasis color
 func (cv *ChatView) processBold(text string) string {
 	// Find all ** pairs and replace them
 	var result strings.Builder
@@ -237,9 +666,9 @@ func (cv *ChatView) processBold(text string) string {
 	for i < len(text) {
 		if i < len(text)-1 && text[i] == '*' && text[i+1] == '*' {
 			if boldOpen {
-				result.WriteString("[white]")
+				result.WriteString(colors.Text)
 			} else {
-				result.WriteString("[yellow]")
+				result.WriteString(colors.Emphasis)
 			}
 			boldOpen = !boldOpen
 			i += 2
@@ -251,37 +680,93 @@ func (cv *ChatView) processBold(text string) string {
 
 	// If we ended with an open bold tag, close it
 	if boldOpen {
-		result.WriteString("[white]")
+		result.WriteString(colors.Text)
 	}
 
 	return result.String()
 }
 
-// extractSources extracts unique document file paths from retrieval result
-func (cv *ChatView) extractSources(ctx context.Context, result *rag.RetrievalResult) []string {
-	sourceMap := make(map[string]bool)
-	var sources []string
-
-	// Get document IDs from chunks
-	docIDs := make(map[uuid.UUID]bool)
-	for _, chunk := range result.Chunks {
-		docIDs[chunk.DocumentID] = true
-	}
-	for _, img := range result.Images {
-		docIDs[img.DocumentID] = true
-	}
-
-	// Fetch document file paths
-	for docID := range docIDs {
-		doc, err := cv.app.db.GetDocumentByID(ctx, docID)
-		if err == nil && doc != nil {
-			filePath := doc.FilePath
-			if !sourceMap[filePath] {
-				sourceMap[filePath] = true
-				sources = append(sources, filepath.Base(filePath))
+// formatDebug describes a chat turn for the debug panel: the retrieved
+// chunks and images with their distances, token counts, and the exact system
+// prompt and prompt
+func (cv *ChatView) formatDebug(ctx context.Context, result *rag.RetrievalResult, system, prompt string, stats *ollama.GenerateResponse) string {
+	var b strings.Builder
+
+	docNames := make(map[uuid.UUID]string)
+	docName := func(docID uuid.UUID) string {
+		if name, ok := docNames[docID]; ok {
+			return name
+		}
+		name := docID.String()
+		if doc, err := cv.app.db.GetDocumentByID(ctx, docID); err == nil && doc != nil {
+			name = filepath.Base(doc.FilePath)
+		}
+		docNames[docID] = name
+		return name
+	}
+
+	b.WriteString(fmt.Sprintf(colors.Emphasis+"Retrieved chunks (%d):"+colors.Text+"\n", len(result.Chunks)))
+	for i, chunk := range result.Chunks {
+		snippet := rag.Preview(chunk.Content, cv.app.cfg.RAG.DebugPreviewChars)
+		location := fmt.Sprintf("#%d", chunk.ChunkIndex)
+		if chunk.Section != "" {
+			location += " " + chunk.Section
+		}
+		b.WriteString(fmt.Sprintf("%d. "+colors.Accent+"%s"+colors.Text+" %s  distance "+colors.Success+"%.4f"+colors.Text+"\n   "+colors.Muted+"%s"+colors.Text+"\n",
+			i+1, tview.Escape(docName(chunk.DocumentID)), tview.Escape(location), chunk.Distance, tview.Escape(snippet)))
+	}
+
+	b.WriteString(fmt.Sprintf("\n"+colors.Emphasis+"Retrieved images (%d):"+colors.Text+"\n", len(result.Images)))
+	for i, img := range result.Images {
+		b.WriteString(fmt.Sprintf("%d. "+colors.Accent+"%s"+colors.Text+"  distance "+colors.Success+"%.4f"+colors.Text+"\n",
+			i+1, tview.Escape(filepath.Base(img.FilePath)), img.Distance))
+		if origin := rag.ImageOrigin(img, result.SourceNames); origin != "" {
+			b.WriteString("   " + colors.Muted + tview.Escape(origin) + colors.Text + "\n")
+		}
+	}
+
+	b.WriteString("\n" + colors.Emphasis + "Tokens:" + colors.Text + "\n")
+	b.WriteString(fmt.Sprintf("  Prompt estimate: %d (~4 chars/token)\n", (len(system)+len(prompt))/4))
+	if stats != nil && stats.PromptEvalCount > 0 {
+		b.WriteString(fmt.Sprintf("  Prompt (model): %d\n  Answer: %d\n", stats.PromptEvalCount, stats.EvalCount))
+	}
+
+	b.WriteString("\n" + colors.Emphasis + "System prompt:" + colors.Text + "\n")
+	b.WriteString(tview.Escape(system))
+	b.WriteString("\n\n" + colors.Emphasis + "Prompt:" + colors.Text + "\n")
+	b.WriteString(tview.Escape(prompt))
+	return b.String()
+}
+
+// stripThinking removes <tag>...</tag> blocks for the given tag names and
+// returns the remaining answer and the removed block contents. An unclosed
+// opening tag (e.g. a truncated response) strips everything after it.
+func stripThinking(text string, tags []string) (answer, thinking string) {
+	var blocks []string
+	for _, tag := range tags {
+		openTag, closeTag := "<"+tag+">", "</"+tag+">"
+		for {
+			start := strings.Index(text, openTag)
+			if start < 0 {
+				break
+			}
+			end := strings.Index(text[start:], closeTag)
+			if end < 0 {
+				blocks = append(blocks, strings.TrimSpace(text[start+len(openTag):]))
+				text = text[:start]
+				break
 			}
+			end += start
+			blocks = append(blocks, strings.TrimSpace(text[start+len(openTag):end]))
+			text = text[:start] + text[end+len(closeTag):]
 		}
 	}
+	return strings.TrimSpace(text), strings.Join(blocks, "\n\n")
+}
 
-	return sources
+// extractSources lists the source documents of a retrieval result, followed
+// by its images numbered as in the context
+func (cv *ChatView) extractSources(ctx context.Context, result *rag.RetrievalResult) []string {
+	sources := cv.app.retriever.Sources(ctx, result)
+	return append(sources, rag.ImageSources(result)...)
 }
@@ -0,0 +1,163 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dream-ai/cli/config"
+	"github.com/dream-ai/cli/internal/embeddings"
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/dream-ai/cli/internal/rag"
+	"github.com/rivo/tview"
+)
+
+// newTestChatView returns a chat view whose app is paused, so background
+// updates queue in app.pending instead of needing a running tview loop.
+// Its Ollama lists one installed model and fails to embed, so that
+// retrieval fails before it would need a database.
+func newTestChatView(t *testing.T) *ChatView {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embeddings" {
+			http.Error(w, "embeddings unavailable", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"models":[{"name":"llama3.2:latest"}]}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := ollama.NewClient(server.URL)
+	app := &App{
+		app:           tview.NewApplication(),
+		cfg:           config.Default(),
+		ollamaClient:  client,
+		modelSelector: ollama.NewModelSelector(client),
+		retriever:     rag.NewRetriever(nil, embeddings.NewTextEmbedder(server.URL, "nomic-embed-text"), 5),
+	}
+	app.paused.Store(true)
+	app.chatView = NewChatView(app, "llama3.2:latest")
+	return app.chatView
+}
+
+// runPending runs the queued background updates, as the UI goroutine would
+func runPending(a *App) {
+	a.pendingMu.Lock()
+	pending := a.pending
+	a.pending = nil
+	a.pendingMu.Unlock()
+	for _, f := range pending {
+		f()
+	}
+}
+
+// TestChatViewConcurrentModelSwitch sends questions, each answered by a
+// generateResponse goroutine, while the model is switched and the chat
+// state read from other goroutines. Run with -race.
+func TestChatViewConcurrentModelSwitch(t *testing.T) {
+	cv := newTestChatView(t)
+	const questions = 20
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	// The Models view switching the chat model
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			cv.SetModel(fmt.Sprintf("model-%d", i%3))
+			_ = cv.Model()
+		}
+	}()
+
+	// The dashboard reading the chat state
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_ = cv.Sent()
+			_ = cv.messageCount()
+		}
+	}()
+
+	// The UI goroutine: each question names a model that is not installed,
+	// so its generation fails at resolving it and queues the reply
+	for i := 0; i < questions; i++ {
+		cv.input.SetText(fmt.Sprintf("@missing-%d question %d", i, i), false)
+		cv.sendMessage()
+		waitForReply(cv)
+	}
+	close(stop)
+	wg.Wait()
+
+	if got := cv.Sent(); got != questions {
+		t.Errorf("Sent() = %d, want %d", got, questions)
+	}
+	if got := cv.messageCount(); got != 2*questions {
+		t.Fatalf("messageCount() = %d, want %d", got, 2*questions)
+	}
+	for i := 1; i < 2*questions; i += 2 {
+		if msg := cv.messagesData[i]; msg.Role != "assistant" || msg.Content == colors.Warning+"Thinking..." {
+			t.Errorf("message %d was not answered: %+v", i, msg)
+		}
+	}
+}
+
+// waitForReply answers the queued background updates until the question
+// sent last has its reply
+func waitForReply(cv *ChatView) {
+	for {
+		runPending(cv.app)
+		cv.mu.Lock()
+		loading := cv.loading
+		cv.mu.Unlock()
+		if !loading {
+			return
+		}
+	}
+}
+
+// TestChatViewModelDirective sends a question to an installed model named
+// with @model, without its tag, and checks that the reply is the one model's
+// and that the session keeps its own
+func TestChatViewModelDirective(t *testing.T) {
+	cv := newTestChatView(t)
+	cv.SetModel("mistral:latest")
+
+	cv.input.SetText("@llama3.2 what do falling dreams mean?", false)
+	cv.sendMessage()
+	waitForReply(cv)
+
+	if got := cv.messageCount(); got != 2 {
+		t.Fatalf("messageCount() = %d, want 2", got)
+	}
+	if msg := cv.messagesData[0]; msg.Content != "@llama3.2 what do falling dreams mean?" {
+		t.Errorf("question = %q", msg.Content)
+	}
+	reply := cv.messagesData[1]
+	if reply.Model != "llama3.2:latest" {
+		t.Errorf("reply model = %q, want llama3.2:latest", reply.Model)
+	}
+	// Resolved, the question went on to retrieval, which the test server
+	// fails
+	if !strings.Contains(reply.Content, "embeddings unavailable") {
+		t.Errorf("reply = %q, want the retrieval error", reply.Content)
+	}
+	if got := cv.Model(); got != "mistral:latest" {
+		t.Errorf("Model() = %q, want the session's mistral:latest", got)
+	}
+}
@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// corpusName returns the display name of a schema, "public" for ""
+func corpusName(schema string) string {
+	if schema == "" {
+		return "public"
+	}
+	return schema
+}
+
+// corpora returns the schemas that can be made the active corpus: the one
+// configured at startup, then database.corpora
+func (a *App) corpora() []string {
+	schemas := []string{a.cfg.Database.Schema}
+	for _, schema := range a.cfg.Database.Corpora {
+		if schema == "public" {
+			schema = ""
+		}
+		if !slices.Contains(schemas, schema) {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+// showCorpusSelector opens a list of the corpora for switching the active one
+func (a *App) showCorpusSelector() {
+	label := a.cfg.Display.CorpusLabel
+	schemas := a.corpora()
+	active := a.db.Schema()
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(fmt.Sprintf(" Select %s (Enter to switch, Esc to cancel) ", label))
+	for _, schema := range schemas {
+		name := corpusName(schema)
+		if schema == active {
+			name += " " + accent("(active)")
+		}
+		list.AddItem(name, "", 0, nil)
+	}
+	list.SetCurrentItem(max(slices.Index(schemas, active), 0))
+	list.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		schema := schemas[index]
+		if schema == a.db.Schema() {
+			a.hideModal()
+			return
+		}
+		if err := a.switchCorpus(schema); err != nil {
+			list.SetTitle(fmt.Sprintf(" %v (Esc to close) ", err))
+			return
+		}
+		a.hideModal()
+		a.documentsView.reloadDocuments()
+		a.documentsView.info.SetText(fmt.Sprintf(colors.Success+"Switched to %s %s", label, corpusName(schema)))
+		a.dashboardView.updateStats()
+		a.dashboardView.render()
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			a.hideModal()
+			return nil
+		}
+		return event
+	})
+
+	a.showModal("corpus", list, 70, min(len(schemas), 10)+2)
+}
+
+// switchCorpus makes schema the active corpus for the dashboard, documents,
+// ingestion and retrieval. It is refused while documents are processing, so
+// that an import does not end up split across corpora.
+func (a *App) switchCorpus(schema string) error {
+	if a.processing.Load() > 0 {
+		return fmt.Errorf("wait for processing to finish before switching")
+	}
+
+	ctx := context.Background()
+	previous := a.db.Schema()
+	if err := a.db.SetSchema(ctx, schema); err != nil {
+		return err
+	}
+	// Each corpus's index must match the configured embedding dimension,
+	// as a profile's must at startup
+	if schema != "" {
+		if err := a.db.EnsureEmbeddingDimension(ctx, a.cfg.Embeddings.Dimension); err != nil {
+			a.db.SetSchema(ctx, previous)
+			return fmt.Errorf("schema %s: %w", schema, err)
+		}
+	}
+
+	// Keep to the model this corpus was built with, as NewApp does
+	indexModel, err := a.db.IndexEmbeddingModel(ctx)
+	if err != nil {
+		indexModel = ""
+	}
+	// An empty corpus goes back to the configured order of models
+	a.textEmb.PreferModel(indexModel)
+	// Queries embedded for the last corpus may be from another model
+	a.retriever.ClearQueryCache()
+	if a.cfg.Embeddings.OnModelChange == "warn" {
+		if indexModel != "" && !a.textEmb.HasModel(indexModel) {
+			log.Printf("warning: corpus %s was built with %s but embeddings.text_model is %s; new chunks will not match existing ones until it is rebuilt",
+				corpusName(schema), indexModel, a.cfg.Embeddings.TextModel)
+		}
+	} else {
+		a.processor.RefuseModelChange(indexModel)
+	}
+	return nil
+}
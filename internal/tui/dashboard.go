@@ -58,26 +58,8 @@ func NewDashboardView(app *App) *DashboardView {
 		SetWrap(false)
 	dv.progress.SetBorder(true).SetTitle(" Progress ")
 
-	// Create menu list
-	dv.menu = tview.NewList().
-		AddItem("Chat Console", "Start chatting about dreams and symbols", '1', func() {
-			app.pages.SwitchToPage("chat")
-		}).
-		AddItem("Documents Manager", "Manage and process documents", '2', func() {
-			app.pages.SwitchToPage("documents")
-		}).
-		AddItem("Model Selection", "Select Ollama model", '3', func() {
-			app.pages.SwitchToPage("models")
-		}).
-		AddItem("Settings", "View application settings", '4', func() {
-			app.pages.SwitchToPage("settings")
-		}).
-		AddItem("Actions", "Document processing actions", '5', func() {
-			app.pages.SwitchToPage("actions")
-		}).
-		AddItem("Quit", "Press to exit", 'q', func() {
-			app.app.Stop()
-		})
+	// Create menu list, filled in by setNavigation once all pages exist
+	dv.menu = tview.NewList()
 	dv.menu.SetBorder(true).SetTitle(" Navigation ")
 
 	// Create main flex layout
@@ -107,14 +89,41 @@ func (dv *DashboardView) GetPrimitive() tview.Primitive {
 	return dv.flex
 }
 
+// setNavigation fills the menu with an entry for each page, followed by Quit
+func (dv *DashboardView) setNavigation(pages []page) {
+	dv.menu.Clear()
+	for _, p := range pages {
+		name := p.name
+		dv.menu.AddItem(p.title, p.description, p.key, func() {
+			dv.app.pages.SwitchToPage(name)
+		})
+	}
+	dv.menu.AddItem("Quit", "Press to exit", 'q', func() {
+		dv.app.app.Stop()
+	})
+}
+
+// processingRefreshTicks is how many ticks apart the stats are refreshed
+// while documents are being processed, so the stats queries do not compete
+// with ingestion for pool connections
+const processingRefreshTicks = 5
+
 // updateStatsLoop updates statistics periodically
 func (dv *DashboardView) updateStatsLoop() {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
+	tick := 0
 	for range ticker.C {
+		if dv.app.paused.Load() {
+			continue
+		}
+		tick++
+		if dv.app.processing.Load() > 0 && tick%processingRefreshTicks != 0 {
+			continue
+		}
 		dv.updateStats()
-		dv.app.app.QueueUpdateDraw(func() {
+		dv.app.queueUpdateDraw(func() {
 			dv.render()
 		})
 	}
@@ -126,16 +135,15 @@ func (dv *DashboardView) updateStats() {
 	stats := DashboardStats{
 		ProcessingStatus: "Ready",
 	}
+	if dv.app.processing.Load() > 0 {
+		stats.ProcessingStatus = "Processing..."
+	}
 
 	// Get document stats
-	docs, err := dv.app.db.GetAllDocuments(ctx)
+	total, processed, err := dv.app.db.CountDocumentStatus(ctx)
 	if err == nil {
-		stats.TotalDocuments = len(docs)
-		for _, doc := range docs {
-			if doc.ProcessedAt != nil {
-				stats.ProcessedDocuments++
-			}
-		}
+		stats.TotalDocuments = total
+		stats.ProcessedDocuments = processed
 	}
 
 		// Get chunk, image, word, and page stats
@@ -154,9 +162,15 @@ func (dv *DashboardView) updateStats() {
 // render updates the display
 func (dv *DashboardView) render() {
 	// Update status
-	statusText := fmt.Sprintf("[green]●[white] %s", dv.statsData.ProcessingStatus)
+	statusText := fmt.Sprintf(colors.Success+"●"+colors.Text+" %s", dv.statsData.ProcessingStatus)
 	if dv.statsData.ProcessingStatus == "Processing..." {
-		statusText = fmt.Sprintf("[yellow]●[white] %s", dv.statsData.ProcessingStatus)
+		statusText = fmt.Sprintf(colors.Warning+"●"+colors.Text+" %s", dv.statsData.ProcessingStatus)
+	}
+	if dv.app.cfg.ReadOnly {
+		statusText += "\n" + colors.Warning + "● Read-only" + colors.Text + " - documents, the index and settings cannot be changed"
+	}
+	if dv.app.chatView.Model() == "" {
+		statusText += "\n" + colors.Error + "● No Ollama models available" + colors.Text + " - pull one and select it in Models (3)"
 	}
 	dv.status.SetText(statusText)
 
@@ -170,18 +184,20 @@ func (dv *DashboardView) render() {
 	}
 
 	// Update stats
-	statsText := fmt.Sprintf(`Documents: [yellow]%d/%d[white] processed
-Chunks: [yellow]%d[white]
-Words: [yellow]%s[white]
-Images: [yellow]%d[white]
-Pages: [yellow]%d[white] total, [yellow]%d[white] with images`,
-		dv.statsData.ProcessedDocuments,
-		dv.statsData.TotalDocuments,
-		dv.statsData.TotalChunks,
-		formatNumber(dv.statsData.TotalWords),
-		dv.statsData.TotalImages,
-		dv.statsData.TotalPages,
-		dv.statsData.PagesWithImages,
+	statsText := fmt.Sprintf(`%s: %s
+Documents: %s processed
+Chunks: %s
+Words: %s
+Images: %s
+Pages: %s total, %s with images`,
+		dv.app.cfg.Display.CorpusLabel,
+		emphasis(corpusName(dv.app.db.Schema())),
+		emphasis(fmt.Sprintf("%d/%d", dv.statsData.ProcessedDocuments, dv.statsData.TotalDocuments)),
+		emphasis(fmt.Sprint(dv.statsData.TotalChunks)),
+		emphasis(formatNumber(dv.statsData.TotalWords)),
+		emphasis(fmt.Sprint(dv.statsData.TotalImages)),
+		emphasis(fmt.Sprint(dv.statsData.TotalPages)),
+		emphasis(fmt.Sprint(dv.statsData.PagesWithImages)),
 	)
 	dv.stats.SetText(statsText)
 }
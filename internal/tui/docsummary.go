@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/documents"
+	"github.com/dream-ai/cli/internal/ollama"
+	"github.com/dream-ai/cli/internal/rag"
+	"github.com/dream-ai/cli/internal/trace"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// summarizeSelected summarizes the selected document with the chat model
+// from an even sample of its chunks, showing the result in a scrollable
+// modal from which it can be saved
+func (dv *DocumentsView) summarizeSelected() {
+	selected := dv.list.GetCurrentItem()
+	if selected < 0 || selected >= len(dv.documents) {
+		return
+	}
+	doc := dv.documents[selected]
+	name := documents.DisplayName(doc.Title, doc.FilePath, dv.app.cfg.Display.CleanTitles)
+
+	model := dv.app.chatView.Model()
+	if model == "" {
+		dv.info.SetText(colors.Error + noModelsMessage)
+		return
+	}
+
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(dv.app.cfg.Display.Wrap).
+		SetScrollable(true).
+		SetText(fmt.Sprintf(colors.Warning+"Summarizing %s with %s...", tview.Escape(name), model))
+	view.SetBorder(true).SetTitle(fmt.Sprintf(" Summary: %s (Esc to close) ", tview.Escape(name)))
+	view.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			dv.app.hideModal()
+		}
+	})
+	dv.app.showModal("summary", view, 100, 30)
+
+	go func() {
+		timeout := dv.app.cfg.Chat.Timeout
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		ctx, _ = trace.Start(ctx)
+
+		summary, err := dv.generateSummary(ctx, doc, name, model)
+		err = ollama.CheckTimeout(ctx, err, timeout)
+		dv.app.queueUpdateDraw(func() {
+			if err != nil {
+				view.SetText(fmt.Sprintf(colors.Error+"Error: %v", trace.Error(ctx, err)))
+				return
+			}
+			view.SetText(dv.app.chatView.formatMarkdown(summary))
+			view.ScrollToBeginning()
+			view.SetTitle(fmt.Sprintf(" Summary: %s (w to save, Esc to close) ", tview.Escape(name)))
+			view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+				if event.Rune() != 'w' {
+					return event
+				}
+				path, err := saveSummary(doc, name, model, summary)
+				if err != nil {
+					view.SetTitle(fmt.Sprintf(" Summary: failed to save: %v (Esc to close) ", err))
+				} else {
+					view.SetTitle(fmt.Sprintf(" Summary: saved to %s (Esc to close) ", path))
+				}
+				return nil
+			})
+		})
+	}()
+}
+
+// generateSummary samples a document's chunks and asks model to summarize them
+func (dv *DocumentsView) generateSummary(ctx context.Context, doc *db.Document, name, model string) (string, error) {
+	chunks, err := dv.app.db.GetChunksByDocument(ctx, doc.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(chunks) == 0 {
+		return "", fmt.Errorf("%s has no text chunks; process it first", name)
+	}
+	sample := rag.SampleChunks(chunks, dv.app.cfg.RAG.SummaryTopK)
+
+	builder := dv.app.contextBuilderFor(ctx, model)
+	summary, _, err := dv.app.ollamaClient.GenerateWithStats(ctx, &ollama.GenerateRequest{
+		Model:  model,
+		System: builder.BuildDocumentSummarySystemPrompt(),
+		Prompt: builder.BuildDocumentSummaryPrompt(name, sample),
+	})
+	if errors.Is(err, ollama.ErrTruncatedResponse) && summary != "" {
+		summary += "\n\n(summary cut off: " + err.Error() + ")"
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate summary: %w", err)
+	}
+	if dv.app.cfg.Ollama.StripThinkTags {
+		summary, _ = stripThinking(summary, dv.app.cfg.Ollama.ThinkTags)
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// saveSummary writes a summary as markdown to ~/.dream-ai/summaries, named
+// after the document's file, and returns the file's path
+func saveSummary(doc *db.Document, name, model, summary string) (string, error) {
+	dir := filepath.Join(os.Getenv("HOME"), ".dream-ai", "summaries")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create summaries directory: %w", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(doc.FilePath), filepath.Ext(doc.FilePath))
+	path := filepath.Join(dir, base+".md")
+	content := fmt.Sprintf("# %s\n\n_Summary of %s by %s, %s_\n\n%s\n",
+		name, doc.FilePath, model, time.Now().Format("2006-01-02"), summary)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to save summary: %w", err)
+	}
+	return path, nil
+}
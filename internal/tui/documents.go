@@ -3,12 +3,15 @@ package tui
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/dream-ai/cli/internal/db"
+	"github.com/dream-ai/cli/internal/documents"
+	"github.com/dream-ai/cli/internal/trace"
 	"github.com/rivo/tview"
 	"github.com/gdamore/tcell/v2"
 )
@@ -20,6 +23,10 @@ type DocumentsView struct {
 	list     *tview.List
 	info     *tview.TextView
 	documents []*db.Document
+
+	sort  string // One of db.DocumentSorts
+	page  int    // Zero-based page of the list shown
+	total int    // Documents across all pages
 }
 
 // NewDocumentsView creates a new documents view
@@ -27,6 +34,10 @@ func NewDocumentsView(app *App) *DocumentsView {
 	dv := &DocumentsView{
 		app:       app,
 		documents: []*db.Document{},
+		sort:      app.cfg.Display.DocumentSort,
+	}
+	if !slices.Contains(db.DocumentSorts, dv.sort) {
+		dv.sort = db.SortByDate
 	}
 
 	// Create list for documents
@@ -38,12 +49,12 @@ func NewDocumentsView(app *App) *DocumentsView {
 		SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
 			dv.showDocumentInfo(index)
 		})
-	dv.list.SetBorder(true).SetTitle(" Documents ")
+	dv.list.SetBorder(true)
 
 	// Create info text view
 	dv.info = tview.NewTextView().
 		SetDynamicColors(true).
-		SetWrap(true)
+		SetWrap(dv.app.cfg.Display.Wrap)
 	dv.info.SetBorder(true).SetTitle(" Info ")
 
 	// Create main flex layout
@@ -57,7 +68,7 @@ func NewDocumentsView(app *App) *DocumentsView {
 		).
 		AddItem(
 			tview.NewTextView().
-				SetText("[yellow]a[white]: Add | [yellow]d[white]: Delete | [yellow]p[white]: Process | [yellow]r[white]: Reload").
+				SetText(dv.help()).
 				SetDynamicColors(true),
 			1, 0, false,
 		)
@@ -77,6 +88,24 @@ func NewDocumentsView(app *App) *DocumentsView {
 		case 'r', 'R':
 			dv.reloadDocuments()
 			return nil
+		case 't', 'T':
+			dv.editTags()
+			return nil
+		case 's', 'S':
+			dv.summarizeSelected()
+			return nil
+		case 'x', 'X':
+			dv.exportSelected()
+			return nil
+		case 'o', 'O':
+			dv.cycleSort()
+			return nil
+		case '>', '.':
+			dv.turnPage(1)
+			return nil
+		case '<', ',':
+			dv.turnPage(-1)
+			return nil
 		}
 		return event
 	})
@@ -92,22 +121,96 @@ func (dv *DocumentsView) GetPrimitive() tview.Primitive {
 	return dv.flex
 }
 
-// reloadDocuments reloads the document list
+// help returns the key help line, with the keys that change documents
+// greyed out in read-only mode
+func (dv *DocumentsView) help() string {
+	key := func(k, label string) string {
+		return colors.Emphasis + k + colors.Text + ": " + label
+	}
+	mutatingKey := func(k, label string) string {
+		if dv.app.cfg.ReadOnly {
+			return dv.app.mutating(k + ": " + label)
+		}
+		return key(k, label)
+	}
+	return strings.Join([]string{
+		mutatingKey("a", "Add"),
+		mutatingKey("d", "Delete"),
+		mutatingKey("p", "Process"),
+		mutatingKey("t", "Tags"),
+		key("s", "Summarize"),
+		key("x", "Export Text"),
+		key("o", "Sort"),
+		key("<>", "Page"),
+		key("r", "Reload"),
+	}, " | ")
+}
+
+// cycleSort switches the list to the next sort order, from its first page
+func (dv *DocumentsView) cycleSort() {
+	next := (slices.Index(db.DocumentSorts, dv.sort) + 1) % len(db.DocumentSorts)
+	dv.sort = db.DocumentSorts[next]
+	dv.page = 0
+	dv.reloadDocuments()
+}
+
+// turnPage moves the list delta pages forward or back, if there is a page
+// there
+func (dv *DocumentsView) turnPage(delta int) {
+	page := dv.page + delta
+	if page < 0 || page >= dv.pageCount() {
+		return
+	}
+	dv.page = page
+	dv.reloadDocuments()
+	dv.list.SetCurrentItem(0)
+}
+
+// pageCount returns the number of pages in the list, at least 1
+func (dv *DocumentsView) pageCount() int {
+	perPage := dv.app.cfg.Display.DocumentsPerPage
+	if perPage <= 0 || dv.total == 0 {
+		return 1
+	}
+	return (dv.total + perPage - 1) / perPage
+}
+
+// updateTitle shows the sort order, and the page when there is more than one
+func (dv *DocumentsView) updateTitle() {
+	title := fmt.Sprintf(" Documents (by %s) ", dv.sort)
+	if pages := dv.pageCount(); pages > 1 {
+		title = fmt.Sprintf(" Documents (by %s, page %d/%d) ", dv.sort, dv.page+1, pages)
+	}
+	dv.list.SetTitle(title)
+}
+
+// reloadDocuments reloads the current page of the document list
 func (dv *DocumentsView) reloadDocuments() {
 	ctx := context.Background()
-	docs, err := dv.app.db.GetAllDocuments(ctx)
+	total, err := dv.app.db.CountDocuments(ctx)
 	if err != nil {
-		dv.info.SetText(fmt.Sprintf("[red]Error loading documents: %v", err))
+		dv.info.SetText(fmt.Sprintf(colors.Error+"Error loading documents: %v", err))
+		return
+	}
+	dv.total = total
+	// Deletions can leave the page past the end
+	dv.page = min(dv.page, dv.pageCount()-1)
+
+	perPage := max(dv.app.cfg.Display.DocumentsPerPage, 0)
+	docs, err := dv.app.db.ListDocuments(ctx, dv.sort, perPage, dv.page*perPage)
+	if err != nil {
+		dv.info.SetText(fmt.Sprintf(colors.Error+"Error loading documents: %v", err))
 		return
 	}
 
 	dv.documents = docs
 	dv.list.Clear()
+	dv.updateTitle()
 
 	for i, doc := range docs {
-		status := "[red]Not processed"
+		status := colors.Error + "Not processed"
 		if doc.ProcessedAt != nil {
-			status = "[green]Processed"
+			status = colors.Success + "Processed"
 		} else if doc.ErrorMessage != nil && *doc.ErrorMessage != "" {
 			// Show error reason if available
 			errorMsg := *doc.ErrorMessage
@@ -115,34 +218,42 @@ func (dv *DocumentsView) reloadDocuments() {
 			if len(errorMsg) > 50 {
 				errorMsg = errorMsg[:47] + "..."
 			}
-			status = fmt.Sprintf("[red]Not processed: %s", errorMsg)
+			status = fmt.Sprintf(colors.Error+"%s: %s", errorLabel(*doc.ErrorMessage), errorMsg)
 		}
 		
-		fileName := filepath.Base(doc.FilePath)
-		mainText := fmt.Sprintf("%d. %s", i+1, fileName)
+		name := documents.DisplayName(doc.Title, doc.FilePath, dv.app.cfg.Display.CleanTitles)
+		mainText := fmt.Sprintf("%d. %s", dv.page*perPage+i+1, tview.Escape(name))
 		secondaryText := fmt.Sprintf("%s | %s", doc.FileType, status)
+		if len(doc.Tags) > 0 {
+			secondaryText += fmt.Sprintf(" "+colors.Text+"| "+colors.Accent+"%s", strings.Join(doc.Tags, ", "))
+		}
 		
 		dv.list.AddItem(mainText, secondaryText, 0, nil)
 	}
 
 	if len(docs) == 0 {
-		dv.info.SetText("[yellow]No documents found. Press 'a' to add documents from the configured directory.")
+		dv.info.SetText(colors.Warning + "No documents found. Press 'a' to add documents from the configured directory.")
 	} else {
 		// Show info for currently selected document
 		selected := dv.list.GetCurrentItem()
 		if selected >= 0 && selected < len(docs) {
 			dv.showDocumentInfo(selected)
 		} else {
-			dv.info.SetText(fmt.Sprintf("[white]Total: %d documents", len(docs)))
+			dv.info.SetText(fmt.Sprintf(colors.Text+"Total: %d documents", dv.total))
 		}
 	}
 }
 
 // addDocuments processes documents from all configured directories
 func (dv *DocumentsView) addDocuments() {
+	if dv.app.refuseInReadOnly(dv.info) {
+		return
+	}
 	// Run processing in a goroutine to avoid blocking UI
 	go func() {
-		ctx := context.Background()
+		defer dv.app.beginProcessing()()
+		// One request ID covers the import, so its documents log together
+		ctx, requestID := trace.Start(context.Background())
 		docDirs := dv.app.cfg.Paths.DocumentsDirs
 		if len(docDirs) == 0 {
 			// Fallback to default directory
@@ -152,41 +263,36 @@ func (dv *DocumentsView) addDocuments() {
 			}
 		}
 
-		dv.app.app.QueueUpdateDraw(func() {
-			dv.info.SetText("[yellow]Scanning directories...")
+		dv.app.queueUpdateDraw(func() {
+			dv.info.SetText(colors.Warning + "Scanning directories...")
 		})
 
 		totalProcessed := 0
 		totalErrors := 0
 		totalSkipped := 0
+		totalResumed := 0
+		totalChunks := 0
+		totalImages := 0
+		totalWarnings := 0
 		var errorFiles []string
-		var allFiles []string
 
-		// Collect all files first
-		for _, docDir := range docDirs {
-			// Expand ~ in path
-			if strings.HasPrefix(docDir, "~") {
-				homeDir := os.Getenv("HOME")
-				docDir = filepath.Join(homeDir, strings.TrimPrefix(docDir, "~"))
-			}
-
-			// Check if directory exists
-			if _, err := os.Stat(docDir); os.IsNotExist(err) {
-				continue
-			}
-
-			// Collect PDFs
-			pdfFiles, _ := filepath.Glob(filepath.Join(docDir, "*.pdf"))
-			allFiles = append(allFiles, pdfFiles...)
-
-			// Collect EPUBs
-			epubFiles, _ := filepath.Glob(filepath.Join(docDir, "*.epub"))
-			allFiles = append(allFiles, epubFiles...)
+		// Files finished by an interrupted import are skipped without
+		// rehashing, unless they have changed since
+		done, err := dv.app.db.GetImportProgress(ctx)
+		if err != nil {
+			done = map[string]bool{}
 		}
 
+		// Collect all files first
+		allFiles, warnings := documents.ScanDirectories(docDirs, dv.app.scanExclusions())
+
 		if len(allFiles) == 0 {
-			dv.app.app.QueueUpdateDraw(func() {
-				dv.info.SetText("[yellow]No documents found in configured directories")
+			dv.app.queueUpdateDraw(func() {
+				msg := colors.Warning + "No documents found in configured directories"
+				for _, warning := range warnings {
+					msg += "\n" + colors.Error + warning
+				}
+				dv.info.SetText(msg)
 				dv.reloadDocuments()
 			})
 			return
@@ -194,52 +300,74 @@ func (dv *DocumentsView) addDocuments() {
 
 		// Process files
 		for i, file := range allFiles {
+			if done[file] && dv.app.processor.ResumeUnchanged(ctx, file) {
+				totalResumed++
+				continue
+			}
 			fileName := filepath.Base(file)
-			dv.app.app.QueueUpdateDraw(func() {
-				dv.info.SetText(fmt.Sprintf("[yellow]Processing %d/%d: %s...", i+1, len(allFiles), fileName))
+			dv.app.queueUpdateDraw(func() {
+				dv.info.SetText(fmt.Sprintf(colors.Warning+"Processing %d/%d: %s...", i+1, len(allFiles), fileName))
 			})
 
-			if err := dv.processDocumentWithSuppressedWarnings(ctx, file); err != nil {
-				// Check if it's a "already processed" skip (which is not an error)
-				if strings.Contains(err.Error(), "already processed") || strings.Contains(err.Error(), "skip") {
-					totalSkipped++
-				} else {
-					totalErrors++
-					errorFiles = append(errorFiles, fmt.Sprintf("%s", fileName))
-				}
-			} else {
+			result, _ := dv.app.processor.ProcessDocumentWithRetry(ctx, file)
+			switch result.Outcome {
+			case documents.ProcessSkipped:
+				totalSkipped++
+			case documents.ProcessFailed:
+				totalErrors++
+				errorFiles = append(errorFiles, fileName)
+			default:
 				totalProcessed++
+				totalChunks += result.Chunks
+				totalImages += result.Images
+				totalWarnings += len(result.Warnings)
+			}
+			if result.Outcome != documents.ProcessFailed {
+				dv.app.db.MarkImportProgress(ctx, file)
 			}
 		}
 
+		// Completed, so the next import starts from scratch
+		dv.app.db.ClearImportProgress(ctx)
+
 		// Update UI with results
-		dv.app.app.QueueUpdateDraw(func() {
+		dv.app.queueUpdateDraw(func() {
 			dv.reloadDocuments()
 			
 			var statusMsg string
-			if totalProcessed > 0 || totalSkipped > 0 {
+			if totalProcessed > 0 || totalSkipped > 0 || totalResumed > 0 {
 				parts := []string{}
+				for _, warning := range warnings {
+					parts = append(parts, colors.Error+"Warning: "+warning)
+				}
+				if totalResumed > 0 {
+					parts = append(parts, fmt.Sprintf(colors.Warning+"Resumed: %d files already done by an interrupted import", totalResumed))
+				}
 				if totalProcessed > 0 {
-					parts = append(parts, fmt.Sprintf("[green]Processed: %d", totalProcessed))
+					parts = append(parts, fmt.Sprintf(colors.Success+"Processed: %d (%d chunks, %d images)", totalProcessed, totalChunks, totalImages))
+				}
+				if totalWarnings > 0 {
+					parts = append(parts, fmt.Sprintf(colors.Warning+"Warnings: %d (see each document's log)", totalWarnings))
 				}
 				if totalSkipped > 0 {
-					parts = append(parts, fmt.Sprintf("[yellow]Skipped (already processed): %d", totalSkipped))
+					parts = append(parts, fmt.Sprintf(colors.Warning+"Skipped (already processed): %d", totalSkipped))
 				}
 				if totalErrors > 0 {
-					parts = append(parts, fmt.Sprintf("[red]Errors: %d", totalErrors))
+					parts = append(parts, fmt.Sprintf(colors.Error+"Errors: %d", totalErrors))
 					if len(errorFiles) > 0 {
 						errorList := strings.Join(errorFiles[:min(5, len(errorFiles))], ", ")
 						if len(errorFiles) > 5 {
 							errorList += fmt.Sprintf(" (+%d more)", len(errorFiles)-5)
 						}
-						parts = append(parts, fmt.Sprintf("[red]Failed: %s", errorList))
+						parts = append(parts, fmt.Sprintf(colors.Error+"Failed: %s", errorList))
 					}
+					parts = append(parts, colors.Muted+"Request "+requestID+" in the log")
 				}
 				statusMsg = strings.Join(parts, "\n")
 			} else if totalErrors > 0 {
-				statusMsg = fmt.Sprintf("[red]Failed to process documents\nErrors: %s", strings.Join(errorFiles, ", "))
+				statusMsg = fmt.Sprintf(colors.Error+"Failed to process documents\nErrors: %s\n"+colors.Muted+"Request %s in the log", strings.Join(errorFiles, ", "), requestID)
 			} else {
-				statusMsg = "[yellow]No documents found in configured directories"
+				statusMsg = colors.Warning + "No documents found in configured directories"
 			}
 			dv.info.SetText(statusMsg)
 		})
@@ -248,6 +376,9 @@ func (dv *DocumentsView) addDocuments() {
 
 // deleteSelected deletes the selected document
 func (dv *DocumentsView) deleteSelected() {
+	if dv.app.refuseInReadOnly(dv.info) {
+		return
+	}
 	selected := dv.list.GetCurrentItem()
 	if selected < 0 || selected >= len(dv.documents) {
 		return
@@ -257,12 +388,12 @@ func (dv *DocumentsView) deleteSelected() {
 	ctx := context.Background()
 
 	if err := dv.app.db.DeleteDocument(ctx, doc.ID); err != nil {
-		dv.info.SetText(fmt.Sprintf("[red]Error deleting document: %v", err))
+		dv.info.SetText(fmt.Sprintf(colors.Error+"Error deleting document: %v", err))
 		return
 	}
 
 	dv.reloadDocuments()
-	dv.info.SetText("[green]Document deleted successfully!")
+	dv.info.SetText(colors.Success + "Document deleted successfully!")
 }
 
 // showDocumentInfo displays information about the selected document
@@ -273,91 +404,198 @@ func (dv *DocumentsView) showDocumentInfo(index int) {
 
 	doc := dv.documents[index]
 	fileName := filepath.Base(doc.FilePath)
+	name := documents.DisplayName(doc.Title, doc.FilePath, dv.app.cfg.Display.CleanTitles)
 	
 	var infoText strings.Builder
-	infoText.WriteString(fmt.Sprintf("[white]File: [yellow]%s[white]\n", fileName))
-	infoText.WriteString(fmt.Sprintf("Type: [cyan]%s[white]\n", doc.FileType))
-	infoText.WriteString(fmt.Sprintf("Path: [gray]%s[white]\n", doc.FilePath))
+	infoText.WriteString(fmt.Sprintf(colors.Text+"Title: "+colors.Emphasis+"%s"+colors.Text+"\n", tview.Escape(name)))
+	infoText.WriteString(fmt.Sprintf("File: "+colors.Emphasis+"%s"+colors.Text+"\n", fileName))
+	infoText.WriteString(fmt.Sprintf("Type: "+colors.Accent+"%s"+colors.Text+"\n", doc.FileType))
+	infoText.WriteString(fmt.Sprintf("Path: "+colors.Muted+"%s"+colors.Text+"\n", doc.FilePath))
+	if len(doc.Tags) > 0 {
+		infoText.WriteString(fmt.Sprintf("Tags: "+colors.Accent+"%s"+colors.Text+"\n", strings.Join(doc.Tags, ", ")))
+	}
+	if doc.ChunkSize > 0 {
+		infoText.WriteString(fmt.Sprintf("Chunking: "+colors.Accent+"size %d, overlap %d%%"+colors.Text+"\n", doc.ChunkSize, doc.ChunkOverlap))
+	}
+	if doc.EmbeddingModel != "" {
+		infoText.WriteString(fmt.Sprintf("Embedded with: "+colors.Accent+"%s"+colors.Text+"\n", doc.EmbeddingModel))
+	}
 	
 	if doc.ProcessedAt != nil {
-		infoText.WriteString(fmt.Sprintf("Status: [green]Processed[white]\n"))
-		infoText.WriteString(fmt.Sprintf("Processed: [gray]%s[white]", doc.ProcessedAt.Format("2006-01-02 15:04:05")))
+		infoText.WriteString("Status: " + colors.Success + "Processed" + colors.Text + "\n")
+		infoText.WriteString(fmt.Sprintf("Processed: "+colors.Muted+"%s"+colors.Text, doc.ProcessedAt.Format("2006-01-02 15:04:05")))
 	} else {
-		infoText.WriteString("Status: [red]Not processed[white]\n")
 		if doc.ErrorMessage != nil && *doc.ErrorMessage != "" {
-			infoText.WriteString(fmt.Sprintf("\n[red]Error:[white]\n%s", *doc.ErrorMessage))
+			infoText.WriteString(fmt.Sprintf("Status: "+colors.Error+"%s"+colors.Text+"\n", errorLabel(*doc.ErrorMessage)))
 		} else {
-			infoText.WriteString("\n[yellow]No error information available")
+			infoText.WriteString("Status: " + colors.Error + "Not processed" + colors.Text + "\n")
 		}
+		if doc.ErrorMessage != nil && *doc.ErrorMessage != "" {
+			infoText.WriteString(fmt.Sprintf("\n"+colors.Error+"Error:"+colors.Text+"\n%s", *doc.ErrorMessage))
+		} else {
+			infoText.WriteString("\n" + colors.Warning + "No error information available")
+		}
+	}
+	
+	if processingLog, err := dv.app.db.GetDocumentLog(context.Background(), doc.ID); err == nil && processingLog != "" {
+		infoText.WriteString(fmt.Sprintf("\n\n"+colors.Emphasis+"Processing log:"+colors.Text+"\n"+colors.Muted+"%s"+colors.Text, tview.Escape(processingLog)))
 	}
 	
-	dv.info.SetText(infoText.String())
+	dv.info.SetText(infoText.String()).ScrollToBeginning()
+}
+
+// editTags opens an input to edit the selected document's comma-separated tags
+func (dv *DocumentsView) editTags() {
+	if dv.app.refuseInReadOnly(dv.info) {
+		return
+	}
+	selected := dv.list.GetCurrentItem()
+	if selected < 0 || selected >= len(dv.documents) {
+		return
+	}
+	doc := dv.documents[selected]
+
+	input := tview.NewInputField().
+		SetLabel("Tags: ").
+		SetText(strings.Join(doc.Tags, ", "))
+	input.SetBorder(true).SetTitle(" Edit Tags (comma-separated, Enter to save, Esc to cancel) ")
+	input.SetDoneFunc(func(key tcell.Key) {
+		dv.app.hideModal()
+		if key != tcell.KeyEnter {
+			return
+		}
+
+		tags := parseTags(input.GetText())
+		if err := dv.app.db.UpdateDocumentTags(context.Background(), doc.ID, tags); err != nil {
+			dv.info.SetText(fmt.Sprintf(colors.Error+"Error updating tags: %v", err))
+			return
+		}
+		dv.reloadDocuments()
+		dv.info.SetText(fmt.Sprintf(colors.Success+"Updated tags for %s", filepath.Base(doc.FilePath)))
+	})
+
+	dv.app.showModal("tags", input, 70, 3)
+}
+
+// parseTags splits comma-separated tags, trimming blanks and duplicates
+func parseTags(text string) []string {
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
 }
 
 // processSelected processes the selected document
 func (dv *DocumentsView) processSelected() {
+	if dv.app.refuseInReadOnly(dv.info) {
+		return
+	}
 	selected := dv.list.GetCurrentItem()
 	if selected < 0 || selected >= len(dv.documents) {
 		return
 	}
 
 	doc := dv.documents[selected]
-	ctx := context.Background()
+	ctx, requestID := trace.Start(context.Background())
 
-	dv.info.SetText(fmt.Sprintf("[yellow]Processing %s...", filepath.Base(doc.FilePath)))
+	dv.info.SetText(fmt.Sprintf(colors.Warning+"Processing %s...", filepath.Base(doc.FilePath)))
 
-	if err := dv.app.processor.ProcessDocument(ctx, doc.FilePath); err != nil {
+	// Capture counts before reprocessing to report how they changed
+	chunksBefore, imagesBefore, countErr := dv.app.db.GetDocumentCounts(ctx, doc.ID)
+
+	done := dv.app.beginProcessing()
+	result, err := dv.app.processor.ReprocessDocument(ctx, doc.FilePath)
+	done()
+	if err != nil {
 		// Reload to get updated error message
 		dv.reloadDocuments()
 		// Show error in info pane
 		if doc.ErrorMessage != nil && *doc.ErrorMessage != "" {
-			dv.info.SetText(fmt.Sprintf("[red]Error: %s", *doc.ErrorMessage))
+			dv.info.SetText(fmt.Sprintf(colors.Error+"Error: %s (request %s)", *doc.ErrorMessage, requestID))
 		} else {
-			dv.info.SetText(fmt.Sprintf("[red]Error processing document: %v", err))
+			dv.info.SetText(fmt.Sprintf(colors.Error+"Error processing document: %v", trace.Error(ctx, err)))
 		}
 		return
 	}
 
 	dv.reloadDocuments()
-	dv.info.SetText("[green]Document processed successfully!")
+
+	status := colors.Success + "Document processed successfully: " + describeResult(result)
+	chunksAfter, imagesAfter, err := dv.app.db.GetDocumentCounts(ctx, doc.ID)
+	if countErr != nil || err != nil {
+		dv.info.SetText(status)
+		return
+	}
+	after, err := dv.app.db.GetDocumentByID(ctx, doc.ID)
+	if err != nil || after == nil {
+		after = doc
+	}
+	dv.info.SetText(status + "\n\n" +
+		formatReprocessDiff(doc, after, chunksBefore, chunksAfter, imagesBefore, imagesAfter))
 }
 
-// processDocumentWithSuppressedWarnings processes a document while suppressing PDF library warnings
-func (dv *DocumentsView) processDocumentWithSuppressedWarnings(ctx context.Context, filePath string) error {
-	// Save original stderr
-	originalStderr := os.Stderr
-	defer func() {
-		os.Stderr = originalStderr
-	}()
-	
-	// Create a pipe to capture stderr
-	r, w, err := os.Pipe()
-	if err != nil {
-		// If pipe creation fails, just process normally
-		return dv.app.processor.ProcessDocument(ctx, filePath)
+// describeResult summarizes a successful processing run in one line
+func describeResult(result *documents.ProcessResult) string {
+	summary := fmt.Sprintf("%s, %d chunks, %d images in %s",
+		result.Outcome, result.Chunks, result.Images, result.Duration.Round(time.Millisecond))
+	if len(result.Warnings) > 0 {
+		summary += fmt.Sprintf(colors.Warning+" (%d warnings, see the document's log)", len(result.Warnings))
 	}
-	
-	// Redirect stderr to the pipe
-	os.Stderr = w
-	
-	// Process document
-	done := make(chan error, 1)
-	go func() {
-		err := dv.app.processor.ProcessDocument(ctx, filePath)
-		w.Close()
-		done <- err
-	}()
-	
-	// Read and discard stderr output in background
-	go func() {
-		io.Copy(io.Discard, r)
-		r.Close()
-	}()
-	
-	// Wait for processing to complete
-	err = <-done
-	
-	return err
+	return summary
+}
+
+// formatReprocessDiff describes how a document's chunk and image counts
+// changed on reprocessing, along with any change in chunk settings or
+// embedding model
+func formatReprocessDiff(before, after *db.Document, chunksBefore, chunksAfter, imagesBefore, imagesAfter int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(colors.Text+"Chunks: %s\n", formatCountChange(chunksBefore, chunksAfter)))
+	b.WriteString(fmt.Sprintf(colors.Text+"Images: %s\n", formatCountChange(imagesBefore, imagesAfter)))
+
+	// Documents chunked before settings were recorded have 0 for both
+	if before.ChunkSize > 0 && before.ChunkSize != after.ChunkSize {
+		b.WriteString(fmt.Sprintf(colors.Text+"after chunk_size "+colors.Accent+"%d → %d"+colors.Text+"\n", before.ChunkSize, after.ChunkSize))
+	}
+	if before.ChunkSize > 0 && before.ChunkOverlap != after.ChunkOverlap {
+		b.WriteString(fmt.Sprintf(colors.Text+"after chunk_overlap_percent "+colors.Accent+"%d%% → %d%%"+colors.Text+"\n", before.ChunkOverlap, after.ChunkOverlap))
+	}
+	if before.EmbeddingModel != "" && before.EmbeddingModel != after.EmbeddingModel {
+		b.WriteString(fmt.Sprintf(colors.Text+"after embedding model "+colors.Accent+"%s → %s"+colors.Text+"\n", before.EmbeddingModel, after.EmbeddingModel))
+	}
+	return b.String()
+}
+
+// formatCountChange renders "old → new (+delta)", colored by direction
+func formatCountChange(before, after int) string {
+	switch {
+	case after > before:
+		return fmt.Sprintf("%d → %d "+colors.Success+"(+%d)"+colors.Text, before, after, after-before)
+	case after < before:
+		return fmt.Sprintf("%d → %d "+colors.Error+"(-%d)"+colors.Text, before, after, before-after)
+	}
+	return fmt.Sprintf("%d → %d "+colors.Muted+"(unchanged)"+colors.Text, before, after)
+}
+
+// errorLabel returns a short status label for a stored document error
+func errorLabel(errorMsg string) string {
+	switch {
+	case strings.HasPrefix(errorMsg, documents.ErrEmptyFile.Error()):
+		return "Empty file"
+	case strings.HasPrefix(errorMsg, documents.ErrCorruptFile.Error()):
+		return "Corrupt file"
+	case strings.HasPrefix(errorMsg, documents.ErrPasswordProtected.Error()):
+		return "Password protected"
+	case strings.HasPrefix(errorMsg, documents.ErrUnsupportedType.Error()):
+		return "Unsupported format"
+	}
+	return "Not processed"
 }
 
 // min returns the minimum of two integers
@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dream-ai/cli/internal/documents"
+)
+
+// exportSelected re-parses the selected document and writes its extracted,
+// normalized text to a .txt file in the export directory, for checking
+// extraction quality or use in other tools
+func (dv *DocumentsView) exportSelected() {
+	selected := dv.list.GetCurrentItem()
+	if selected < 0 || selected >= len(dv.documents) {
+		return
+	}
+	doc := dv.documents[selected]
+	dv.info.SetText(fmt.Sprintf(colors.Warning+"Extracting text from %s...", filepath.Base(doc.FilePath)))
+
+	go func() {
+		path, err := dv.exportText(doc.FileType, doc.FilePath)
+		dv.app.queueUpdateDraw(func() {
+			if err != nil {
+				dv.info.SetText(fmt.Sprintf(colors.Error+"Error: %v", err))
+				return
+			}
+			dv.info.SetText(fmt.Sprintf(colors.Success+"Extracted text saved to %s", path))
+		})
+	}()
+}
+
+// exportText writes the extracted text of a document to the export
+// directory and returns the file's path
+func (dv *DocumentsView) exportText(fileType, filePath string) (string, error) {
+	text, err := dv.app.processor.ExtractText(fileType, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	dir := documents.ExpandHome(dv.app.cfg.Paths.ExportDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+	base := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	path := filepath.Join(dir, base+".txt")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("failed to save extracted text: %w", err)
+	}
+	return path, nil
+}
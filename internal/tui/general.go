@@ -0,0 +1,28 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/dream-ai/cli/internal/rag"
+)
+
+// generalLabel introduces each part of an answer drawn from general knowledge
+const generalLabel = "(general knowledge) "
+
+// formatAnswer renders an answer's markdown, dimming the parts the model
+// marked as general knowledge so that they stand apart from those resting on
+// the retrieved sources
+func (cv *ChatView) formatAnswer(text string) string {
+	var b strings.Builder
+	for _, part := range rag.SplitGeneralKnowledge(text) {
+		formatted := cv.formatMarkdown(part.Text)
+		if !part.General {
+			b.WriteString(formatted)
+			continue
+		}
+		// Headings and bold text return to the body color; keep it dim
+		formatted = strings.ReplaceAll(formatted, colors.Text, colors.Muted)
+		b.WriteString(colors.Muted + generalLabel + strings.TrimSpace(formatted) + colors.Text)
+	}
+	return b.String()
+}
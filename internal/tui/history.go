@@ -0,0 +1,253 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dream-ai/cli/internal/db"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// dateLayout is how dates are typed into the History view's range filter
+const dateLayout = "2006-01-02"
+
+// HistoryView lists past conversations a page at a time, optionally limited
+// to a range of dates
+type HistoryView struct {
+	app           *App
+	flex          *tview.Flex
+	list          *tview.List
+	info          *tview.TextView
+	conversations []*db.Conversation
+
+	dates string               // Range filter as typed, "" for all dates
+	r     db.ConversationRange // Parsed from dates
+	page  int                  // Zero-based page of the list shown
+	total int                  // Conversations in the range across all pages
+}
+
+// NewHistoryView creates a new conversation history view
+func NewHistoryView(app *App) *HistoryView {
+	hv := &HistoryView{app: app}
+
+	hv.list = tview.NewList().
+		ShowSecondaryText(true).
+		SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+			hv.showConversation(index)
+		})
+	hv.list.SetBorder(true)
+
+	hv.info = tview.NewTextView().
+		SetDynamicColors(true).
+		SetWrap(app.cfg.Display.Wrap)
+	hv.info.SetBorder(true).SetTitle(" Conversation ")
+
+	key := func(k, label string) string {
+		return colors.Emphasis + k + colors.Text + ": " + label
+	}
+	hv.flex = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(
+			tview.NewFlex().
+				AddItem(hv.list, 0, 1, true).
+				AddItem(hv.info, 0, 2, false),
+			0, 1, true,
+		).
+		AddItem(
+			tview.NewTextView().
+				SetText(strings.Join([]string{
+					key("f", "Filter Dates"),
+					key("<>", "Page"),
+					key("r", "Reload"),
+				}, " | ")).
+				SetDynamicColors(true),
+			1, 0, false,
+		)
+
+	hv.list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'f', 'F':
+			hv.editDates()
+			return nil
+		case 'r', 'R':
+			hv.reload()
+			return nil
+		case '>', '.':
+			hv.turnPage(1)
+			return nil
+		case '<', ',':
+			hv.turnPage(-1)
+			return nil
+		}
+		return event
+	})
+
+	return hv
+}
+
+// GetPrimitive returns the tview primitive
+func (hv *HistoryView) GetPrimitive() tview.Primitive {
+	return hv.flex
+}
+
+// turnPage moves the list delta pages forward or back, if there is a page
+// there
+func (hv *HistoryView) turnPage(delta int) {
+	page := hv.page + delta
+	if page < 0 || page >= hv.pageCount() {
+		return
+	}
+	hv.page = page
+	hv.reload()
+	hv.list.SetCurrentItem(0)
+}
+
+// pageCount returns the number of pages in the list, at least 1
+func (hv *HistoryView) pageCount() int {
+	perPage := hv.app.cfg.Display.HistoryPerPage
+	if perPage <= 0 || hv.total == 0 {
+		return 1
+	}
+	return (hv.total + perPage - 1) / perPage
+}
+
+// updateTitle shows the date filter, and the page when there is more than one
+func (hv *HistoryView) updateTitle() {
+	title := " History"
+	if hv.dates != "" {
+		title += " (" + hv.dates + ")"
+	}
+	if pages := hv.pageCount(); pages > 1 {
+		title += fmt.Sprintf(" page %d/%d", hv.page+1, pages)
+	}
+	hv.list.SetTitle(tview.Escape(title + " "))
+}
+
+// reload reloads the current page of conversations
+func (hv *HistoryView) reload() {
+	ctx := context.Background()
+	total, err := hv.app.db.CountConversations(ctx, hv.r)
+	if err != nil {
+		hv.info.SetText(fmt.Sprintf(colors.Error+"Error loading history: %v", err))
+		return
+	}
+	hv.total = total
+	// New conversations can move the last page
+	hv.page = min(hv.page, hv.pageCount()-1)
+
+	perPage := max(hv.app.cfg.Display.HistoryPerPage, 0)
+	convs, err := hv.app.db.GetRecentConversations(ctx, hv.r, perPage, hv.page*perPage)
+	if err != nil {
+		hv.info.SetText(fmt.Sprintf(colors.Error+"Error loading history: %v", err))
+		return
+	}
+
+	hv.conversations = convs
+	hv.list.Clear()
+	hv.updateTitle()
+	for i, conv := range convs {
+		question, _, _ := strings.Cut(strings.TrimSpace(conv.UserMessage), "\n")
+		mainText := fmt.Sprintf("%d. %s", hv.page*perPage+i+1, tview.Escape(question))
+		secondaryText := fmt.Sprintf("%s | %s", conv.CreatedAt.Format("2006-01-02 15:04"), conv.ModelName)
+		hv.list.AddItem(mainText, secondaryText, 0, nil)
+	}
+
+	switch {
+	case len(convs) > 0:
+		hv.showConversation(hv.list.GetCurrentItem())
+	case hv.dates != "":
+		hv.info.SetText(colors.Warning + "No conversations in this range. Press 'f' to change it.")
+	default:
+		hv.info.SetText(colors.Warning + "No conversations yet. Questions answered in the chat are listed here.")
+	}
+}
+
+// showConversation shows the question and answer of the conversation at
+// index in the current page
+func (hv *HistoryView) showConversation(index int) {
+	if index < 0 || index >= len(hv.conversations) {
+		return
+	}
+	conv := hv.conversations[index]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, colors.Muted+"%s | %s | %d passages, %d images\n\n",
+		conv.CreatedAt.Format("2006-01-02 15:04:05"), conv.ModelName,
+		len(conv.ContextChunkIDs), len(conv.ContextImageIDs))
+	fmt.Fprintf(&b, colors.Accent+"You:"+colors.Text+" %s\n\n", tview.Escape(conv.UserMessage))
+	// Rendered as the chat renders answers, general knowledge markers and all
+	fmt.Fprintf(&b, colors.Success+"Assistant:"+colors.Text+" %s", hv.app.chatView.formatAnswer(tview.Escape(conv.AssistantMessage)))
+	hv.info.SetText(b.String()).ScrollToBeginning()
+}
+
+// editDates asks for the range of dates to list, as FROM..TO, where either
+// end may be left out, or a single day
+func (hv *HistoryView) editDates() {
+	input := tview.NewInputField().
+		SetLabel("Dates: ").
+		SetPlaceholder("YYYY-MM-DD..YYYY-MM-DD").
+		SetText(hv.dates)
+	input.SetBorder(true).SetTitle(" Filter by Date (FROM..TO, either optional; empty for all) ")
+	input.SetDoneFunc(func(key tcell.Key) {
+		hv.app.hideModal()
+		if key != tcell.KeyEnter {
+			return
+		}
+
+		dates := strings.TrimSpace(input.GetText())
+		r, err := parseDateRange(dates)
+		if err != nil {
+			hv.info.SetText(fmt.Sprintf(colors.Error+"%v", err))
+			return
+		}
+		hv.dates, hv.r, hv.page = dates, r, 0
+		hv.reload()
+		hv.list.SetCurrentItem(0)
+	})
+
+	hv.app.showModal("history-dates", input, 70, 3)
+}
+
+// parseDateRange parses FROM..TO, either end of which may be left out, or a
+// single date, into a range including both days, in local time. "" is all
+// dates.
+func parseDateRange(text string) (db.ConversationRange, error) {
+	var r db.ConversationRange
+	if text == "" {
+		return r, nil
+	}
+	from, to, isRange := strings.Cut(text, "..")
+	if !isRange {
+		to = from
+	}
+
+	parse := func(s string) (time.Time, error) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return time.Time{}, nil
+		}
+		t, err := time.ParseInLocation(dateLayout, s, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+		}
+		return t, nil
+	}
+	var err error
+	if r.Since, err = parse(from); err != nil {
+		return r, err
+	}
+	if r.Until, err = parse(to); err != nil {
+		return r, err
+	}
+	if !r.Until.IsZero() {
+		// Until is exclusive; the last day is included
+		r.Until = r.Until.AddDate(0, 0, 1)
+	}
+	if !r.Since.IsZero() && !r.Until.IsZero() && !r.Since.Before(r.Until) {
+		return r, fmt.Errorf("invalid date range %q: %s is after %s", text, strings.TrimSpace(from), strings.TrimSpace(to))
+	}
+	return r, nil
+}
@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dream-ai/cli/internal/documents"
+)
+
+// sessionMetrics accumulates query timings for the session's metrics record
+type sessionMetrics struct {
+	mu          sync.Mutex
+	started     time.Time
+	queries     int           // Answered questions
+	cacheHits   int           // Answers served from the answer cache
+	generations int           // Answers generated by the model
+	retrieval   time.Duration // Total retrieval time over all queries
+	generation  time.Duration // Total generation time over generated answers
+}
+
+// recordQuery adds an answered question to the metrics. generation is
+// ignored for answers served from the cache.
+func (m *sessionMetrics) recordQuery(retrieval, generation time.Duration, cached bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries++
+	m.retrieval += retrieval
+	if cached {
+		m.cacheHits++
+		return
+	}
+	m.generations++
+	m.generation += generation
+}
+
+// metricsRecord is one session's line in the metrics file
+type metricsRecord struct {
+	Started            time.Time `json:"started"`
+	Ended              time.Time `json:"ended"`
+	Queries            int       `json:"queries"`
+	CacheHits          int       `json:"cache_hits"`
+	CacheHitRate       float64   `json:"cache_hit_rate"`
+	AvgRetrievalMs     float64   `json:"avg_retrieval_ms"`
+	AvgGenerationMs    float64   `json:"avg_generation_ms"`
+	PromptTokens       int       `json:"prompt_tokens"`
+	GeneratedTokens    int       `json:"generated_tokens"`
+	TokensPerSecond    float64   `json:"tokens_per_second"`
+	DocumentsProcessed int       `json:"documents_processed"`
+
+	// The corpus and settings in effect, to relate changes in latency to them
+	Chunks              int    `json:"chunks"`
+	TextModel           string `json:"text_model"`
+	ChatModel           string `json:"chat_model"`
+	TopK                int    `json:"top_k"`
+	ChunkSize           int    `json:"chunk_size"`
+	ChunkOverlapPercent int    `json:"chunk_overlap_percent"`
+}
+
+// WriteMetrics appends a record of the session to the metrics file as one
+// JSON object per line. It is meant to be called after Run returns.
+func (a *App) WriteMetrics() error {
+	a.metrics.mu.Lock()
+	m := metricsRecord{
+		Started:   a.metrics.started,
+		Ended:     time.Now(),
+		Queries:   a.metrics.queries,
+		CacheHits: a.metrics.cacheHits,
+	}
+	if a.metrics.queries > 0 {
+		m.CacheHitRate = float64(a.metrics.cacheHits) / float64(a.metrics.queries)
+		m.AvgRetrievalMs = milliseconds(a.metrics.retrieval) / float64(a.metrics.queries)
+	}
+	if a.metrics.generations > 0 {
+		m.AvgGenerationMs = milliseconds(a.metrics.generation) / float64(a.metrics.generations)
+	}
+	a.metrics.mu.Unlock()
+
+	usage := a.ollamaClient.Usage()
+	m.PromptTokens = usage.PromptTokens
+	m.GeneratedTokens = usage.GeneratedTokens
+	m.TokensPerSecond = usage.TokensPerSecond()
+	m.DocumentsProcessed = a.processor.Processed()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if chunks, _, _, _, _, err := a.db.GetStats(ctx); err == nil {
+		m.Chunks = chunks
+	}
+	m.TextModel = a.textEmb.Model()
+	m.ChatModel = a.chatView.Model()
+	m.TopK = a.cfg.Processing.TopK
+	m.ChunkSize = a.cfg.Processing.ChunkSize
+	m.ChunkOverlapPercent = a.cfg.Processing.ChunkOverlapPercent
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	path := documents.ExpandHome(a.cfg.Metrics.File)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return f.Close()
+}
+
+// milliseconds returns d in fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
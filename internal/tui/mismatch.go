@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dream-ai/cli/internal/trace"
+	"github.com/rivo/tview"
+)
+
+// Actions list entries the dimension mismatch notice leads to
+const (
+	actionReprocessAll      = 0
+	actionRebuildEmbeddings = 6
+)
+
+// noticeDimensionMismatch explains, once per session and kind, that a search
+// failed because the stored embeddings differ in dimension from the query's,
+// and offers the action that fixes it: Rebuild Embeddings for chunks, or
+// reprocessing for images. With embeddings.on_dimension_mismatch set to
+// "rebuild", a chunk mismatch starts the rebuild straight away. Must be
+// called from the UI goroutine.
+func (a *App) noticeDimensionMismatch(images bool) {
+	noticed := &a.chunkMismatchNoticed
+	if images {
+		noticed = &a.imageMismatchNoticed
+	}
+	if *noticed {
+		return
+	}
+	*noticed = true
+
+	if !images && a.cfg.Embeddings.OnDimensionMismatch == "rebuild" && !a.cfg.ReadOnly {
+		ctx, _ := trace.Start(context.Background())
+		a.actionsView.rebuildEmbeddings(ctx)
+		a.pages.SwitchToPage("actions")
+		return
+	}
+	if a.modal != "" {
+		return
+	}
+
+	var text string
+	var buttons []string
+	action := actionRebuildEmbeddings
+	if images {
+		text = "Stored image embeddings have a different dimension from this question's, so image search is skipped.\n\nReprocess the documents to embed their images again."
+		buttons = []string{"Open Actions", "Dismiss"}
+		action = actionReprocessAll
+	} else {
+		text = fmt.Sprintf("Stored embeddings have a different dimension from those of %s (%d), so searches cannot match them.\n\nRebuild Embeddings re-embeds every chunk with the current model.",
+			a.textEmb.Model(), a.cfg.Embeddings.Dimension)
+		buttons = []string{"Rebuild Now", "Open Actions", "Dismiss"}
+		if a.cfg.ReadOnly {
+			buttons = []string{"Dismiss"}
+		}
+	}
+
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(_ int, label string) {
+			a.hideModal()
+			switch label {
+			case "Rebuild Now":
+				ctx, _ := trace.Start(context.Background())
+				a.actionsView.list.SetCurrentItem(action)
+				a.actionsView.rebuildEmbeddings(ctx)
+				a.pages.SwitchToPage("actions")
+			case "Open Actions":
+				a.actionsView.list.SetCurrentItem(action)
+				a.pages.SwitchToPage("actions")
+			}
+		})
+
+	a.showModal("dimension-mismatch", modal, 80, 11)
+}
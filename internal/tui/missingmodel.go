@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+)
+
+// missingModelMessage is shown in place of an answer when Ollama no longer
+// has the model it was asked for
+func missingModelMessage(model string) string {
+	return fmt.Sprintf("Model %s is no longer available in Ollama - pull it again (ollama pull %s) or select another in the Models view (press 3)", model, model)
+}
+
+// showMissingModel offers to switch the chat from a model that has been
+// removed from Ollama to replacement, the best model still installed, or to
+// open the Models view. replacement is empty when no models are left. Must be
+// called from the UI goroutine.
+func (a *App) showMissingModel(model, replacement string) {
+	if a.modal != "" || a.chatView.Model() != model {
+		return
+	}
+
+	text := fmt.Sprintf("Model %s is no longer available in Ollama.", model)
+	var buttons []string
+	if replacement != "" {
+		text += fmt.Sprintf("\n\nSwitch to %s?", replacement)
+		buttons = append(buttons, "Use "+replacement)
+	} else {
+		text += "\n\nNo other models are installed; pull one with ollama pull."
+	}
+	buttons = append(buttons, "Open Models", "Cancel")
+
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(_ int, label string) {
+			a.hideModal()
+			switch label {
+			case "Use " + replacement:
+				a.modelsView.useModel(replacement)
+				a.chatView.mu.Lock()
+				a.chatView.messagesData = append(a.chatView.messagesData, Message{
+					Role:    "assistant",
+					Content: colors.Success + "Switched to model " + replacement + " - send your question again",
+				})
+				a.chatView.mu.Unlock()
+				a.chatView.renderMessages()
+			case "Open Models":
+				a.modelsView.reloadModels()
+				a.pages.SwitchToPage("models")
+			}
+		})
+
+	a.showModal("missing-model", modal, 70, 9)
+}
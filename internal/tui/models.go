@@ -37,7 +37,7 @@ func NewModelsView(app *App, currentModel string) *ModelsView {
 	// Create info text view
 	mv.info = tview.NewTextView().
 		SetDynamicColors(true).
-		SetWrap(true)
+		SetWrap(mv.app.cfg.Display.Wrap)
 	mv.info.SetBorder(true).SetTitle(" Model Info ")
 
 	// Create main flex layout
@@ -61,7 +61,7 @@ func (mv *ModelsView) reloadModels() {
 	ctx := context.Background()
 	models, err := mv.app.modelSelector.ListModels(ctx)
 	if err != nil {
-		mv.info.SetText(fmt.Sprintf("[red]Error loading models: %v", err))
+		mv.info.SetText(fmt.Sprintf(colors.Error+"Error loading models: %v", err))
 		return
 	}
 
@@ -71,7 +71,7 @@ func (mv *ModelsView) reloadModels() {
 	for i, model := range models {
 		isCurrent := ""
 		if model.Name == mv.current {
-			isCurrent = " [green]← Current"
+			isCurrent = " " + colors.Success + "← Current"
 		}
 		
 		mainText := fmt.Sprintf("%d. %s%s", i+1, model.Name, isCurrent)
@@ -81,9 +81,9 @@ func (mv *ModelsView) reloadModels() {
 	}
 
 	if len(models) == 0 {
-		mv.info.SetText("[yellow]No models found. Make sure Ollama is running.")
+		mv.info.SetText(colors.Warning + "No models found. Make sure Ollama is running and pull a model, e.g. ollama pull llama3.2")
 	} else {
-		mv.info.SetText(fmt.Sprintf("[white]Total: %d models available", len(models)))
+		mv.info.SetText(fmt.Sprintf(colors.Text+"Total: %d models available", len(models)))
 	}
 }
 
@@ -93,12 +93,16 @@ func (mv *ModelsView) selectModel(index int) {
 		return
 	}
 
-	model := mv.models[index]
-	mv.current = model.Name
-	mv.app.chatView.model = model.Name
-	
+	mv.useModel(mv.models[index].Name)
+}
+
+// useModel makes name the chat model and marks it current in the list
+func (mv *ModelsView) useModel(name string) {
+	mv.current = name
+	mv.app.chatView.SetModel(name)
+
 	mv.reloadModels()
-	mv.info.SetText(fmt.Sprintf("[green]Selected model: %s", model.Name))
+	mv.info.SetText(fmt.Sprintf(colors.Success+"Selected model: %s", name))
 }
 
 // formatModelSize formats model size
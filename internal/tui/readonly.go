@@ -0,0 +1,26 @@
+package tui
+
+import "github.com/rivo/tview"
+
+// readOnlyMessage is shown when an action that would change the index or
+// settings is tried in read-only mode
+const readOnlyMessage = "Read-only mode: documents, the index and settings cannot be changed (started with -readonly or read_only: true)"
+
+// refuseInReadOnly shows readOnlyMessage in info and returns true in
+// read-only mode, for actions that change documents, the index or settings
+func (a *App) refuseInReadOnly(info *tview.TextView) bool {
+	if !a.cfg.ReadOnly {
+		return false
+	}
+	info.SetText(colors.Warning + readOnlyMessage)
+	return true
+}
+
+// mutating greys out label, a menu entry or key for an action that changes
+// documents, the index or settings, in read-only mode
+func (a *App) mutating(label string) string {
+	if !a.cfg.ReadOnly {
+		return label
+	}
+	return colors.Muted + label + colors.Text
+}
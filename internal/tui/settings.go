@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/dream-ai/cli/config"
 	"github.com/rivo/tview"
 )
 
@@ -16,6 +18,12 @@ type SettingsView struct {
 	form     *tview.Form
 	text     *tview.TextView
 	docDirs  []string
+
+	// Form values of the numeric settings, validated on save
+	chunkSize        string
+	chunkOverlap     string
+	topK             string
+	maxContextLength string
 }
 
 // NewSettingsView creates a new settings view
@@ -25,34 +33,17 @@ func NewSettingsView(app *App) *SettingsView {
 		docDirs: make([]string, len(app.cfg.Paths.DocumentsDirs)),
 	}
 	copy(sv.docDirs, app.cfg.Paths.DocumentsDirs)
+	sv.loadNumbers(app.cfg)
 
-	// Create form for editing document directories
-	sv.form = tview.NewForm().
-		AddTextView("Document Directories", "Configure where to look for documents:", 0, 1, false, false).
-		AddInputField("Directory 1", sv.getDocDir(0), 0, nil, func(text string) {
-			sv.setDocDir(0, text)
-		}).
-		AddInputField("Directory 2", sv.getDocDir(1), 0, nil, func(text string) {
-			sv.setDocDir(1, text)
-		}).
-		AddInputField("Directory 3", sv.getDocDir(2), 0, nil, func(text string) {
-			sv.setDocDir(2, text)
-		}).
-		AddButton("Add Directory", func() {
-			sv.addDocDir()
-		}).
-		AddButton("Save", func() {
-			sv.saveSettings()
-		}).
-		AddButton("Reset to Defaults", func() {
-			sv.resetToDefaults()
-		})
-	sv.form.SetBorder(true).SetTitle(" Document Directories ")
+	// Create form for editing document directories and retrieval settings
+	sv.form = tview.NewForm()
+	sv.form.SetBorder(true).SetTitle(" Edit Settings ")
+	sv.rebuildForm()
 
 	// Create info text view
 	sv.text = tview.NewTextView().
 		SetDynamicColors(true).
-		SetWrap(true)
+		SetWrap(sv.app.cfg.Display.Wrap)
 	sv.text.SetBorder(true).SetTitle(" Current Settings ")
 
 	// Create main flex layout
@@ -104,8 +95,59 @@ func (sv *SettingsView) addDocDir() {
 	sv.rebuildForm()
 }
 
-// saveSettings saves the settings
+// removeDocDir removes the directory field at index, keeping at least one field
+func (sv *SettingsView) removeDocDir(index int) {
+	if index < 0 || index >= len(sv.docDirs) {
+		return
+	}
+	sv.docDirs = append(sv.docDirs[:index], sv.docDirs[index+1:]...)
+	sv.rebuildForm()
+}
+
+// loadNumbers fills the numeric form values from cfg
+func (sv *SettingsView) loadNumbers(cfg *config.Config) {
+	sv.chunkSize = strconv.Itoa(cfg.Processing.ChunkSize)
+	sv.chunkOverlap = strconv.Itoa(cfg.Processing.ChunkOverlapPercent)
+	sv.topK = strconv.Itoa(cfg.Processing.TopK)
+	sv.maxContextLength = strconv.Itoa(cfg.RAG.MaxContextLength)
+}
+
+// parseSetting parses a numeric form value, which must be within min-max
+func parseSetting(name, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a whole number from %d to %d", name, min, max)
+	}
+	return n, nil
+}
+
+// saveSettings validates the form, applies it to the running app and saves
+// it to the config file
 func (sv *SettingsView) saveSettings() {
+	if sv.app.refuseInReadOnly(sv.text) {
+		return
+	}
+	chunkSize, err := parseSetting("Chunk Size", sv.chunkSize, 1, 100000)
+	if err != nil {
+		sv.text.SetText(colors.Error + err.Error())
+		return
+	}
+	chunkOverlap, err := parseSetting("Chunk Overlap", sv.chunkOverlap, 0, 90)
+	if err != nil {
+		sv.text.SetText(colors.Error + err.Error())
+		return
+	}
+	topK, err := parseSetting("Top K", sv.topK, 1, 100)
+	if err != nil {
+		sv.text.SetText(colors.Error + err.Error())
+		return
+	}
+	maxContextLength, err := parseSetting("Max Context Length", sv.maxContextLength, 1, 1000000)
+	if err != nil {
+		sv.text.SetText(colors.Error + err.Error())
+		return
+	}
+
 	// Filter out empty directories
 	filtered := []string{}
 	for _, dir := range sv.docDirs {
@@ -122,49 +164,108 @@ func (sv *SettingsView) saveSettings() {
 		}
 	}
 
-	sv.app.cfg.Paths.DocumentsDirs = filtered
+	cfg := sv.app.cfg
+	chunkingChanged := chunkSize != cfg.Processing.ChunkSize || chunkOverlap != cfg.Processing.ChunkOverlapPercent
+	// A document chunked partly before the change would be recorded with
+	// the wrong chunking
+	if chunkingChanged && sv.app.processing.Load() > 0 {
+		sv.text.SetText(colors.Warning + "Wait for processing to finish before changing the chunking")
+		return
+	}
+	cfg.Paths.DocumentsDirs = filtered
+	cfg.Processing.ChunkSize = chunkSize
+	cfg.Processing.ChunkOverlapPercent = chunkOverlap
+	cfg.Processing.TopK = topK
+	cfg.RAG.MaxContextLength = maxContextLength
+
+	// Take effect for the next document processed and question asked
+	sv.app.processor.SetChunking(chunkSize, chunkOverlap)
+	sv.app.retriever.SetTopK(topK)
+	sv.app.contextBuilder.SetMaxTokens(maxContextLength)
+	if cfg.RAG.AdaptiveTopK {
+		sv.app.retriever.SetTokenBudget(maxContextLength, cfg.RAG.MaxTopK)
+	}
 
-	// Save to config file
-	if err := sv.app.cfg.Save(); err != nil {
-		sv.text.SetText(fmt.Sprintf("[red]Error saving settings: %v", err))
+	// Save to config file, changing only the fields edited here
+	err = config.Update(func(c *config.Config) {
+		c.Paths.DocumentsDirs = filtered
+		c.Processing.ChunkSize = chunkSize
+		c.Processing.ChunkOverlapPercent = chunkOverlap
+		c.Processing.TopK = topK
+		c.RAG.MaxContextLength = maxContextLength
+	})
+	if err != nil {
+		sv.text.SetText(fmt.Sprintf(colors.Error+"Error saving settings: %v", err))
 		return
 	}
 
-	sv.text.SetText("[green]Settings saved successfully!")
 	sv.render()
+	if chunkingChanged {
+		sv.text.SetText(colors.Success + "Settings saved successfully!\n" + colors.Warning +
+			"Chunk settings apply to documents processed from now on; reprocess existing ones (Actions) to rechunk them.\n\n" +
+			colors.Text + sv.text.GetText(false))
+	}
 }
 
-// resetToDefaults resets to default directories
+// resetToDefaults resets the form to the default directories and values
 func (sv *SettingsView) resetToDefaults() {
 	homeDir := os.Getenv("HOME")
 	sv.docDirs = []string{
 		filepath.Join(homeDir, ".config", "dream-ai", "documents"),
 	}
+	sv.loadNumbers(config.Default())
 	sv.rebuildForm()
-	sv.text.SetText("[yellow]Reset to defaults. Press Save to apply.")
+	sv.text.SetText(colors.Warning + "Reset to defaults. Press Save to apply.")
 }
 
-// rebuildForm rebuilds the form with current directories
+// rebuildForm rebuilds the form with one field per configured directory
 func (sv *SettingsView) rebuildForm() {
+	if len(sv.docDirs) == 0 {
+		sv.docDirs = []string{""}
+	}
+
 	sv.form.Clear(true)
 	sv.form.AddTextView("Document Directories", "Configure where to look for documents:", 0, 1, false, false)
 	
-	for i := 0; i < len(sv.docDirs) || i < 3; i++ {
+	for i := range sv.docDirs {
 		idx := i
 		sv.form.AddInputField(fmt.Sprintf("Directory %d", i+1), sv.getDocDir(i), 0, nil, func(text string) {
 			sv.setDocDir(idx, text)
 		})
 	}
 	
+	sv.form.AddTextView("Retrieval and Chunking", "Chunk changes apply to newly processed documents:", 0, 1, false, false)
+	sv.form.AddInputField("Chunk Size", sv.chunkSize, 0, tview.InputFieldInteger, func(text string) {
+		sv.chunkSize = text
+	})
+	sv.form.AddInputField("Chunk Overlap % (0-90)", sv.chunkOverlap, 0, tview.InputFieldInteger, func(text string) {
+		sv.chunkOverlap = text
+	})
+	sv.form.AddInputField("Top K", sv.topK, 0, tview.InputFieldInteger, func(text string) {
+		sv.topK = text
+	})
+	sv.form.AddInputField("Max Context Length", sv.maxContextLength, 0, tview.InputFieldInteger, func(text string) {
+		sv.maxContextLength = text
+	})
+
 	sv.form.AddButton("Add Directory", func() {
 		sv.addDocDir()
-	}).
-	AddButton("Save", func() {
+	})
+	for i := range sv.docDirs {
+		idx := i
+		sv.form.AddButton(fmt.Sprintf("Remove %d", i+1), func() {
+			sv.removeDocDir(idx)
+		})
+	}
+	sv.form.AddButton("Save", func() {
 		sv.saveSettings()
 	}).
 	AddButton("Reset to Defaults", func() {
 		sv.resetToDefaults()
 	})
+	if sv.app.cfg.ReadOnly {
+		sv.form.GetButton(sv.form.GetButtonIndex("Save")).SetDisabled(true)
+	}
 }
 
 // render updates the settings display
@@ -176,39 +277,51 @@ func (sv *SettingsView) render() {
 		docDirsText = strings.Join(cfg.Paths.DocumentsDirs, "\n  ")
 	}
 	
-	settingsText := fmt.Sprintf(`[white]Database:
-  Connection: [cyan]%s[white]
+	schema := cfg.Database.Schema
+	if schema == "" {
+		schema = "public"
+	}
+
+	settingsText := fmt.Sprintf(colors.Text+`Database:
+  Connection: %s
+  Schema: %s
 
 Ollama:
-  Base URL: [cyan]%s[white]
-  Text Model: [cyan]%s[white]
+  Base URL: %s
+  Text Model: %s
 
 CLIP2:
-  Python Path: [cyan]%s[white]
-  Script Path: [cyan]%s[white]
+  Python Path: %s
+  Script Path: %s
 
 Document Directories:
-  [cyan]%s[white]
+  %s
 
 Images Directory:
-  [cyan]%s[white]
+  %s
 
 Processing:
-  Chunk Size: [cyan]%d[white]
-  Chunk Overlap: [cyan]%d[white]
+  Chunk Size: %s
+  Chunk Overlap: %s
 
 RAG:
-  Top K: [cyan]5[white]
-  Max Context Length: [cyan]2000[white]`,
-		cfg.Database.ConnectionString,
-		cfg.Ollama.BaseURL,
-		cfg.Embeddings.TextModel,
-		cfg.CLIP2.PythonPath,
-		cfg.CLIP2.ScriptPath,
-		docDirsText,
-		cfg.Paths.ImageDir,
-		cfg.Processing.ChunkSize,
-		cfg.Processing.ChunkOverlap,
+  Top K: %s
+  Max Context Length: %s (capped to %.0f%% of the model's window)
+  Context Order: %s`,
+		accent(cfg.Database.ConnectionString),
+		accent(schema),
+		accent(cfg.Ollama.BaseURL),
+		accent(cfg.Embeddings.TextModel),
+		accent(cfg.CLIP2.PythonPath),
+		accent(cfg.CLIP2.ScriptPath),
+		accent(docDirsText),
+		accent(cfg.Paths.ImageDir),
+		accent(fmt.Sprint(cfg.Processing.ChunkSize)),
+		accent(fmt.Sprintf("%d%%", cfg.Processing.ChunkOverlapPercent)),
+		accent(fmt.Sprint(cfg.Processing.TopK)),
+		accent(fmt.Sprint(cfg.RAG.MaxContextLength)),
+		cfg.RAG.ContextWindowFraction*100,
+		accent(cfg.RAG.ContextOrder),
 	)
 
 	sv.text.SetText(settingsText)
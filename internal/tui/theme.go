@@ -0,0 +1,177 @@
+package tui
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Theme maps the roles text plays in the views to colors. Colors are tcell
+// color names ("yellow", "darkcyan"), hex values ("#ffaf00"), or "default"
+// for the terminal's own color.
+type Theme struct {
+	Background string // Panel background
+	Text       string // Body text
+	Muted      string // Secondary detail such as snippets and logs
+	Accent     string // Values, names, and the user's messages
+	Emphasis   string // Headings, labels, key hints, and bold text
+	Success    string
+	Warning    string // Progress and notices as well as warnings
+	Error      string
+	Border     string
+	Banner     string // "foreground:background" of the paused indicator
+}
+
+// builtinThemes are the themes selectable by name with display.theme
+var builtinThemes = map[string]Theme{
+	"dark": {
+		Background: "black",
+		Text:       "white",
+		Muted:      "gray",
+		Accent:     "cyan",
+		Emphasis:   "yellow",
+		Success:    "green",
+		Warning:    "yellow",
+		Error:      "red",
+		Border:     "white",
+		Banner:     "black:yellow",
+	},
+	"light": {
+		Background: "default",
+		Text:       "black",
+		Muted:      "dimgray",
+		Accent:     "darkblue",
+		Emphasis:   "darkmagenta",
+		Success:    "darkgreen",
+		Warning:    "darkorange",
+		Error:      "darkred",
+		Border:     "black",
+		Banner:     "white:darkblue",
+	},
+	// Bold, widely distinguishable colors; success and error differ in
+	// brightness as well as hue for red-green color blindness
+	"high-contrast": {
+		Background: "black",
+		Text:       "white",
+		Muted:      "silver",
+		Accent:     "aqua",
+		Emphasis:   "yellow",
+		Success:    "#00afff",
+		Warning:    "#ffaf00",
+		Error:      "#ff5f5f",
+		Border:     "white",
+		Banner:     "black:white",
+	},
+}
+
+// colorTags holds the tview color tag for each role of the active theme
+type colorTags struct {
+	Text, Muted, Accent, Emphasis, Success, Warning, Error, Banner string
+}
+
+// colors are the color tags views write text with, in place of literal
+// tags. applyTheme sets them before the views are created.
+var colors = newColorTags(builtinThemes["dark"])
+
+// newColorTags returns the color tags for a theme
+func newColorTags(t Theme) colorTags {
+	tag := func(color string) string { return "[" + color + "]" }
+	return colorTags{
+		Text:     tag(t.Text),
+		Muted:    tag(t.Muted),
+		Accent:   tag(t.Accent),
+		Emphasis: tag(t.Emphasis),
+		Success:  tag(t.Success),
+		Warning:  tag(t.Warning),
+		Error:    tag(t.Error),
+		Banner:   tag(t.Banner),
+	}
+}
+
+// emphasis returns s in the emphasis color, followed by the text color
+func emphasis(s string) string {
+	return colors.Emphasis + s + colors.Text
+}
+
+// accent returns s in the accent color, followed by the text color
+func accent(s string) string {
+	return colors.Accent + s + colors.Text
+}
+
+// resolveTheme returns the named built-in theme with per-role color
+// overrides applied. An unknown theme falls back to dark, and unknown roles
+// or colors are ignored; both are logged.
+func resolveTheme(name string, overrides map[string]string) Theme {
+	if name == "" {
+		name = "dark"
+	}
+	theme, ok := builtinThemes[name]
+	if !ok {
+		log.Printf("warning: unknown theme %q, using dark", name)
+		theme = builtinThemes["dark"]
+	}
+
+	roles := map[string]*string{
+		"background": &theme.Background,
+		"text":       &theme.Text,
+		"muted":      &theme.Muted,
+		"accent":     &theme.Accent,
+		"emphasis":   &theme.Emphasis,
+		"success":    &theme.Success,
+		"warning":    &theme.Warning,
+		"error":      &theme.Error,
+		"border":     &theme.Border,
+		"banner":     &theme.Banner,
+	}
+	for role, color := range overrides {
+		field, ok := roles[role]
+		if !ok {
+			log.Printf("warning: unknown theme color role %q", role)
+			continue
+		}
+		color = strings.ToLower(strings.TrimSpace(color))
+		if !validColor(color, role == "banner") {
+			log.Printf("warning: invalid color %q for theme role %q", color, role)
+			continue
+		}
+		*field = color
+	}
+	return theme
+}
+
+// validColor reports whether color is a color tcell knows, or a
+// "foreground:background" pair of them if pair is set
+func validColor(color string, pair bool) bool {
+	if pair {
+		fg, bg, ok := strings.Cut(color, ":")
+		return ok && validColor(fg, false) && validColor(bg, false)
+	}
+	if color == "default" {
+		return true
+	}
+	if _, ok := tcell.ColorNames[color]; ok {
+		return true
+	}
+	return strings.HasPrefix(color, "#") && tcell.GetColor(color) != tcell.ColorDefault
+}
+
+// applyTheme makes t the active theme: it sets the color tags used by the
+// views and tview's default styles for borders, titles and backgrounds.
+// It must be called before any view is created.
+func applyTheme(t Theme) {
+	colors = newColorTags(t)
+
+	tview.Styles.PrimitiveBackgroundColor = tcell.GetColor(t.Background)
+	tview.Styles.ContrastBackgroundColor = tcell.GetColor(t.Accent)
+	tview.Styles.MoreContrastBackgroundColor = tcell.GetColor(t.Emphasis)
+	tview.Styles.BorderColor = tcell.GetColor(t.Border)
+	tview.Styles.TitleColor = tcell.GetColor(t.Text)
+	tview.Styles.GraphicsColor = tcell.GetColor(t.Border)
+	tview.Styles.PrimaryTextColor = tcell.GetColor(t.Text)
+	tview.Styles.SecondaryTextColor = tcell.GetColor(t.Emphasis)
+	tview.Styles.TertiaryTextColor = tcell.GetColor(t.Success)
+	tview.Styles.InverseTextColor = tcell.GetColor(t.Accent)
+	tview.Styles.ContrastSecondaryTextColor = tcell.GetColor(t.Muted)
+}
@@ -2,4 +2,15 @@ DROP TABLE IF EXISTS conversations;
 DROP TABLE IF EXISTS images;
 DROP TABLE IF EXISTS chunks;
 DROP TABLE IF EXISTS documents;
-DROP EXTENSION IF EXISTS vector;
+
+-- Other schemas (profiles and corpora) may still have vector columns; the
+-- extension is only dropped once no table uses it
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_attribute
+        WHERE atttypid = to_regtype('vector') AND NOT attisdropped
+    ) THEN
+        DROP EXTENSION IF EXISTS vector;
+    END IF;
+END $$;
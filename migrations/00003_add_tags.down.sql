@@ -0,0 +1,4 @@
+-- Remove tags from documents and chunks
+DROP INDEX IF EXISTS idx_chunks_tags;
+ALTER TABLE chunks DROP COLUMN tags;
+ALTER TABLE documents DROP COLUMN tags;
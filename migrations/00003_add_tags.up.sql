@@ -0,0 +1,5 @@
+-- Add tags to documents, propagated to their chunks
+ALTER TABLE documents ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE chunks ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_chunks_tags ON chunks USING GIN (tags);
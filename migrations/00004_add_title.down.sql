@@ -0,0 +1,2 @@
+-- Remove the metadata title from documents
+ALTER TABLE documents DROP COLUMN title;
@@ -0,0 +1,2 @@
+-- Add the title from document metadata, used for display
+ALTER TABLE documents ADD COLUMN title TEXT NOT NULL DEFAULT '';
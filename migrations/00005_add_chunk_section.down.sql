@@ -0,0 +1,2 @@
+-- Remove the outline section from chunks
+ALTER TABLE chunks DROP COLUMN section;
@@ -0,0 +1,2 @@
+-- Add the enclosing outline section of each chunk, for citations
+ALTER TABLE chunks ADD COLUMN section TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Remove the recorded chunk settings from documents
+ALTER TABLE documents DROP COLUMN chunk_overlap;
+ALTER TABLE documents DROP COLUMN chunk_size;
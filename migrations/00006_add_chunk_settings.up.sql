@@ -0,0 +1,3 @@
+-- Record the chunk settings each document was last chunked with
+ALTER TABLE documents ADD COLUMN chunk_size INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN chunk_overlap INTEGER NOT NULL DEFAULT 0;
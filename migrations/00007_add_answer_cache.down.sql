@@ -0,0 +1,4 @@
+-- Remove the answer cache
+DROP TRIGGER IF EXISTS chunks_invalidate_answer_cache ON chunks;
+DROP FUNCTION IF EXISTS invalidate_answer_cache();
+DROP TABLE IF EXISTS answer_cache;
@@ -0,0 +1,28 @@
+-- Cache of generated answers, keyed by a hash of the normalized query, the
+-- model and the retrieved chunk IDs
+CREATE TABLE answer_cache (
+    cache_key TEXT PRIMARY KEY,
+    query TEXT NOT NULL,
+    model_name TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    chunk_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_answer_cache_chunks ON answer_cache USING GIN (chunk_ids);
+
+-- Drop cached answers built from chunks that are deleted, including chunks
+-- removed along with their document
+CREATE FUNCTION invalidate_answer_cache() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM answer_cache
+    WHERE chunk_ids && ARRAY(SELECT id FROM deleted_chunks);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER chunks_invalidate_answer_cache
+    AFTER DELETE ON chunks
+    REFERENCING OLD TABLE AS deleted_chunks
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION invalidate_answer_cache();
@@ -0,0 +1,2 @@
+-- Remove the processing log from documents
+ALTER TABLE documents DROP COLUMN processing_log;
@@ -0,0 +1,2 @@
+-- Add a record of each document's last processing run
+ALTER TABLE documents ADD COLUMN processing_log TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Remove image thumbnail paths
+ALTER TABLE images DROP COLUMN thumbnail_path;
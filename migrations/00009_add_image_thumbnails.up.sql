@@ -0,0 +1,2 @@
+-- Add the path of each image's preview thumbnail; empty if none was made
+ALTER TABLE images ADD COLUMN thumbnail_path TEXT NOT NULL DEFAULT '';
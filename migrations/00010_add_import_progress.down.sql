@@ -0,0 +1,2 @@
+-- Remove directory import progress
+DROP TABLE IF EXISTS import_progress;
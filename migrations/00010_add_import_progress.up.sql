@@ -0,0 +1,6 @@
+-- Track files completed by an unfinished directory import so that a
+-- resumed import can skip them without rehashing
+CREATE TABLE IF NOT EXISTS import_progress (
+    file_path TEXT PRIMARY KEY,
+    completed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
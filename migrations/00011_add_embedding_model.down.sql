@@ -0,0 +1,2 @@
+-- Remove the recorded embedding model from documents
+ALTER TABLE documents DROP COLUMN embedding_model;
@@ -0,0 +1,2 @@
+-- Record the embedding model each document was last embedded with
+ALTER TABLE documents ADD COLUMN embedding_model TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Remove document summaries
+DROP TABLE IF EXISTS document_summaries;
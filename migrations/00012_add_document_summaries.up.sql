@@ -0,0 +1,22 @@
+-- Embedded summary of each document, searched first to pick the documents
+-- whose chunks answer a broad question
+CREATE TABLE IF NOT EXISTS document_summaries (
+    document_id UUID PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    summary TEXT NOT NULL,
+    model_name TEXT NOT NULL,
+    embedding vector(768), -- Same model and dimension as chunks.embedding
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- An index built with another embedding model has resized chunks.embedding;
+-- size the summaries' embedding to match it
+DO $$
+DECLARE
+    dim INTEGER;
+BEGIN
+    SELECT atttypmod INTO dim FROM pg_attribute
+    WHERE attrelid = 'chunks'::regclass AND attname = 'embedding';
+    IF dim > 0 AND dim <> 768 THEN
+        EXECUTE format('ALTER TABLE document_summaries ALTER COLUMN embedding TYPE vector(%s)', dim);
+    END IF;
+END $$;
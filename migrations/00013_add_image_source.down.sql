@@ -0,0 +1,3 @@
+-- Remove image source pages and locations
+ALTER TABLE images DROP COLUMN source_location;
+ALTER TABLE images DROP COLUMN source_page;
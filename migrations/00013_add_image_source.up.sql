@@ -0,0 +1,5 @@
+-- Record where in its document each image came from: the page it was
+-- rendered from (PDF, and EPUB when rendered by page) or its path inside an
+-- EPUB archive
+ALTER TABLE images ADD COLUMN source_page INTEGER;
+ALTER TABLE images ADD COLUMN source_location TEXT NOT NULL DEFAULT '';
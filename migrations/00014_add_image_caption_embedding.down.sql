@@ -0,0 +1,2 @@
+-- Remove image caption embeddings
+ALTER TABLE images DROP COLUMN caption_embedding;
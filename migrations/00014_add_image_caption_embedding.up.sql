@@ -0,0 +1,16 @@
+-- Embed image captions with the text model, so that images can be found by
+-- ordinary text search (clip2.caption_only stores no CLIP vector at all)
+ALTER TABLE images ADD COLUMN caption_embedding vector(768); -- Same model and dimension as chunks.embedding
+
+-- An index built with another embedding model has resized chunks.embedding;
+-- size the caption embedding to match it
+DO $$
+DECLARE
+    dim INTEGER;
+BEGIN
+    SELECT atttypmod INTO dim FROM pg_attribute
+    WHERE attrelid = 'chunks'::regclass AND attname = 'embedding';
+    IF dim > 0 AND dim <> 768 THEN
+        EXECUTE format('ALTER TABLE images ALTER COLUMN caption_embedding TYPE vector(%s)', dim);
+    END IF;
+END $$;
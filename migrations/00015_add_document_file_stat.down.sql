@@ -0,0 +1,3 @@
+-- Remove document file sizes and modification times
+ALTER TABLE documents DROP COLUMN file_mtime;
+ALTER TABLE documents DROP COLUMN file_size;
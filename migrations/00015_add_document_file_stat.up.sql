@@ -0,0 +1,4 @@
+-- Record each document's file size and modification time when it is hashed,
+-- so that scans can skip re-hashing files whose size and time are unchanged
+ALTER TABLE documents ADD COLUMN file_size BIGINT;
+ALTER TABLE documents ADD COLUMN file_mtime TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Remove image processing status
+DROP INDEX IF EXISTS idx_images_status;
+ALTER TABLE images DROP COLUMN error_message;
+ALTER TABLE images DROP COLUMN status;
@@ -0,0 +1,6 @@
+-- Record whether each image has been captioned and embedded, and why it
+-- failed if it has not, so that failed images can be retried on their own
+ALTER TABLE images ADD COLUMN status TEXT NOT NULL DEFAULT 'done'; -- pending, failed or done
+ALTER TABLE images ADD COLUMN error_message TEXT NOT NULL DEFAULT '';
+UPDATE images SET status = 'pending' WHERE embedding IS NULL AND caption_embedding IS NULL;
+CREATE INDEX idx_images_status ON images(status) WHERE status <> 'done';
@@ -0,0 +1,3 @@
+-- Restore the PDF and EPUB file type check; fails while documents of other
+-- types are stored
+ALTER TABLE documents ADD CONSTRAINT documents_file_type_check CHECK (file_type IN ('pdf', 'epub'));
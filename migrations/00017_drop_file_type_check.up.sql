@@ -0,0 +1,3 @@
+-- Accept any file type the parser registry knows, so that registering a
+-- parser needs no schema change
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_file_type_check;
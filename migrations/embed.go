@@ -0,0 +1,10 @@
+// Package migrations holds the database schema changes, embedded in the
+// binary so that -migrate needs no files beside it
+package migrations
+
+import "embed"
+
+// Files holds the NNNNN_name.up.sql and NNNNN_name.down.sql migrations
+//
+//go:embed *.sql
+var Files embed.FS
@@ -16,8 +16,45 @@ except ImportError:
     HAS_CLIP = False
     print("Warning: transformers not installed. Install with: pip install transformers torch pillow", file=sys.stderr)
 
-def process_image(image_path):
-    """Process an image and return caption and embedding"""
+try:
+    from transformers import BlipProcessor, BlipForConditionalGeneration
+    HAS_CAPTIONER = True
+except ImportError:
+    HAS_CAPTIONER = False
+
+# Image captioning model, used when only captions are stored
+CAPTION_MODEL = "Salesforce/blip-image-captioning-base"
+
+def caption_image(image_path):
+    """Describe an image in a short English sentence with BLIP"""
+    processor = BlipProcessor.from_pretrained(CAPTION_MODEL)
+    model = BlipForConditionalGeneration.from_pretrained(CAPTION_MODEL)
+
+    image = Image.open(image_path).convert("RGB")
+    inputs = processor(images=image, return_tensors="pt")
+    with torch.no_grad():
+        output = model.generate(**inputs, max_new_tokens=40)
+    return processor.decode(output[0], skip_special_tokens=True)
+
+def process_image(image_path, language=None, caption_only=False):
+    """Process an image and return caption and embedding.
+
+    language asks for the caption in that language (e.g. "de"). Captions
+    are always English; the output's "language" field says which language
+    the caption is in, and the caller translates it when that differs from
+    the one asked for. caption_only skips the CLIP embedding and captions
+    the image with BLIP instead of CLIP's placeholder, since the caption is
+    all that is searched; it fails rather than return a placeholder."""
+    if caption_only:
+        if not HAS_CAPTIONER:
+            return {"error": "caption_only needs an image captioning model. Install with: pip install transformers torch pillow"}
+        try:
+            return {
+                "caption": caption_image(image_path),
+                "language": "en"
+            }
+        except Exception as e:
+            return {"error": f"Error captioning image: {str(e)}"}
     if not HAS_CLIP:
         # Fallback: return placeholder
         return {
@@ -50,6 +87,7 @@ def process_image(image_path):
         
         return {
             "caption": caption,
+            "language": "en",
             "embedding": embedding
         }
     except Exception as e:
@@ -58,11 +96,39 @@ def process_image(image_path):
             "embedding": [0.0] * 512
         }
 
+def embed_text(text):
+    """Embed text into the same space as images, for searching them"""
+    if not HAS_CLIP:
+        return {"error": "transformers not installed"}
+
+    try:
+        model_name = "openai/clip-vit-base-patch32"
+        model = CLIPModel.from_pretrained(model_name)
+        processor = CLIPProcessor.from_pretrained(model_name)
+
+        inputs = processor(text=[text], return_tensors="pt", padding=True, truncation=True)
+        with torch.no_grad():
+            text_features = model.get_text_features(**inputs)
+
+        text_features = text_features / text_features.norm(dim=-1, keepdim=True)
+        return {"embedding": text_features[0].tolist()}
+    except Exception as e:
+        return {"error": f"Error embedding text: {str(e)}"}
+
 if __name__ == "__main__":
     if len(sys.argv) < 2:
-        print(json.dumps({"error": "Usage: clip2_process.py <image_path>"}), file=sys.stderr)
+        print(json.dumps({"error": "Usage: clip2_process.py [--caption-only] [--language <code>] <image_path> | --text <query>"}), file=sys.stderr)
         sys.exit(1)
     
-    image_path = sys.argv[1]
-    result = process_image(image_path)
+    if sys.argv[1] == "--text":
+        result = embed_text(" ".join(sys.argv[2:]))
+    else:
+        args = sys.argv[1:]
+        caption_only = False
+        if len(args) >= 2 and args[0] == "--caption-only":
+            caption_only, args = True, args[1:]
+        language = None
+        if len(args) >= 3 and args[0] == "--language":
+            language, args = args[1], args[2:]
+        result = process_image(args[0], language, caption_only)
     print(json.dumps(result))